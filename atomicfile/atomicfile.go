@@ -0,0 +1,141 @@
+// package atomicfile writes files so that readers, and the file system after
+// a crash, see either the previous content or the complete new content,
+// never a truncated mix. This matters most for integrity files: a checksum
+// file cut short by a crash can silently drop entries, and still "verify".
+//
+// Content is written to a temporary file in the destination's directory,
+// synced as the chosen Durability requires, and renamed over the
+// destination, which POSIX file systems perform atomically.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Durability selects how much syncing is done before and after the rename.
+type Durability int
+
+const (
+	// SyncFile syncs the new content to disk before renaming it into
+	// place, so the destination can never name a file whose data was
+	// lost. It is the zero value, and the right choice for most callers.
+	SyncFile Durability = iota
+	// SyncDir additionally syncs the destination directory after the
+	// rename, so the new name itself survives power loss. It is ignored on
+	// Windows, which cannot sync directories.
+	SyncDir
+	// NoSync only renames, which still hides partial writes from readers
+	// and survives the writing process crashing, but not a system crash.
+	NoSync
+)
+
+// File is a file being written atomically. Writes go to a temporary file;
+// Close publishes it under the destination name, and Abort discards it.
+type File struct {
+	*os.File
+	name       string
+	perm       os.FileMode
+	durability Durability
+	done       bool
+}
+
+// Create starts writing name atomically, with permissions 0644 once
+// published.
+func Create(name string, durability Durability) (*File, error) {
+	return CreateMode(name, 0o644, durability)
+}
+
+// CreateMode is Create for a file published with permissions perm, which,
+// unlike with os.OpenFile, the umask does not reduce.
+func CreateMode(name string, perm os.FileMode, durability Durability) (*File, error) {
+	temp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: temp, name: name, perm: perm, durability: durability}, nil
+}
+
+// Name returns the destination name, not that of the temporary file.
+func (f *File) Name() string {
+	return f.name
+}
+
+// SetName changes the destination name before Close, for writers that only
+// learn it from the content, such as content-addressed stores naming files
+// by their digest. The new name must be on the same file system as the one
+// given to Create, for the rename to be atomic.
+func (f *File) SetName(name string) {
+	f.name = name
+}
+
+// Close publishes everything written under the destination name, replacing
+// any file already there. If publishing fails, the temporary file is
+// removed and the destination is left as it was.
+func (f *File) Close() (err error) {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	temp := f.File.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(temp)
+		}
+	}()
+	if f.durability != NoSync {
+		if err = f.File.Sync(); err != nil {
+			f.File.Close()
+			return err
+		}
+	}
+	if err = f.File.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(temp, f.perm); err != nil {
+		return err
+	}
+	if err = os.Rename(temp, f.name); err != nil {
+		return err
+	}
+	if f.durability == SyncDir && runtime.GOOS != "windows" {
+		return syncDir(filepath.Dir(f.name))
+	}
+	return nil
+}
+
+// Abort discards everything written, leaving the destination as it was. It
+// does nothing after Close, so it can be deferred to clean up on every error
+// path.
+func (f *File) Abort() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
+
+// WriteFile atomically replaces name with data, as os.WriteFile does
+// non-atomically.
+func WriteFile(name string, data []byte, perm os.FileMode, durability Durability) error {
+	f, err := CreateMode(name, perm, durability)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
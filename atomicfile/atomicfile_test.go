@@ -0,0 +1,82 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Create(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "SHA256SUMS")
+	if err := os.WriteFile(name, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Create(name, SyncDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("new\n"))
+	if contents, _ := os.ReadFile(name); string(contents) != "old\n" {
+		t.Fatalf("destination changed before Close: %q\n", contents)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if contents, _ := os.ReadFile(name); string(contents) != "new\n" {
+		t.Fatalf("destination after Close was %q\n", contents)
+	}
+
+	f, err = Create(name, NoSync)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial"))
+	if err = f.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if contents, _ := os.ReadFile(name); string(contents) != "new\n" {
+		t.Fatalf("destination after Abort was %q\n", contents)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("temporary files left in %s: %v\n", dir, entries)
+	}
+}
+
+func Test_WriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "integrity.json")
+	if err := WriteFile(name, []byte("{}\n"), 0o600, SyncFile); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 || info.Size() != 3 {
+		t.Fatalf("wrote %v, %d bytes\n", info.Mode(), info.Size())
+	}
+}
+
+func Test_SetName(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Create(filepath.Join(dir, "incoming"), SyncDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("blob"))
+	if err = os.Mkdir(filepath.Join(dir, "ab"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	named := filepath.Join(dir, "ab", "abcdef")
+	f.SetName(named)
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if contents, _ := os.ReadFile(named); string(contents) != "blob" {
+		t.Fatalf("renamed destination holds %q\n", contents)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("unexpected files left in %s: %v\n", dir, entries)
+	}
+}
@@ -0,0 +1,122 @@
+// package audit produces tamper-evident records of integrity checks, for
+// compliance-driven monitoring: signed statements of which digests a file or
+// report had, when, and on which host.
+package audit
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/release"
+)
+
+// Record states that Subject, a file path or the name of a report, had the
+// given digests at Time, as observed on Host. Digests maps each algorithm
+// name to a lower-case hex digest.
+type Record struct {
+	Time    time.Time         `json:"time"`
+	Host    string            `json:"host"`
+	Subject string            `json:"subject"`
+	Digests map[string]string `json:"digests"`
+}
+
+// NewRecord returns a Record of digests, under the same-indexed algorithms,
+// observed now on this host.
+func NewRecord(subject string, algorithms []string, digests [][]byte) Record {
+	host, _ := os.Hostname()
+	record := Record{
+		Time:    time.Now().UTC(),
+		Host:    host,
+		Subject: subject,
+		Digests: make(map[string]string, len(algorithms)),
+	}
+	for index, algorithm := range algorithms {
+		record.Digests[algorithm] = hex.EncodeToString(digests[index])
+	}
+	return record
+}
+
+// RecordFile hashes filename with algorithms and returns a Record of the
+// result, with the file name as the subject. A scan report is recorded the
+// same way, by hashing the file it was written to.
+func RecordFile(filename string, algorithms ...string) (Record, error) {
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return Record{}, err
+	}
+	digests, err := multihash.FromFile(filename, hashes...)
+	if err != nil {
+		return Record{}, err
+	}
+	return NewRecord(filename, algorithms, digests), nil
+}
+
+// Payload returns the canonical encoding of r that signatures cover: its
+// JSON form, which is deterministic since encoding/json sorts map keys.
+func (r Record) Payload() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// Signer signs record payloads. Implementations can wrap a local key, a
+// hardware token or a remote KMS.
+type Signer interface {
+	Sign(payload []byte) (signature []byte, err error)
+}
+
+// Verifier checks signatures made by a Signer.
+type Verifier interface {
+	Verify(payload, signature []byte) error
+}
+
+// CryptoSigner adapts a crypto.Signer, signing as release.Sign does: Ed25519
+// keys sign the payload directly, others its SHA-256 digest.
+type CryptoSigner struct {
+	crypto.Signer
+}
+
+func (s CryptoSigner) Sign(payload []byte) ([]byte, error) {
+	return release.Sign(s.Signer, payload)
+}
+
+// Ed25519Verifier checks signatures made by CryptoSigner with an Ed25519
+// key.
+type Ed25519Verifier ed25519.PublicKey
+
+func (v Ed25519Verifier) Verify(payload, signature []byte) error {
+	return release.VerifyEd25519(ed25519.PublicKey(v), payload, signature)
+}
+
+// SignedRecord is a Record with a signature over its Payload. Its JSON form
+// is self-contained and suitable for appending to an audit trail.
+type SignedRecord struct {
+	Record    Record `json:"record"`
+	Signature []byte `json:"signature"`
+}
+
+// Sign signs record with signer.
+func Sign(record Record, signer Signer) (SignedRecord, error) {
+	payload, err := record.Payload()
+	if err != nil {
+		return SignedRecord{}, err
+	}
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return SignedRecord{}, err
+	}
+	return SignedRecord{Record: record, Signature: signature}, nil
+}
+
+// Verify checks the signature over s.Record with verifier, so that a record
+// altered in any field after signing is rejected.
+func (s SignedRecord) Verify(verifier Verifier) error {
+	payload, err := s.Record.Payload()
+	if err != nil {
+		return err
+	}
+	return verifier.Verify(payload, s.Signature)
+}
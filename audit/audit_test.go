@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	_ "crypto/md5"
+	_ "crypto/sha256"
+
+	"github.com/trytriangles/multihash/release"
+)
+
+func Test_SignedRecord(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := RecordFile("../testing/text1.txt", "sha256", "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Digests["sha256"] != "8bb5bc05618f1036a063bbf83cf74cca163a60343791c0c930acc31bf0c090ea" {
+		t.Fatalf("recorded digests %v\n", record.Digests)
+	}
+	signed, err := Sign(record, CryptoSigner{private})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded SignedRecord
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err = decoded.Verify(Ed25519Verifier(public)); err != nil {
+		t.Fatalf("round-tripped record failed to verify: %v\n", err)
+	}
+
+	decoded.Record.Digests["md5"] = "00000000000000000000000000000000"
+	if err = decoded.Verify(Ed25519Verifier(public)); !errors.Is(err, release.ErrBadSignature) {
+		t.Fatalf("tampered record gave %v\n", err)
+	}
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+var ErrMalformedTimestamp = errcode.New(errcode.Malformed, "malformed timestamp")
+var ErrTimestampRejected = errcode.New(errcode.Unavailable, "timestamp request rejected")
+
+type MalformedTimestampError struct {
+	Reason string
+}
+
+func (e MalformedTimestampError) Error() string {
+	return "malformed timestamp: " + e.Reason
+}
+
+func (e MalformedTimestampError) Is(target error) bool {
+	return target == ErrMalformedTimestamp
+}
+
+func (e MalformedTimestampError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+type TimestampRejectedError struct {
+	Status int
+	Text   []string
+}
+
+func (e TimestampRejectedError) Error() string {
+	message := "timestamp request rejected with status " + strconv.Itoa(e.Status)
+	if len(e.Text) > 0 {
+		message += ": " + strings.Join(e.Text, "; ")
+	}
+	return message
+}
+
+func (e TimestampRejectedError) Is(target error) bool {
+	return target == ErrTimestampRejected
+}
+
+func (e TimestampRejectedError) Code() errcode.Code {
+	return errcode.Unavailable
+}
+
+var ErrBrokenChain = errcode.New(errcode.Mismatch, "audit log chain broken")
+
+type BrokenChainError struct {
+	Sequence int
+	Reason   string
+}
+
+func (e BrokenChainError) Error() string {
+	return "audit log chain broken at entry " + strconv.Itoa(e.Sequence) + ": " + e.Reason
+}
+
+func (e BrokenChainError) Is(target error) bool {
+	return target == ErrBrokenChain
+}
+
+func (e BrokenChainError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+var ErrTornEntry = errcode.New(errcode.Incomplete, "audit log ends with a partial entry")
+
+type TornEntryError struct {
+	Sequence int
+}
+
+func (e TornEntryError) Error() string {
+	return "audit log entry " + strconv.Itoa(e.Sequence) + " was not completely written"
+}
+
+func (e TornEntryError) Is(target error) bool {
+	return target == ErrTornEntry
+}
+
+func (e TornEntryError) Code() errcode.Code {
+	return errcode.Incomplete
+}
+
+var ErrTreeRange = errcode.New(errcode.InvalidArgument, "Merkle log size or index out of range")
+
+type TreeRangeError struct {
+	Value int
+	Max   int
+}
+
+func (e TreeRangeError) Error() string {
+	return "Merkle log size or index " + strconv.Itoa(e.Value) + " out of range 0 to " + strconv.Itoa(e.Max)
+}
+
+func (e TreeRangeError) Is(target error) bool {
+	return target == ErrTreeRange
+}
+
+func (e TreeRangeError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
@@ -0,0 +1,181 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Event is something worth keeping a tamper-evident record of: a scan, a
+// verification run, a sidecar regeneration. Kind and Outcome are free-form
+// short words, such as "verify" and "ok"; Details holds anything else, such
+// as counts of mismatched files.
+type Event struct {
+	Time    time.Time         `json:"time"`
+	Kind    string            `json:"kind"`
+	Subject string            `json:"subject"`
+	Outcome string            `json:"outcome"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// VerifyEvent summarizes a verification of subject, such as a manifest's
+// path, as an Event.
+func VerifyEvent(subject string, report multihash.VerifyReport) Event {
+	outcome := "ok"
+	if !report.OK() {
+		outcome = "failed"
+	}
+	return Event{
+		Time:    time.Now().UTC(),
+		Kind:    "verify",
+		Subject: subject,
+		Outcome: outcome,
+		Details: map[string]string{
+			"verified":   strconv.Itoa(len(report.Verified)),
+			"mismatched": strconv.Itoa(len(report.Mismatched)),
+			"missing":    strconv.Itoa(len(report.Missing)),
+			"unverified": strconv.Itoa(len(report.Unverified)),
+			"extra":      strconv.Itoa(len(report.Extra)),
+			"failed":     strconv.Itoa(len(report.Failed)),
+		},
+	}
+}
+
+// LogEntry is one line of a Log. Previous is the hex SHA-256 of the
+// preceding line as written, or empty for the first entry, so that altering,
+// removing or reordering any entry breaks every link after it.
+type LogEntry struct {
+	Sequence int    `json:"seq"`
+	Previous string `json:"prev"`
+	Event    Event  `json:"event"`
+}
+
+// Log is an append-only, hash-chained log of events, one JSON LogEntry per
+// line. Each entry is synced as it is appended, so a crash loses at most the
+// entry being written. The chain gives tamper-evidence for the monitoring
+// itself: an attacker who rewrites history must rewrite every later entry,
+// and anyone holding an earlier copy of the last link, or a Merkle log root
+// covering it, can tell.
+type Log struct {
+	f        *os.File
+	sequence int
+	previous string
+	// err is the error of a failed write, after which nothing more is
+	// appended.
+	err error
+}
+
+// OpenLog opens the log at filename for appending, creating it if needed.
+// The existing entries are validated first, and a broken chain is returned
+// as an error rather than appended to. A final line without its newline is
+// an entry whose Append was cut short by a crash, never acknowledged, so it
+// is truncated away and the chain continues from the entry before it.
+func OpenLog(filename string) (*Log, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	sequence, previous, length, torn, err := validate(f)
+	if err == nil && torn {
+		if err = f.Truncate(length); err == nil {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Log{f: f, sequence: sequence, previous: previous}, nil
+}
+
+// Append writes event as the next entry and syncs it. An entry that was
+// written but failed to sync is in the file all the same, so it still takes
+// its place in the chain. A failed write may have left part of an entry
+// behind, which only OpenLog can repair, so it fails the log: every later
+// Append returns the same error.
+func (l *Log) Append(event Event) error {
+	if l.err != nil {
+		return l.err
+	}
+	line, err := json.Marshal(LogEntry{Sequence: l.sequence, Previous: l.previous, Event: event})
+	if err != nil {
+		return err
+	}
+	if _, err = l.f.Write(append(line, '\n')); err != nil {
+		l.err = err
+		return err
+	}
+	l.sequence++
+	l.previous = lineDigest(line)
+	return l.f.Sync()
+}
+
+// Head returns the number of entries and the digest of the last one, which
+// together commit to the whole log. Publishing or timestamping them lets
+// later tampering be detected even by whoever controls the log file.
+func (l *Log) Head() (entries int, digest string) {
+	return l.sequence, l.previous
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.f.Close()
+}
+
+// ValidateLog checks the chain of a log read from r, returning the number of
+// entries and the digest of the last. The first entry that is malformed or
+// does not link to its predecessor gives a BrokenChainError. A final line
+// without its newline, as a crash during Append leaves, gives a
+// TornEntryError instead, along with the count and digest of the entries
+// before it, which OpenLog would keep.
+func ValidateLog(r io.Reader) (entries int, head string, err error) {
+	entries, head, _, torn, err := validate(r)
+	if err == nil && torn {
+		err = TornEntryError{Sequence: entries}
+	}
+	return entries, head, err
+}
+
+// validate checks the chain of the log read from r, as ValidateLog does,
+// additionally returning the length in bytes of its complete lines and
+// whether a final, unterminated line follows them. That line is not
+// checked.
+func validate(r io.Reader) (sequence int, previous string, length int64, torn bool, err error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr == io.EOF {
+			return sequence, previous, length, len(line) > 0, nil
+		}
+		if readErr != nil {
+			return 0, "", 0, false, readErr
+		}
+		length += int64(len(line))
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte{'\n'}), []byte{'\r'})
+		var entry LogEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			return 0, "", 0, false, BrokenChainError{Sequence: sequence, Reason: "malformed entry"}
+		}
+		if entry.Sequence != sequence {
+			return 0, "", 0, false, BrokenChainError{Sequence: sequence, Reason: "entry out of sequence"}
+		}
+		if entry.Previous != previous {
+			return 0, "", 0, false, BrokenChainError{Sequence: sequence, Reason: "does not link to the previous entry"}
+		}
+		sequence++
+		previous = lineDigest(line)
+	}
+}
+
+func lineDigest(line []byte) string {
+	digest := sha256.Sum256(line)
+	return hex.EncodeToString(digest[:])
+}
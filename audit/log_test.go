@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+func Test_Log(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	log, err := OpenLog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Append(Event{Kind: "scan", Subject: "/srv/archive", Outcome: "ok"})
+	log.Append(VerifyEvent("/srv/archive/SHA256SUMS", multihash.VerifyReport{Verified: []string{"a"}, Mismatched: []string{"b"}}))
+	log.Close()
+
+	// Reopening continues the chain.
+	log, err = OpenLog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = log.Append(Event{Kind: "verify", Subject: "/srv/archive/SHA256SUMS", Outcome: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, head := log.Head()
+	log.Close()
+
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validated, validatedHead, err := ValidateLog(bytes.NewReader(contents))
+	if err != nil || validated != 3 || entries != 3 || validatedHead != head {
+		t.Fatalf("validated %d entries up to %s (%v); log reported %d up to %s\n", validated, validatedHead, err, entries, head)
+	}
+
+	// A crash part-way through Append leaves a final line without its
+	// newline. Validating reports it apart from tampering, and reopening
+	// drops it and carries on from the entry before.
+	torn := append(append([]byte(nil), contents...), `{"seq":3,"prev":"`...)
+	if validated, validatedHead, err = ValidateLog(bytes.NewReader(torn)); !errors.Is(err, ErrTornEntry) || errors.Is(err, ErrBrokenChain) || validated != 3 || validatedHead != head {
+		t.Fatalf("torn log validated %d entries up to %s (%v)\n", validated, validatedHead, err)
+	}
+	if err = os.WriteFile(filename, torn, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if log, err = OpenLog(filename); err != nil {
+		t.Fatalf("opening a torn log gave %v\n", err)
+	}
+	if err = log.Append(Event{Kind: "scan", Subject: "/srv/archive", Outcome: "ok"}); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+	if contents, err = os.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if validated, _, err = ValidateLog(bytes.NewReader(contents)); err != nil || validated != 4 {
+		t.Fatalf("log appended to after a tear validated %d entries (%v)\n", validated, err)
+	}
+
+	tampered := bytes.Replace(contents, []byte(`"outcome":"failed"`), []byte(`"outcome":"ok"`), 1)
+	if bytes.Equal(tampered, contents) {
+		t.Fatal("test did not tamper with the log")
+	}
+	if _, _, err = ValidateLog(bytes.NewReader(tampered)); !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("tampered log gave %v\n", err)
+	}
+	if err = os.WriteFile(filename, tampered, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenLog(filename); !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("opening a tampered log gave %v\n", err)
+	}
+}
+
+func Test_Log_failedWrite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.log")
+	log, err := OpenLog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.f.Close()
+	first := log.Append(Event{Kind: "scan", Outcome: "ok"})
+	if first == nil {
+		t.Fatal("appending to a closed file succeeded")
+	}
+
+	// Once a write fails, nothing more is appended, even to a working file.
+	if log.f, err = os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	if err = log.Append(Event{Kind: "scan", Outcome: "ok"}); err != first {
+		t.Fatalf("appending after a failed write gave %v\n", err)
+	}
+	if entries, _ := log.Head(); entries != 0 {
+		t.Fatalf("%d entries after failed writes\n", entries)
+	}
+}
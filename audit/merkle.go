@@ -0,0 +1,273 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// MerkleLog is an append-only Merkle tree over a sequence of leaves, such as
+// the root digests of successive scans as returned by
+// manifest.Manifest.RootDigest, in the style of Certificate Transparency
+// (RFC 9162). Its root commits to the whole history: an auditor who kept an
+// earlier root can demand a consistency proof that the current log only
+// extends it, and so that no earlier scan was rewritten or removed, without
+// seeing the log itself; and an inclusion proof shows that a given scan is
+// part of the history.
+//
+// Leaves are stored one per line, hex-encoded, and each append is synced.
+type MerkleLog struct {
+	f      *os.File
+	hashes [][]byte
+	// err is the error of a failed write, after which nothing more is
+	// appended.
+	err error
+}
+
+// OpenMerkleLog opens the Merkle log at filename for appending, creating it
+// if needed. A final line without its newline is a leaf whose Append was cut
+// short by a crash, never acknowledged, so it is truncated away, as OpenLog
+// does.
+func OpenMerkleLog(filename string) (*MerkleLog, error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	log := &MerkleLog{f: f}
+	br := bufio.NewReaderSize(f, 64*1024)
+	var length int64
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr == io.EOF {
+			if len(line) > 0 {
+				if err = f.Truncate(length); err == nil {
+					err = f.Sync()
+				}
+			}
+			break
+		}
+		if readErr != nil {
+			err = readErr
+			break
+		}
+		length += int64(len(line))
+		leaf, decodeErr := hex.DecodeString(string(bytes.TrimSpace(line)))
+		if decodeErr != nil {
+			err = BrokenChainError{Sequence: len(log.hashes), Reason: "malformed leaf"}
+			break
+		}
+		log.hashes = append(log.hashes, LeafHash(leaf))
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return log, nil
+}
+
+// Append adds leaf to the log and returns its index. A leaf that was
+// written but failed to sync is in the file all the same, so it is still
+// added; a failed write fails the log, as for Log.Append.
+func (l *MerkleLog) Append(leaf []byte) (index int, err error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if _, err = l.f.WriteString(hex.EncodeToString(leaf) + "\n"); err != nil {
+		l.err = err
+		return 0, err
+	}
+	l.hashes = append(l.hashes, LeafHash(leaf))
+	return len(l.hashes) - 1, l.f.Sync()
+}
+
+// Size returns the number of leaves in the log.
+func (l *MerkleLog) Size() int {
+	return len(l.hashes)
+}
+
+// Root returns the root hash of the log's first size leaves, so that roots
+// published in the past can be recomputed. A size the log has not reached
+// is a TreeRangeError.
+func (l *MerkleLog) Root(size int) ([]byte, error) {
+	if err := l.checkSize(size); err != nil {
+		return nil, err
+	}
+	return treeHash(l.hashes[:size]), nil
+}
+
+// InclusionProof proves that the leaf at index is included in the log's
+// first size leaves, for VerifyInclusion. A size the log has not reached, or
+// an index not below it, is a TreeRangeError.
+func (l *MerkleLog) InclusionProof(index, size int) ([][]byte, error) {
+	if err := l.checkSize(size); err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= size {
+		return nil, TreeRangeError{Value: index, Max: size - 1}
+	}
+	return inclusionPath(index, l.hashes[:size]), nil
+}
+
+// ConsistencyProof proves that the log's first oldSize leaves are a prefix
+// of its first newSize, for VerifyConsistency. A newSize the log has not
+// reached, or an oldSize above it, is a TreeRangeError.
+func (l *MerkleLog) ConsistencyProof(oldSize, newSize int) ([][]byte, error) {
+	if err := l.checkSize(newSize); err != nil {
+		return nil, err
+	}
+	if oldSize < 0 || oldSize > newSize {
+		return nil, TreeRangeError{Value: oldSize, Max: newSize}
+	}
+	if oldSize == 0 || oldSize == newSize {
+		return nil, nil
+	}
+	return subproof(oldSize, l.hashes[:newSize], true), nil
+}
+
+func (l *MerkleLog) checkSize(size int) error {
+	if size < 0 || size > len(l.hashes) {
+		return TreeRangeError{Value: size, Max: len(l.hashes)}
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *MerkleLog) Close() error {
+	return l.f.Close()
+}
+
+// LeafHash returns the RFC 9162 hash of a leaf.
+func LeafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// split returns the largest power of two smaller than n, for n > 1.
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func treeHash(hashes [][]byte) []byte {
+	switch len(hashes) {
+	case 0:
+		digest := sha256.Sum256(nil)
+		return digest[:]
+	case 1:
+		return hashes[0]
+	}
+	k := split(len(hashes))
+	return nodeHash(treeHash(hashes[:k]), treeHash(hashes[k:]))
+}
+
+func inclusionPath(index int, hashes [][]byte) [][]byte {
+	if len(hashes) <= 1 {
+		return nil
+	}
+	k := split(len(hashes))
+	if index < k {
+		return append(inclusionPath(index, hashes[:k]), treeHash(hashes[k:]))
+	}
+	return append(inclusionPath(index-k, hashes[k:]), treeHash(hashes[:k]))
+}
+
+func subproof(m int, hashes [][]byte, complete bool) [][]byte {
+	if m == len(hashes) {
+		if complete {
+			return nil
+		}
+		return [][]byte{treeHash(hashes)}
+	}
+	k := split(len(hashes))
+	if m <= k {
+		return append(subproof(m, hashes[:k], complete), treeHash(hashes[k:]))
+	}
+	return append(subproof(m-k, hashes[k:], false), treeHash(hashes[:k]))
+}
+
+// VerifyInclusion checks an inclusion proof that the leaf with leafHash is
+// at index in a tree of size leaves with the given root.
+func VerifyInclusion(leafHash []byte, index, size int, proof [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+// VerifyConsistency checks a consistency proof that the tree of oldSize
+// leaves with oldRoot is a prefix of the tree of newSize leaves with
+// newRoot.
+func VerifyConsistency(oldSize, newSize int, oldRoot, newRoot []byte, proof [][]byte) bool {
+	switch {
+	case oldSize < 0 || oldSize > newSize:
+		return false
+	case oldSize == newSize:
+		return len(proof) == 0 && bytes.Equal(oldRoot, newRoot)
+	case oldSize == 0:
+		return len(proof) == 0
+	}
+	if oldSize&(oldSize-1) == 0 {
+		proof = append([][]byte{oldRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return false
+	}
+	fn, sn := oldSize-1, newSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(fr, oldRoot) && bytes.Equal(sr, newRoot)
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func Test_MerkleLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "roots.log")
+	log, err := OpenMerkleLog(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 13
+	for index := 0; index < size; index++ {
+		if _, err = log.Append([]byte("tree1:sha256:scan-" + strconv.Itoa(index))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.Close()
+	if log, err = OpenMerkleLog(filename); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	if log.Size() != size {
+		t.Fatalf("reopened log has %d leaves\n", log.Size())
+	}
+
+	for n := 1; n <= size; n++ {
+		root := mustRoot(t, log, n)
+		for index := 0; index < n; index++ {
+			leaf := LeafHash([]byte("tree1:sha256:scan-" + strconv.Itoa(index)))
+			proof, err := log.InclusionProof(index, n)
+			if err != nil || !VerifyInclusion(leaf, index, n, proof, root) {
+				t.Fatalf("inclusion of leaf %d in a tree of %d failed (%v)\n", index, n, err)
+			}
+		}
+		for m := 0; m <= n; m++ {
+			proof, err := log.ConsistencyProof(m, n)
+			if err != nil || !VerifyConsistency(m, n, mustRoot(t, log, m), root, proof) {
+				t.Fatalf("consistency of %d with %d failed (%v)\n", m, n, err)
+			}
+		}
+	}
+
+	// A rewritten history is not consistent with the published root.
+	forged := append([][]byte(nil), log.hashes...)
+	forged[2] = LeafHash([]byte("tree1:sha256:rewritten"))
+	forgedRoot := treeHash(forged)
+	if VerifyConsistency(5, size, mustRoot(t, log, 5), forgedRoot, subproof(5, forged, true)) {
+		t.Fatal("rewritten history passed a consistency check")
+	}
+	proof, _ := log.InclusionProof(3, size)
+	if VerifyInclusion(LeafHash([]byte("tree1:sha256:scan-3")), 4, size, proof, mustRoot(t, log, size)) {
+		t.Fatal("inclusion proof accepted at the wrong index")
+	}
+
+	// Sizes and indexes beyond the log are errors, not panics.
+	if _, err = log.Root(size + 1); !errors.Is(err, ErrTreeRange) {
+		t.Fatalf("root beyond the log gave %v\n", err)
+	}
+	if _, err = log.InclusionProof(size, size); !errors.Is(err, ErrTreeRange) {
+		t.Fatalf("inclusion of a leaf beyond the tree gave %v\n", err)
+	}
+	if _, err = log.ConsistencyProof(2, size+1); !errors.Is(err, ErrTreeRange) {
+		t.Fatalf("consistency with a tree beyond the log gave %v\n", err)
+	}
+	if _, err = log.ConsistencyProof(3, 2); !errors.Is(err, ErrTreeRange) {
+		t.Fatalf("consistency with a smaller tree gave %v\n", err)
+	}
+}
+
+func Test_MerkleLog_torn(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "roots.log")
+	if err := os.WriteFile(filename, []byte("00ff\nabcd\n12"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	log, err := OpenMerkleLog(filename)
+	if err != nil {
+		t.Fatalf("opening a torn log gave %v\n", err)
+	}
+	if log.Size() != 2 {
+		t.Fatalf("torn log has %d leaves\n", log.Size())
+	}
+	if index, err := log.Append([]byte{0x34}); err != nil || index != 2 {
+		t.Fatalf("append after a torn leaf gave %d, %v\n", index, err)
+	}
+	log.Close()
+	if contents, _ := os.ReadFile(filename); string(contents) != "00ff\nabcd\n34\n" {
+		t.Fatalf("unexpected log contents %q\n", contents)
+	}
+}
+
+func mustRoot(t *testing.T, log *MerkleLog, size int) []byte {
+	t.Helper()
+	root, err := log.Root(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
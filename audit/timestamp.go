@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Object identifiers from RFC 3161, RFC 5652 and RFC 5754.
+var (
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	hashOIDs      = map[crypto.Hash]asn1.ObjectIdentifier{
+		crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+		crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+		crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+	}
+)
+
+// Timestamp is a trusted timestamp obtained from an RFC 3161 time-stamping
+// authority, attesting that a digest existed no later than Time.
+type Timestamp struct {
+	Time   time.Time
+	Serial *big.Int
+	Policy asn1.ObjectIdentifier
+	// Token is the DER-encoded TimeStampToken as the authority issued it:
+	// the evidence to archive next to the manifest or report, and what
+	// third parties verify, e.g. with `openssl ts -verify`.
+	Token []byte
+
+	nonce *big.Int
+}
+
+// RequestTimestamp asks the time-stamping authority at url to timestamp
+// digest, the h digest of a manifest, report or other file, such as one
+// recorded by RecordFile. client is http.DefaultClient if nil.
+//
+// The response is checked to be a granted timestamp over exactly digest,
+// answering this request's nonce. The authority's CMS signature over the
+// token is not checked here, since that needs the authority's certificate
+// chain and a CMS implementation the standard library lacks; Token must be
+// verified against the authority's certificate before it is relied on.
+func RequestTimestamp(url string, client *http.Client, h crypto.Hash, digest []byte) (*Timestamp, error) {
+	oid, ok := hashOIDs[h]
+	if !ok || len(digest) != h.Size() {
+		return nil, multihash.UnavailableHashFunctionError{Hash: h}
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	imprint := messageImprint{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+		HashedMessage: digest,
+	}
+	request, err := asn1.Marshal(timeStampReq{Version: 1, MessageImprint: imprint, Nonce: nonce, CertReq: true})
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Post(url, "application/timestamp-query", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, multihash.HTTPStatusError{URL: url, StatusCode: response.StatusCode}
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	var resp timeStampResp
+	if _, err = asn1.Unmarshal(body, &resp); err != nil {
+		return nil, MalformedTimestampError{Reason: err.Error()}
+	}
+	// Statuses 0 and 1 are granted and grantedWithMods.
+	if resp.Status.Status > 1 {
+		return nil, TimestampRejectedError{Status: resp.Status.Status, Text: resp.Status.StatusString}
+	}
+	timestamp, err := ParseTimestamp(resp.TimeStampToken.FullBytes, h, digest)
+	if err != nil {
+		return nil, err
+	}
+	if timestamp.nonce == nil || timestamp.nonce.Cmp(nonce) != 0 {
+		return nil, MalformedTimestampError{Reason: "nonce does not match the request"}
+	}
+	return timestamp, nil
+}
+
+// ParseTimestamp decodes a TimeStampToken, as stored from Timestamp.Token,
+// and checks that it covers digest under h. As with RequestTimestamp, the
+// token's signature is not checked. An unsupported h, or a digest of the
+// wrong size for it, is a multihash.UnavailableHashFunctionError, as there.
+func ParseTimestamp(token []byte, h crypto.Hash, digest []byte) (*Timestamp, error) {
+	oid, ok := hashOIDs[h]
+	if !ok || len(digest) != h.Size() {
+		return nil, multihash.UnavailableHashFunctionError{Hash: h}
+	}
+	var info contentInfo
+	if _, err := asn1.Unmarshal(token, &info); err != nil {
+		return nil, MalformedTimestampError{Reason: err.Error()}
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, MalformedTimestampError{Reason: "token is not CMS signed data"}
+	}
+	var signed signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signed); err != nil {
+		return nil, MalformedTimestampError{Reason: err.Error()}
+	}
+	if !signed.EncapContentInfo.ContentType.Equal(oidTSTInfo) {
+		return nil, MalformedTimestampError{Reason: "token does not hold TSTInfo"}
+	}
+	var tst tstInfo
+	if _, err := asn1.Unmarshal(signed.EncapContentInfo.Content, &tst); err != nil {
+		return nil, MalformedTimestampError{Reason: err.Error()}
+	}
+	if !tst.MessageImprint.HashAlgorithm.Algorithm.Equal(oid) ||
+		!bytes.Equal(tst.MessageImprint.HashedMessage, digest) {
+		return nil, multihash.DigestMismatchError{Expected: digest, Actual: tst.MessageImprint.HashedMessage}
+	}
+	return &Timestamp{
+		Time:   tst.GenTime,
+		Serial: tst.SerialNumber,
+		Policy: tst.Policy,
+		Token:  token,
+		nonce:  tst.Nonce,
+	}, nil
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional,default:false"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// signedData covers the leading fields of CMS SignedData; certificates and
+// signer infos follow, and are left unparsed.
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapsulatedContentInfo
+}
+
+type encapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"explicit,tag:0"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional,default:false"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
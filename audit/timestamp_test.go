@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trytriangles/multihash"
+)
+
+// fakeTSA answers timestamp requests with unsigned tokens, which is enough
+// to exercise request encoding and response parsing.
+func fakeTSA(t *testing.T, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req timeStampReq
+		if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+			t.Errorf("bad request: %v\n", err)
+			return
+		}
+		resp := timeStampResp{Status: pkiStatusInfo{Status: status}}
+		if status <= 1 {
+			tst, _ := asn1.Marshal(tstInfo{
+				Version:        1,
+				Policy:         asn1.ObjectIdentifier{1, 2, 3},
+				MessageImprint: req.MessageImprint,
+				SerialNumber:   big.NewInt(42),
+				GenTime:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				Nonce:          req.Nonce,
+			})
+			encapsulated, _ := asn1.Marshal(struct {
+				ContentType asn1.ObjectIdentifier
+				Content     []byte `asn1:"explicit,tag:0"`
+			}{oidTSTInfo, tst})
+			signed, _ := asn1.Marshal(struct {
+				Version          int
+				DigestAlgorithms []asn1.ObjectIdentifier `asn1:"set"`
+				EncapContentInfo asn1.RawValue
+				SignerInfos      []asn1.ObjectIdentifier `asn1:"set"`
+			}{3, nil, asn1.RawValue{FullBytes: encapsulated}, nil})
+			token, _ := asn1.Marshal(struct {
+				ContentType asn1.ObjectIdentifier
+				Content     asn1.RawValue
+			}{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signed}})
+			resp.TimeStampToken = asn1.RawValue{FullBytes: token}
+		} else {
+			resp.Status.StatusString = []string{"bad request"}
+		}
+		encoded, err := asn1.Marshal(resp)
+		if err != nil {
+			t.Errorf("encoding response: %v\n", err)
+		}
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(encoded)
+	}))
+}
+
+func Test_RequestTimestamp(t *testing.T) {
+	server := fakeTSA(t, 0)
+	defer server.Close()
+	digest := sha256.Sum256([]byte("SHA256SUMS contents"))
+	timestamp, err := RequestTimestamp(server.URL, nil, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !timestamp.Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) || timestamp.Serial.Int64() != 42 {
+		t.Fatalf("timestamp was %+v\n", timestamp)
+	}
+	if _, err = ParseTimestamp(timestamp.Token, crypto.SHA256, digest[:]); err != nil {
+		t.Fatalf("stored token did not parse: %v\n", err)
+	}
+	other := sha256.Sum256([]byte("other contents"))
+	if _, err = ParseTimestamp(timestamp.Token, crypto.SHA256, other[:]); err == nil {
+		t.Fatal("token accepted for a different digest")
+	}
+	if _, err = ParseTimestamp(timestamp.Token, crypto.MD4, digest[:16]); !errors.Is(err, multihash.ErrHashFunctionNotAvailable) {
+		t.Fatalf("an unsupported hash gave %v\n", err)
+	}
+
+	rejecting := fakeTSA(t, 2)
+	defer rejecting.Close()
+	if _, err = RequestTimestamp(rejecting.URL, nil, crypto.SHA256, digest[:]); !errors.Is(err, ErrTimestampRejected) {
+		t.Fatalf("rejected request gave %v\n", err)
+	}
+}
@@ -0,0 +1,117 @@
+package multihash
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// BlockMap lists the expected digests of consecutive fixed-size blocks of a
+// stream, such as the piece hashes of a torrent or a block map shipped with
+// an update image. The final block may be shorter than BlockSize.
+type BlockMap struct {
+	BlockSize int64
+	// NewHash constructs the hash each block's digest was computed with.
+	NewHash func() hash.Hash
+	Digests [][]byte
+}
+
+// VerifyBlocks hashes data like FromReader while checking each block of it
+// against blocks as the stream progresses. Reading stops at the end of the
+// first block that does not match, which is reported as a
+// BlockMismatchError, rather than after draining the whole corrupt input. A
+// stream with more or fewer blocks than the map is a BlockCountError. A map
+// whose BlockSize is not positive is a BlockSizeError, and one without
+// NewHash gives ErrNoBlockHash, both before anything is read.
+func VerifyBlocks(data io.Reader, blocks BlockMap, hashes ...hash.Hash) (hashset [][]byte, err error) {
+	if blocks.BlockSize <= 0 {
+		return nil, BlockSizeError{Size: blocks.BlockSize}
+	}
+	if blocks.NewHash == nil {
+		return nil, ErrNoBlockHash
+	}
+	verifier := &blockVerifier{blocks: blocks, current: blocks.NewHash()}
+	hashset, err = FromReader(data, append([]hash.Hash{verifier}, hashes...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifier.finish(); err != nil {
+		return nil, err
+	}
+	return hashset[1:], nil
+}
+
+// blockVerifier is a hash.Hash, so that it can ride along in FromReader's
+// pipeline, whose Write fails as soon as a completed block mismatches.
+type blockVerifier struct {
+	blocks  BlockMap
+	current hash.Hash
+	index   int
+	filled  int64
+}
+
+func (v *blockVerifier) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		take := v.blocks.BlockSize - v.filled
+		if int64(len(p)) < take {
+			take = int64(len(p))
+		}
+		v.current.Write(p[:take])
+		v.filled += take
+		n += int(take)
+		p = p[take:]
+		if v.filled == v.blocks.BlockSize {
+			if err = v.check(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// check compares the block accumulated so far against the map and starts
+// the next one.
+func (v *blockVerifier) check() error {
+	if v.index >= len(v.blocks.Digests) {
+		return BlockCountError{Expected: len(v.blocks.Digests), Actual: v.index + 1}
+	}
+	actual := v.current.Sum(nil)
+	if !bytes.Equal(actual, v.blocks.Digests[v.index]) {
+		return BlockMismatchError{
+			Index:    v.index,
+			Offset:   int64(v.index) * v.blocks.BlockSize,
+			Expected: v.blocks.Digests[v.index],
+			Actual:   actual,
+		}
+	}
+	v.index++
+	v.filled = 0
+	v.current.Reset()
+	return nil
+}
+
+// finish checks the trailing partial block, if any, and that the stream
+// covered every block in the map.
+func (v *blockVerifier) finish() error {
+	if v.filled > 0 {
+		if err := v.check(); err != nil {
+			return err
+		}
+	}
+	if v.index != len(v.blocks.Digests) {
+		return BlockCountError{Expected: len(v.blocks.Digests), Actual: v.index}
+	}
+	return nil
+}
+
+func (v *blockVerifier) Sum(b []byte) []byte { return b }
+
+func (v *blockVerifier) Reset() {
+	v.current.Reset()
+	v.index = 0
+	v.filled = 0
+}
+
+func (v *blockVerifier) Size() int { return 0 }
+
+func (v *blockVerifier) BlockSize() int { return int(v.blocks.BlockSize) }
@@ -0,0 +1,81 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"errors"
+	"io"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func blockMap(data []byte, blockSize int) BlockMap {
+	m := BlockMap{BlockSize: int64(blockSize), NewHash: sha1.New}
+	for offset := 0; offset < len(data); offset += blockSize {
+		end := offset + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha1.Sum(data[offset:end])
+		m.Digests = append(m.Digests, sum[:])
+	}
+	return m
+}
+
+func Test_VerifyBlocks(t *testing.T) {
+	data := bytes.Repeat([]byte("block data "), 100000)
+	blocks := blockMap(data, 32768)
+
+	hashset, err := VerifyBlocks(bytes.NewReader(data), blocks, crypto.SHA256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := crypto.SHA256.New()
+	expected.Write(data)
+	if !slicesEqual(hashset[0], expected.Sum(nil)) {
+		t.Fatalf("whole-stream digest was %x\n", hashset[0])
+	}
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[40000] ^= 0xff
+	reader := &countingReader{r: bytes.NewReader(corrupt)}
+	_, err = VerifyBlocks(reader, blocks, crypto.SHA256.New())
+	var mismatch BlockMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Index != 1 {
+		t.Fatalf("expected a mismatch in block 1, got %v\n", err)
+	}
+	if reader.read >= len(corrupt) {
+		t.Fatalf("read all %d bytes despite an early mismatch\n", reader.read)
+	}
+
+	_, err = VerifyBlocks(bytes.NewReader(data[:len(data)-40000]), blocks)
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected a truncated stream to fail, got %v\n", err)
+	}
+	_, err = VerifyBlocks(bytes.NewReader(append(data, 'x')), blocks)
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected an overlong stream to fail, got %v\n", err)
+	}
+	for _, size := range []int64{0, -1} {
+		invalid := BlockMap{BlockSize: size, NewHash: blocks.NewHash, Digests: blocks.Digests}
+		if _, err = VerifyBlocks(bytes.NewReader(data), invalid); !errors.Is(err, ErrInvalidBlockSize) {
+			t.Fatalf("block size %d gave %v\n", size, err)
+		}
+	}
+	if _, err = VerifyBlocks(bytes.NewReader(data), BlockMap{BlockSize: blocks.BlockSize, Digests: blocks.Digests}); !errors.Is(err, ErrNoBlockHash) {
+		t.Fatalf("a block map without NewHash gave %v\n", err)
+	}
+}
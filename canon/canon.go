@@ -0,0 +1,36 @@
+// package canon rewrites structured documents into a canonical form before
+// hashing, so that documents with the same meaning have the same digest
+// whatever their whitespace, key order or number spelling: JSON per the
+// JSON Canonicalization Scheme (RFC 8785) and CBOR per the core
+// deterministic encoding of RFC 8949, section 4.2.1. This is what signing
+// and deduplicating configuration or manifest documents needs; a digest of
+// the raw bytes changes whenever an editor reformats the file.
+package canon
+
+import (
+	"bytes"
+	"hash"
+	"io"
+
+	"github.com/trytriangles/multihash"
+)
+
+// FromJSON canonicalizes the JSON document read from r with JSON and hashes
+// the result, returning the digests in the same order as hashes.
+func FromJSON(r io.Reader, hashes ...hash.Hash) ([][]byte, error) {
+	canonical, err := JSON(r)
+	if err != nil {
+		return nil, err
+	}
+	return multihash.FromReader(bytes.NewReader(canonical), hashes...)
+}
+
+// FromCBOR canonicalizes the CBOR data item in data with CBOR and hashes the
+// result, returning the digests in the same order as hashes.
+func FromCBOR(data []byte, hashes ...hash.Hash) ([][]byte, error) {
+	canonical, err := CBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	return multihash.FromReader(bytes.NewReader(canonical), hashes...)
+}
@@ -0,0 +1,308 @@
+package canon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// maxCBORDepth bounds the nesting CBOR accepts, so that hostile input
+// cannot exhaust the stack.
+const maxCBORDepth = 512
+
+// CBOR returns the core deterministic encoding (RFC 8949, section 4.2.1) of
+// the single CBOR data item in data: every argument in its shortest form,
+// indefinite-length items made definite, map entries sorted by the bytes of
+// their encoded keys, and floating-point values in the shortest of half,
+// single and double precision that represents them exactly, with NaN as
+// the half-precision quiet NaN. Tags are kept. Malformed items, duplicate
+// map keys and trailing data are rejected with a MalformedDocumentError.
+func CBOR(data []byte) ([]byte, error) {
+	d := cborDecoder{data: data}
+	var out bytes.Buffer
+	if err := d.item(&out, 0); err != nil {
+		return nil, err
+	}
+	if d.offset != len(data) {
+		return nil, d.malformed("data after the item")
+	}
+	return out.Bytes(), nil
+}
+
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *cborDecoder) malformed(reason string) error {
+	return MalformedDocumentError{Format: "CBOR", Reason: reason + " at offset " + strconv.Itoa(d.offset)}
+}
+
+// head reads an initial byte and its argument. indefinite is set for
+// additional information 31, in which case argument is meaningless.
+func (d *cborDecoder) head() (major byte, info byte, argument uint64, indefinite bool, err error) {
+	if d.offset >= len(d.data) {
+		return 0, 0, 0, false, d.malformed("unexpected end of data")
+	}
+	initial := d.data[d.offset]
+	d.offset++
+	major, info = initial>>5, initial&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info == 31:
+		return major, info, 0, true, nil
+	case info > 27:
+		return 0, 0, 0, false, d.malformed("reserved additional information")
+	}
+	size := 1 << (info - 24)
+	if d.offset+size > len(d.data) {
+		return 0, 0, 0, false, d.malformed("unexpected end of data")
+	}
+	raw := d.data[d.offset : d.offset+size]
+	d.offset += size
+	switch size {
+	case 1:
+		argument = uint64(raw[0])
+	case 2:
+		argument = uint64(binary.BigEndian.Uint16(raw))
+	case 4:
+		argument = uint64(binary.BigEndian.Uint32(raw))
+	default:
+		argument = binary.BigEndian.Uint64(raw)
+	}
+	return major, info, argument, false, nil
+}
+
+func writeHead(out *bytes.Buffer, major byte, argument uint64) {
+	major <<= 5
+	switch {
+	case argument < 24:
+		out.WriteByte(major | byte(argument))
+	case argument <= math.MaxUint8:
+		out.Write([]byte{major | 24, byte(argument)})
+	case argument <= math.MaxUint16:
+		out.WriteByte(major | 25)
+		binary.Write(out, binary.BigEndian, uint16(argument))
+	case argument <= math.MaxUint32:
+		out.WriteByte(major | 26)
+		binary.Write(out, binary.BigEndian, uint32(argument))
+	default:
+		out.WriteByte(major | 27)
+		binary.Write(out, binary.BigEndian, argument)
+	}
+}
+
+func (d *cborDecoder) item(out *bytes.Buffer, depth int) error {
+	if depth > maxCBORDepth {
+		return d.malformed("nesting too deep")
+	}
+	major, info, argument, indefinite, err := d.head()
+	if err != nil {
+		return err
+	}
+	if indefinite && (major == 0 || major == 1 || major == 6) {
+		return d.malformed("indefinite length on major type " + strconv.Itoa(int(major)))
+	}
+	switch major {
+	case 0, 1:
+		writeHead(out, major, argument)
+	case 2, 3:
+		return d.str(out, major, argument, indefinite)
+	case 4:
+		return d.array(out, argument, indefinite, depth)
+	case 5:
+		return d.mapping(out, argument, indefinite, depth)
+	case 6:
+		writeHead(out, 6, argument)
+		return d.item(out, depth+1)
+	case 7:
+		return d.simple(out, info, argument, indefinite)
+	}
+	return nil
+}
+
+func (d *cborDecoder) str(out *bytes.Buffer, major byte, length uint64, indefinite bool) error {
+	if !indefinite {
+		if length > uint64(len(d.data)-d.offset) {
+			return d.malformed("string longer than data")
+		}
+		writeHead(out, major, length)
+		out.Write(d.data[d.offset : d.offset+int(length)])
+		d.offset += int(length)
+		return nil
+	}
+	var joined []byte
+	for {
+		if d.offset < len(d.data) && d.data[d.offset] == 0xff {
+			d.offset++
+			break
+		}
+		chunkMajor, _, chunkLength, chunkIndefinite, err := d.head()
+		if err != nil {
+			return err
+		}
+		if chunkMajor != major || chunkIndefinite {
+			return d.malformed("bad chunk in indefinite-length string")
+		}
+		if chunkLength > uint64(len(d.data)-d.offset) {
+			return d.malformed("string longer than data")
+		}
+		joined = append(joined, d.data[d.offset:d.offset+int(chunkLength)]...)
+		d.offset += int(chunkLength)
+	}
+	writeHead(out, major, uint64(len(joined)))
+	out.Write(joined)
+	return nil
+}
+
+// next reports whether another element of a container follows, consuming
+// the break code that ends an indefinite-length one.
+func (d *cborDecoder) next(remaining *uint64, indefinite bool) bool {
+	if indefinite {
+		if d.offset < len(d.data) && d.data[d.offset] == 0xff {
+			d.offset++
+			return false
+		}
+		return true
+	}
+	if *remaining == 0 {
+		return false
+	}
+	*remaining--
+	return true
+}
+
+func (d *cborDecoder) array(out *bytes.Buffer, length uint64, indefinite bool, depth int) error {
+	var elements bytes.Buffer
+	count := uint64(0)
+	for d.next(&length, indefinite) {
+		if err := d.item(&elements, depth+1); err != nil {
+			return err
+		}
+		count++
+	}
+	writeHead(out, 4, count)
+	out.Write(elements.Bytes())
+	return nil
+}
+
+func (d *cborDecoder) mapping(out *bytes.Buffer, length uint64, indefinite bool, depth int) error {
+	type entry struct{ key, value []byte }
+	var entries []entry
+	for d.next(&length, indefinite) {
+		var key, value bytes.Buffer
+		if err := d.item(&key, depth+1); err != nil {
+			return err
+		}
+		if err := d.item(&value, depth+1); err != nil {
+			return err
+		}
+		entries = append(entries, entry{key.Bytes(), value.Bytes()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+	for index := 1; index < len(entries); index++ {
+		if bytes.Equal(entries[index-1].key, entries[index].key) {
+			return d.malformed("duplicate map key")
+		}
+	}
+	writeHead(out, 5, uint64(len(entries)))
+	for _, e := range entries {
+		out.Write(e.key)
+		out.Write(e.value)
+	}
+	return nil
+}
+
+func (d *cborDecoder) simple(out *bytes.Buffer, info byte, argument uint64, indefinite bool) error {
+	switch {
+	case indefinite:
+		return d.malformed("unexpected break")
+	case info < 24:
+		out.WriteByte(0xe0 | info)
+	case info == 24:
+		if argument < 32 {
+			return d.malformed("simple value in two-byte form")
+		}
+		out.Write([]byte{0xf8, byte(argument)})
+	case info == 25:
+		writeFloat(out, halfToFloat64(uint16(argument)))
+	case info == 26:
+		writeFloat(out, float64(math.Float32frombits(uint32(argument))))
+	case info == 27:
+		writeFloat(out, math.Float64frombits(argument))
+	}
+	return nil
+}
+
+// writeFloat writes f in the shortest precision that holds it exactly.
+func writeFloat(out *bytes.Buffer, f float64) {
+	if math.IsNaN(f) {
+		out.Write([]byte{0xf9, 0x7e, 0x00})
+		return
+	}
+	if f32 := float32(f); float64(f32) == f {
+		if half, ok := float32ToHalf(f32); ok {
+			out.WriteByte(0xf9)
+			binary.Write(out, binary.BigEndian, half)
+			return
+		}
+		out.WriteByte(0xfa)
+		binary.Write(out, binary.BigEndian, math.Float32bits(f32))
+		return
+	}
+	out.WriteByte(0xfb)
+	binary.Write(out, binary.BigEndian, math.Float64bits(f))
+}
+
+func halfToFloat64(half uint16) float64 {
+	exponent, fraction := int(half>>10&0x1f), float64(half&0x3ff)
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(fraction, -24)
+	case 31:
+		if fraction == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(fraction+1024, exponent-25)
+	}
+	if half&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// float32ToHalf converts f to half precision if it can be done exactly.
+func float32ToHalf(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exponent := int(bits>>23&0xff) - 127
+	fraction := bits & 0x7fffff
+	switch {
+	case math.IsInf(float64(f), 0):
+		return sign | 0x7c00, true
+	case bits&0x7fffffff == 0:
+		return sign, true
+	case exponent >= -14 && exponent <= 15:
+		if fraction&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(exponent+15)<<10 | uint16(fraction>>13), true
+	case exponent >= -24 && exponent < -14:
+		// Subnormal in half precision: the value is m * 2^-24.
+		m := (fraction | 0x800000) >> uint(-exponent-14+13)
+		if float64(m)*math.Ldexp(1, -24) != float64(math.Abs(float64(f))) {
+			return 0, false
+		}
+		return sign | uint16(m), true
+	}
+	return 0, false
+}
@@ -0,0 +1,57 @@
+package canon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func Test_CBOR(t *testing.T) {
+	cases := map[string]string{
+		// Indefinite-length array and string made definite.
+		"9f0102ff":       "820102",
+		"5f4201024103ff": "43010203",
+		// Non-shortest argument.
+		"1805": "05",
+		// Map keys sorted by their encoding.
+		"a2616201616102": "a2616102616201",
+		// Floats in the shortest exact precision.
+		"fb3ff8000000000000": "f93e00",
+		"fb40f86a0000000000": "fa47c35000",
+		"fb3e70000000000000": "f90001",
+		"fb3ff199999999999a": "fb3ff199999999999a",
+		"fa7fc00000":         "f97e00",
+		// Tags are kept.
+		"c11a514b67b0": "c11a514b67b0",
+	}
+	for input, expected := range cases {
+		data, _ := hex.DecodeString(input)
+		canonical, err := CBOR(data)
+		if err != nil {
+			t.Fatalf("%s: %v\n", input, err)
+		}
+		if hex.EncodeToString(canonical) != expected {
+			t.Fatalf("%s: expected %s, got %x\n", input, expected, canonical)
+		}
+	}
+
+	for _, input := range []string{"a201010102", "82", "1c", "0101", "ff"} {
+		data, _ := hex.DecodeString(input)
+		if _, err := CBOR(data); !errors.Is(err, ErrMalformedDocument) {
+			t.Fatalf("expected %s to be rejected, got %v\n", input, err)
+		}
+	}
+
+	a, _ := hex.DecodeString("bf61620161611802ff")
+	b, _ := hex.DecodeString("a2616102616201")
+	digestA, err := FromCBOR(a, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	digestB, _ := FromCBOR(b, sha256.New())
+	if !bytes.Equal(digestA[0], digestB[0]) {
+		t.Fatal("equivalent documents have different digests")
+	}
+}
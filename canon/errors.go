@@ -0,0 +1,22 @@
+package canon
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrMalformedDocument = errcode.New(errcode.Malformed, "malformed document")
+
+type MalformedDocumentError struct {
+	Format string
+	Reason string
+}
+
+func (e MalformedDocumentError) Error() string {
+	return "malformed " + e.Format + " document: " + e.Reason
+}
+
+func (e MalformedDocumentError) Is(target error) bool {
+	return target == ErrMalformedDocument
+}
+
+func (e MalformedDocumentError) Code() errcode.Code {
+	return errcode.Malformed
+}
@@ -0,0 +1,215 @@
+package canon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// JSON returns the RFC 8785 canonical form of the single JSON document read
+// from r: no insignificant whitespace, object members sorted by the UTF-16
+// code units of their names, strings escaped minimally, and numbers written
+// as ECMAScript writes the nearest IEEE 754 double. Documents with
+// duplicate member names, numbers out of double range or trailing data are
+// rejected with a MalformedDocumentError. Invalid UTF-8 and lone surrogate
+// escapes, which RFC 8785 also forbids, are instead replaced with U+FFFD,
+// as encoding/json does when decoding.
+//
+// The document is parsed token by token, so the only memory held beyond the
+// output is the members of the object being sorted.
+func JSON(r io.Reader) ([]byte, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var out bytes.Buffer
+	if err := writeJSONValue(&out, dec); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, MalformedDocumentError{Format: "JSON", Reason: "data after the document"}
+	}
+	return out.Bytes(), nil
+}
+
+func writeJSONValue(out *bytes.Buffer, dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return jsonError(err)
+	}
+	switch v := token.(type) {
+	case json.Delim:
+		if v == '[' {
+			return writeJSONArray(out, dec)
+		}
+		if v == '{' {
+			return writeJSONObject(out, dec)
+		}
+		return MalformedDocumentError{Format: "JSON", Reason: "unexpected " + string(v)}
+	case string:
+		return writeJSONString(out, v)
+	case json.Number:
+		return writeJSONNumber(out, v)
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+func writeJSONArray(out *bytes.Buffer, dec *json.Decoder) error {
+	out.WriteByte('[')
+	for first := true; dec.More(); first = false {
+		if !first {
+			out.WriteByte(',')
+		}
+		if err := writeJSONValue(out, dec); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+	out.WriteByte(']')
+	return nil
+}
+
+type jsonMember struct {
+	name  []uint16
+	value []byte
+}
+
+func writeJSONObject(out *bytes.Buffer, dec *json.Decoder) error {
+	var members []jsonMember
+	seen := map[string]bool{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return jsonError(err)
+		}
+		name := token.(string)
+		if seen[name] {
+			return MalformedDocumentError{Format: "JSON", Reason: "duplicate member " + strconv.Quote(name)}
+		}
+		seen[name] = true
+		var member bytes.Buffer
+		if err = writeJSONString(&member, name); err != nil {
+			return err
+		}
+		member.WriteByte(':')
+		if err = writeJSONValue(&member, dec); err != nil {
+			return err
+		}
+		members = append(members, jsonMember{name: utf16.Encode([]rune(name)), value: member.Bytes()})
+	}
+	if _, err := dec.Token(); err != nil {
+		return jsonError(err)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i].name, members[j].name
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	out.WriteByte('{')
+	for index, member := range members {
+		if index > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(member.value)
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) error {
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				out.WriteString(`\u00`)
+				out.WriteByte("0123456789abcdef"[r>>4])
+				out.WriteByte("0123456789abcdef"[r&0xf])
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return nil
+}
+
+func writeJSONNumber(out *bytes.Buffer, n json.Number) error {
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || math.IsInf(f, 0) {
+		return MalformedDocumentError{Format: "JSON", Reason: "number " + string(n) + " out of range"}
+	}
+	out.WriteString(formatECMAScript(f))
+	return nil
+}
+
+// formatECMAScript formats f as ECMAScript's Number.prototype.toString does,
+// from the shortest decimal digits that round-trip.
+func formatECMAScript(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// Shortest digits d.ddd and exponent e; n is the ECMAScript exponent,
+	// the position of the decimal point relative to the digits.
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exponent)
+	k, n := len(digits), e+1
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	result := sign + digits[:1]
+	if k > 1 {
+		result += "." + digits[1:]
+	}
+	if n-1 >= 0 {
+		return result + "e+" + strconv.Itoa(n-1)
+	}
+	return result + "e" + strconv.Itoa(n-1)
+}
+
+func jsonError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return MalformedDocumentError{Format: "JSON", Reason: "unexpected end of document"}
+	}
+	if _, ok := err.(*json.SyntaxError); ok {
+		return MalformedDocumentError{Format: "JSON", Reason: err.Error()}
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package canon
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func Test_JSON(t *testing.T) {
+	// The example of RFC 8785, section 3.2.2.
+	input := `{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`
+	expected := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	canonical, err := JSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(canonical) != expected {
+		t.Fatalf("expected\n%s\ngot\n%s\n", expected, canonical)
+	}
+
+	// Members sort by UTF-16 code units, which puts U+1F600 before U+FB33.
+	canonical, err = JSON(strings.NewReader(`{"דּ":1,"😀":2,"a":3}`))
+	if err != nil || string(canonical) != "{\"a\":3,\"\U0001f600\":2,\"דּ\":1}" {
+		t.Fatalf("unexpected member order %s, %v\n", canonical, err)
+	}
+
+	for _, input := range []string{`{"a":1,"a":2}`, `[1,2`, `1e400`, `{} {}`} {
+		if _, err = JSON(strings.NewReader(input)); !errors.Is(err, ErrMalformedDocument) {
+			t.Fatalf("expected %s to be rejected, got %v\n", input, err)
+		}
+	}
+}
+
+func Test_formatECMAScript(t *testing.T) {
+	// From RFC 8785, appendix B.
+	cases := map[float64]string{
+		0:                       "0",
+		math.Copysign(0, -1):    "0",
+		5e-324:                  "5e-324",
+		-1.7976931348623157e308: "-1.7976931348623157e+308",
+		9007199254740992:        "9007199254740992",
+		295147905179352830000:   "295147905179352830000",
+		1e21:                    "1e+21",
+		1e-7:                    "1e-7",
+		0.000001:                "0.000001",
+		333333333.3333332:       "333333333.3333332",
+		1.0000000000000002:      "1.0000000000000002",
+	}
+	for f, expected := range cases {
+		if actual := formatECMAScript(f); actual != expected {
+			t.Fatalf("%v: expected %s, got %s\n", f, expected, actual)
+		}
+	}
+}
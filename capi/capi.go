@@ -0,0 +1,96 @@
+// package main builds a C shared library exposing multihash to other
+// languages, so that Python, Rust or C tools in a mixed-language pipeline
+// can reuse the same engine and algorithm names. Build it with
+//
+//	go build -buildmode=c-shared -o libmultihash.so ./capi
+//
+// which also writes libmultihash.h declaring:
+//
+//	long long mh_hash_file(char *path, char *algorithms,
+//	                       unsigned char *out, size_t out_len, char **err);
+//	long long mh_hash_buffer(void *data, size_t len, char *algorithms,
+//	                         unsigned char *out, size_t out_len, char **err);
+//	int mh_digest_size(char *algorithm);
+//	char *mh_algorithms(void);
+//	void mh_free(void *p);
+//
+// algorithms is a comma-separated list of names as accepted by
+// multihash.New, e.g. "md5,sha256". The hash functions write the digests
+// one after another, in that order, into out, and return their total length.
+// As with snprintf, a result larger than out_len means nothing was written
+// and the call should be repeated with a larger buffer; mh_digest_size gives
+// the length of each digest for splitting the output. On failure they return
+// -1 and, if err is not NULL, set *err to a message the caller frees with
+// mh_free. mh_algorithms returns the available names, one per line, also to
+// be freed with mh_free.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"strings"
+	"unsafe"
+
+	"github.com/trytriangles/multihash"
+)
+
+const maxInt = int(^uint(0) >> 1)
+
+//export mh_hash_file
+func mh_hash_file(path *C.char, algorithms *C.char, out *C.uchar, outLen C.size_t, errOut **C.char) C.longlong {
+	digests, err := hashFile(C.GoString(path), C.GoString(algorithms))
+	return deliver(digests, err, out, outLen, errOut)
+}
+
+//export mh_hash_buffer
+func mh_hash_buffer(data unsafe.Pointer, length C.size_t, algorithms *C.char, out *C.uchar, outLen C.size_t, errOut **C.char) C.longlong {
+	if uint64(length) > uint64(maxInt) {
+		return deliver(nil, ErrBufferTooLarge, out, outLen, errOut)
+	}
+	// The buffer is hashed in place rather than copied; nothing keeps it
+	// once the call returns.
+	var contents []byte
+	if length > 0 {
+		contents = unsafe.Slice((*byte)(data), int(length))
+	}
+	digests, err := hashReader(bytes.NewReader(contents), C.GoString(algorithms))
+	return deliver(digests, err, out, outLen, errOut)
+}
+
+//export mh_digest_size
+func mh_digest_size(algorithm *C.char) C.int {
+	h, err := multihash.New(C.GoString(algorithm))
+	if err != nil {
+		return -1
+	}
+	return C.int(h.Size())
+}
+
+//export mh_algorithms
+func mh_algorithms() *C.char {
+	return C.CString(strings.Join(multihash.Algorithms(), "\n"))
+}
+
+//export mh_free
+func mh_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// deliver copies digests into the caller's buffer if they fit, or reports
+// err through errOut.
+func deliver(digests []byte, err error, out *C.uchar, outLen C.size_t, errOut **C.char) C.longlong {
+	if err != nil {
+		if errOut != nil {
+			*errOut = C.CString(err.Error())
+		}
+		return -1
+	}
+	if len(digests) <= int(outLen) && len(digests) > 0 {
+		copy(unsafe.Slice((*byte)(unsafe.Pointer(out)), int(outLen)), digests)
+	}
+	return C.longlong(len(digests))
+}
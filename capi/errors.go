@@ -0,0 +1,5 @@
+package main
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrBufferTooLarge = errcode.New(errcode.InvalidArgument, "buffer too large to hash")
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	_ "github.com/trytriangles/multihash/mailru"
+	_ "github.com/trytriangles/multihash/quickxor"
+)
+
+// main is required of a c-shared build but never runs.
+func main() {}
+
+func hashFile(filename, algorithms string) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return hashReader(f, algorithms)
+}
+
+// hashReader hashes r with each of the comma-separated algorithms, returning
+// the digests concatenated in the same order.
+func hashReader(r io.Reader, algorithms string) ([]byte, error) {
+	hashes, err := multihash.NewAll(strings.Split(algorithms, ",")...)
+	if err != nil {
+		return nil, err
+	}
+	digests, err := multihash.FromReader(r, hashes...)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, digest := range digests {
+		out = append(out, digest...)
+	}
+	return out, nil
+}
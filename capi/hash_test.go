@@ -0,0 +1,27 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+func Test_hashReader(t *testing.T) {
+	digests, err := hashReader(strings.NewReader("Sample text file\n"), "md5,sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md5Sum := md5.Sum([]byte("Sample text file\n"))
+	sha256Sum := sha256.Sum256([]byte("Sample text file\n"))
+	expected := append(md5Sum[:], sha256Sum[:]...)
+	if string(digests) != string(expected) {
+		t.Fatalf("digests were %x, expected %x\n", digests, expected)
+	}
+	if _, err = hashReader(strings.NewReader(""), "md5,nonexistent"); !errors.Is(err, multihash.ErrHashFunctionNotAvailable) {
+		t.Fatalf("unknown algorithm gave %v\n", err)
+	}
+}
@@ -0,0 +1,118 @@
+// package cas stores data in a content-addressable layout: each blob lives at
+// a path derived from its digest, so identical content is stored once and
+// any blob can be found, and checked, by its digest alone.
+package cas
+
+import (
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/atomicfile"
+)
+
+// DefaultAlgorithm names blobs when a Store's Algorithm is empty.
+const DefaultAlgorithm = "sha256"
+
+// Store is a content-addressable store rooted at a directory. The zero value
+// of every field other than Root is usable.
+type Store struct {
+	Root string
+	// Algorithm is the registered multihash algorithm whose hex digest
+	// names each blob.
+	Algorithm string
+	// FanOut gives the widths, in hex characters, of the directory levels
+	// taken from the start of the digest. {2, 2} stores digest abcdef... at
+	// ab/cd/abcdef..., keeping directories small in large stores.
+	FanOut []int
+	// Extra lists further algorithms computed in the same pass as Algorithm,
+	// whose digests Put also returns.
+	Extra []string
+	// Durability controls syncing as Put publishes each blob; see
+	// atomicfile. The zero value syncs every blob before naming it.
+	Durability atomicfile.Durability
+}
+
+func (s *Store) algorithm() string {
+	if s.Algorithm == "" {
+		return DefaultAlgorithm
+	}
+	return s.Algorithm
+}
+
+// Path returns where the blob with the given digest under s.Algorithm is,
+// or would be, stored.
+func (s *Store) Path(digest []byte) string {
+	name := hex.EncodeToString(digest)
+	parts := []string{s.Root}
+	offset := 0
+	for _, width := range s.FanOut {
+		if offset+width > len(name) {
+			break
+		}
+		parts = append(parts, name[offset:offset+width])
+		offset += width
+	}
+	return filepath.Join(append(parts, name)...)
+}
+
+// Put streams data into a temporary file in the store while hashing it,
+// then renames the file into place under its digest, as atomicfile does.
+// Nothing appears under a digest-derived name until its content is complete
+// and synced as s.Durability requires, so readers never observe partial
+// blobs. If the blob is already present the new copy is discarded.
+//
+// The returned digests are s.Algorithm's followed by those of s.Extra, in
+// order.
+func (s *Store) Put(data io.Reader) (digests [][]byte, err error) {
+	hashes, err := multihash.NewAll(append([]string{s.algorithm()}, s.Extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(s.Root, 0o755); err != nil {
+		return nil, err
+	}
+	temp, err := atomicfile.Create(filepath.Join(s.Root, ".incoming"), s.Durability)
+	if err != nil {
+		return nil, err
+	}
+	defer temp.Abort()
+	digests, err = multihash.FromReader(io.TeeReader(data, temp), hashes...)
+	if err != nil {
+		return nil, err
+	}
+	target := s.Path(digests[0])
+	if _, statErr := os.Stat(target); statErr == nil {
+		return digests, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+	temp.SetName(target)
+	if err = temp.Close(); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// Open opens the blob stored under digest. The content is verified against
+// the digest as it is read: if the blob has been corrupted on disk, reading
+// it to the end yields a multihash.DigestMismatchError instead of io.EOF.
+func (s *Store) Open(digest []byte) (io.ReadCloser, error) {
+	h, err := multihash.New(s.algorithm())
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.Path(digest))
+	if err != nil {
+		return nil, err
+	}
+	return verifiedBlob{multihash.NewVerifyingReader(f, h, digest), f}, nil
+}
+
+type verifiedBlob struct {
+	io.Reader
+	io.Closer
+}
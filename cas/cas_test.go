@@ -0,0 +1,75 @@
+package cas
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+
+	_ "crypto/md5"
+	_ "crypto/sha256"
+)
+
+func Test_Put(t *testing.T) {
+	store := &Store{Root: t.TempDir(), FanOut: []int{2, 2}, Extra: []string{"md5"}}
+	data := []byte("Sample text file\n")
+	digests, err := store.Put(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "8bb5bc05618f1036a063bbf83cf74cca163a60343791c0c930acc31bf0c090ea"
+	if hex.EncodeToString(digests[0]) != name || hex.EncodeToString(digests[1]) != "5530de3071a1a9035478defcc1d586e1" {
+		t.Fatalf("unexpected digests %x\n", digests)
+	}
+	expectedPath := filepath.Join(store.Root, "8b", "b5", name)
+	if store.Path(digests[0]) != expectedPath {
+		t.Fatalf("blob path was %s, expected %s\n", store.Path(digests[0]), expectedPath)
+	}
+	stored, err := os.ReadFile(expectedPath)
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Fatalf("stored blob was %q, %v\n", stored, err)
+	}
+
+	if _, err = store.Put(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(store.Root, ".incoming-*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v\n", leftovers)
+	}
+}
+
+func Test_Open(t *testing.T) {
+	store := &Store{Root: t.TempDir()}
+	data := bytes.Repeat([]byte("blob "), 30000)
+	digests, err := store.Put(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := store.Open(digests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil || !bytes.Equal(read, data) {
+		t.Fatalf("read back %d bytes, %v\n", len(read), err)
+	}
+
+	if err = os.WriteFile(store.Path(digests[0]), data[1:], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	blob, err = store.Open(digests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blob.Close()
+	if _, err = io.ReadAll(blob); !errors.Is(err, multihash.ErrDigestMismatch) {
+		t.Fatalf("expected corruption to be detected, got %v\n", err)
+	}
+}
@@ -0,0 +1,22 @@
+package cas
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrCollision = errcode.New(errcode.Conflict, "different content at digest-derived path")
+
+type CollisionError struct {
+	Path     string
+	Existing string
+}
+
+func (e CollisionError) Error() string {
+	return e.Path + ": different content already at " + e.Existing
+}
+
+func (e CollisionError) Is(target error) bool {
+	return target == ErrCollision
+}
+
+func (e CollisionError) Code() errcode.Code {
+	return errcode.Conflict
+}
@@ -0,0 +1,158 @@
+package cas
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/trytriangles/multihash"
+)
+
+// OrganizeMode says how Organize places files into the store.
+type OrganizeMode int
+
+const (
+	// Move renames each file to its digest-derived path.
+	Move OrganizeMode = iota
+	// Link hard-links each file at its digest-derived path, leaving the
+	// original where it is.
+	Link
+)
+
+// Placement records where Organize put one file.
+type Placement struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Duplicate is set when To already held identical content, in which
+	// case the file at From was left untouched.
+	Duplicate bool `json:"duplicate,omitempty"`
+	Linked    bool `json:"linked,omitempty"`
+}
+
+// Mapping is the record of an Organize run. Written out with WriteTo, it
+// can be read back with ReadMapping and reversed with Undo.
+type Mapping []Placement
+
+// Organize hashes each of paths and moves or links it into the store at its
+// digest-derived path, as for a filing scheme sorting photos into
+// sha256-prefixed buckets. If keepExtension is set the original file
+// extension is appended to the stored name.
+//
+// When the target already exists its contents are compared byte for byte:
+// identical content is recorded as a Duplicate and the source left alone;
+// different content under the same name is a CollisionError and stops the
+// run. The returned Mapping covers every file placed before any error.
+func (s *Store) Organize(paths []string, mode OrganizeMode, keepExtension bool) (Mapping, error) {
+	var mapping Mapping
+	for _, path := range paths {
+		h, err := multihash.New(s.algorithm())
+		if err != nil {
+			return mapping, err
+		}
+		digests, err := multihash.FromFile(path, h)
+		if err != nil {
+			return mapping, err
+		}
+		target := s.Path(digests[0])
+		if keepExtension {
+			target += filepath.Ext(path)
+		}
+		placement := Placement{From: path, To: target, Linked: mode == Link}
+		if _, err = os.Stat(target); err == nil {
+			equal, err := multihash.EqualFiles(path, target, s.algorithm(), true)
+			if err != nil {
+				return mapping, err
+			}
+			if !equal {
+				return mapping, CollisionError{Path: path, Existing: target}
+			}
+			placement.Duplicate = true
+			mapping = append(mapping, placement)
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return mapping, err
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return mapping, err
+		}
+		if mode == Link {
+			err = os.Link(path, target)
+		} else {
+			err = os.Rename(path, target)
+		}
+		if err != nil {
+			return mapping, err
+		}
+		mapping = append(mapping, placement)
+	}
+	return mapping, nil
+}
+
+// Undo reverses an Organize run: moved files are moved back to where they
+// came from and links are removed. Duplicates were never touched and are
+// skipped. Placements are undone in reverse order.
+func (m Mapping) Undo() error {
+	for i := len(m) - 1; i >= 0; i-- {
+		placement := m[i]
+		switch {
+		case placement.Duplicate:
+			continue
+		case placement.Linked:
+			if err := os.Remove(placement.To); err != nil {
+				return err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(placement.From), 0o755); err != nil {
+				return err
+			}
+			if err := os.Rename(placement.To, placement.From); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the mapping as JSON lines, one placement per line.
+func (m Mapping) WriteTo(w io.Writer) (n int64, err error) {
+	counter := &countingWriter{w: w}
+	encoder := json.NewEncoder(counter)
+	for _, placement := range m {
+		if err = encoder.Encode(placement); err != nil {
+			break
+		}
+	}
+	return counter.n, err
+}
+
+// ReadMapping reads a mapping written by Mapping.WriteTo.
+func ReadMapping(r io.Reader) (Mapping, error) {
+	var mapping Mapping
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var placement Placement
+		err := decoder.Decode(&placement)
+		if err == io.EOF {
+			return mapping, nil
+		}
+		if err != nil {
+			return mapping, err
+		}
+		mapping = append(mapping, placement)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
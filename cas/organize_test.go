@@ -0,0 +1,69 @@
+package cas
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Organize(t *testing.T) {
+	source := t.TempDir()
+	store := &Store{Root: filepath.Join(t.TempDir(), "by-digest"), FanOut: []int{2}}
+	var paths []string
+	for name, contents := range map[string]string{"a.jpg": "photo one", "b.jpg": "photo two", "c.jpg": "photo one"} {
+		path := filepath.Join(source, name)
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	mapping, err := store.Organize(paths, Move, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicates := 0
+	for _, placement := range mapping {
+		if placement.Duplicate {
+			duplicates++
+			continue
+		}
+		if filepath.Ext(placement.To) != ".jpg" {
+			t.Fatalf("extension was not kept: %s\n", placement.To)
+		}
+		if _, err := os.Stat(placement.From); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s was not moved\n", placement.From)
+		}
+	}
+	if duplicates != 1 {
+		t.Fatalf("expected one duplicate, mapping was %+v\n", mapping)
+	}
+
+	var saved bytes.Buffer
+	if _, err = mapping.WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ReadMapping(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = restored.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("%s was not restored: %v\n", path, err)
+		}
+	}
+
+	// Plant unrelated content where a.jpg would go.
+	mapping, _ = store.Organize(paths[:1], Link, true)
+	target := mapping[0].To
+	mapping.Undo()
+	os.WriteFile(target, []byte("something else"), 0o644)
+	if _, err = store.Organize(paths[:1], Link, true); !errors.Is(err, ErrCollision) {
+		t.Fatalf("expected a collision, got %v\n", err)
+	}
+}
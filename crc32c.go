@@ -0,0 +1,50 @@
+package multihash
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// NewCRC32C returns a hash computing the CRC32C, the Castagnoli CRC, as
+// New("crc32c") does, sharing the package's table.
+func NewCRC32C() hash.Hash32 {
+	return crc32.New(castagnoliTable)
+}
+
+// VerifyCRC32C hashes data like FromReader, additionally computing its
+// CRC32C in the same pass and comparing it against expected, as reported by
+// a storage service (a GCS x-goog-hash crc32c value, an S3
+// x-amz-checksum-crc32c header, and so on).
+//
+// If the CRC32C does not match, the data is known to be corrupt without
+// looking any further, so the digests are discarded and a
+// DigestMismatchError holding the two big-endian CRCs is returned. Only when
+// the cheap check passes are the digests of hashes returned, for the caller
+// to compare against whatever cryptographic values it trusts.
+func VerifyCRC32C(data io.Reader, expected uint32, hashes ...hash.Hash) (hashset [][]byte, err error) {
+	crc := NewCRC32C()
+	hashset, err = FromReader(data, append([]hash.Hash{crc}, hashes...)...)
+	if err != nil {
+		return nil, err
+	}
+	if crc.Sum32() != expected {
+		return nil, DigestMismatchError{Expected: binary.BigEndian.AppendUint32(nil, expected), Actual: hashset[0]}
+	}
+	return hashset[1:], nil
+}
+
+// ParseCRC32C decodes a CRC32C as storage services report it: base64 of the
+// four big-endian bytes, as used by GCS and S3, or eight hex digits.
+func ParseCRC32C(s string) (uint32, error) {
+	if raw, err := base64.StdEncoding.DecodeString(s); err == nil && len(raw) == 4 {
+		return binary.BigEndian.Uint32(raw), nil
+	}
+	if raw, err := hex.DecodeString(s); err == nil && len(raw) == 4 {
+		return binary.BigEndian.Uint32(raw), nil
+	}
+	return 0, MalformedDigestError{Text: s}
+}
@@ -0,0 +1,55 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+func Test_VerifyCRC32C(t *testing.T) {
+	data, err := os.ReadFile("testing/text1.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crc, err := New("crc32c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crc.Write(data)
+	encoded := base64.StdEncoding.EncodeToString(crc.Sum(nil))
+	expected, err := ParseCRC32C(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashset, err := VerifyCRC32C(bytes.NewReader(data), expected, crypto.SHA256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashset) != 1 || len(hashset[0]) != crypto.SHA256.Size() {
+		t.Fatalf("unexpected digests %x\n", hashset)
+	}
+
+	hashset, err = VerifyCRC32C(bytes.NewReader(data), expected+1, crypto.SHA256.New())
+	if !errors.Is(err, ErrDigestMismatch) || hashset != nil {
+		t.Fatalf("expected a mismatch and no digests, got %x, %v\n", hashset, err)
+	}
+
+	if _, err = ParseCRC32C("not a crc"); !errors.Is(err, ErrMalformedDigest) {
+		t.Fatalf("expected a malformed digest error, got %v\n", err)
+	}
+}
+
+func Test_NewCRC32C(t *testing.T) {
+	// The check value of CRC-32C, from the catalogue of parametrised CRCs.
+	crc := NewCRC32C()
+	crc.Write([]byte("123456789"))
+	if crc.Sum32() != 0xe3069283 {
+		t.Fatalf("CRC32C of \"123456789\" was %08x, expected e3069283\n", crc.Sum32())
+	}
+}
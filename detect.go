@@ -0,0 +1,98 @@
+package multihash
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// bareDigestAlgorithms lists, by digest size in bytes, the algorithms a bare
+// digest of that size is taken to be. Each size maps to the algorithm
+// checksums of that size are conventionally published with; rarer algorithms
+// of the same size, such as SHA3-256, need an explicit prefix. Sizes shared
+// by equally common algorithms list all of them, and a bare digest of such a
+// size is ambiguous.
+var bareDigestAlgorithms = map[int][]string{
+	4:  {"crc32", "crc32c"},
+	16: {"md5"},
+	20: {"sha1"},
+	28: {"sha224"},
+	32: {"sha256"},
+	48: {"sha384"},
+	64: {"sha512"},
+}
+
+// ParseDigest works out the algorithm and value of a checksum string in any
+// of the forms it is commonly published in, so that users can paste whatever
+// they were given:
+//
+//   - "sha256:9f86d0…", as in OCI image references, for any name New
+//     accepts;
+//   - "sha256-n4bQgY…", a Subresource Integrity value; see ParseSRI;
+//   - a bare hex digest, e.g. 32 hex digits for MD5 or 64 for SHA-256;
+//   - a bare base64 digest, as in Content-MD5 headers.
+//
+// A bare digest consisting only of hex digits is always read as hex. A bare
+// digest whose size fits more than one common algorithm, such as a 4-byte
+// CRC, gives an AmbiguousDigestError listing the candidates; one whose size
+// fits none gives a MalformedDigestError.
+func ParseDigest(text string) (algorithm string, digest []byte, err error) {
+	text = strings.TrimSpace(text)
+	if name, encoded, found := strings.Cut(text, ":"); found {
+		h, err := New(strings.ToLower(name))
+		if err != nil {
+			return "", nil, err
+		}
+		digest, err = hex.DecodeString(encoded)
+		if err != nil || len(digest) != h.Size() {
+			return "", nil, MalformedDigestError{Text: text}
+		}
+		return strings.ToLower(name), digest, nil
+	}
+	if prefix, _, found := strings.Cut(text, "-"); found {
+		for _, name := range sriAlgorithms {
+			if prefix == name {
+				return ParseSRI(text)
+			}
+		}
+	}
+	if digest, err = hex.DecodeString(text); err != nil {
+		if digest, err = base64.StdEncoding.DecodeString(text); err != nil {
+			if digest, err = base64.URLEncoding.DecodeString(text); err != nil {
+				return "", nil, MalformedDigestError{Text: text}
+			}
+		}
+	}
+	candidates := bareDigestAlgorithms[len(digest)]
+	switch len(candidates) {
+	case 0:
+		return "", nil, MalformedDigestError{Text: text}
+	case 1:
+		return candidates[0], digest, nil
+	}
+	return "", nil, AmbiguousDigestError{Text: text, Candidates: candidates}
+}
+
+// VerifyDigest hashes data with the algorithm ParseDigest detects in
+// expected, returning that algorithm, and a DigestMismatchError if data does
+// not match.
+func VerifyDigest(data io.Reader, expected string) (algorithm string, err error) {
+	algorithm, digest, err := ParseDigest(expected)
+	if err != nil {
+		return "", err
+	}
+	h, err := New(algorithm)
+	if err != nil {
+		return algorithm, err
+	}
+	hashset, err := FromReader(data, h)
+	if err != nil {
+		return algorithm, err
+	}
+	if !bytes.Equal(hashset[0], digest) {
+		return algorithm, DigestMismatchError{Expected: digest, Actual: hashset[0]}
+	}
+	return algorithm, nil
+}
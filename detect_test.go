@@ -0,0 +1,54 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_ParseDigest(t *testing.T) {
+	data := []byte("Sample text file\n")
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	for _, tc := range []struct {
+		text, algorithm string
+		digest          []byte
+	}{
+		{hex.EncodeToString(md5Sum[:]), "md5", md5Sum[:]},
+		{strings.ToUpper(hex.EncodeToString(sha256Sum[:])), "sha256", sha256Sum[:]},
+		{" " + base64.StdEncoding.EncodeToString(md5Sum[:]) + "\n", "md5", md5Sum[:]},
+		{"sha256:" + hex.EncodeToString(sha256Sum[:]), "sha256", sha256Sum[:]},
+		{"MD5:" + hex.EncodeToString(md5Sum[:]), "md5", md5Sum[:]},
+		{FormatSRI("sha256", sha256Sum[:]), "sha256", sha256Sum[:]},
+	} {
+		algorithm, digest, err := ParseDigest(tc.text)
+		if err != nil {
+			t.Fatalf("%q: %v\n", tc.text, err)
+		}
+		if algorithm != tc.algorithm || !slicesEqual(digest, tc.digest) {
+			t.Fatalf("%q parsed as %s %x\n", tc.text, algorithm, digest)
+		}
+	}
+
+	if _, _, err := ParseDigest("d87f7e0c"); !errors.Is(err, ErrAmbiguousDigest) {
+		t.Fatalf("bare CRC gave %v\n", err)
+	}
+	if _, _, err := ParseDigest("abcdef"); !errors.Is(err, ErrMalformedDigest) {
+		t.Fatalf("digest of no known size gave %v\n", err)
+	}
+	if _, _, err := ParseDigest("sha256:abcd"); !errors.Is(err, ErrMalformedDigest) {
+		t.Fatalf("short prefixed digest gave %v\n", err)
+	}
+
+	algorithm, err := VerifyDigest(strings.NewReader(string(data)), hex.EncodeToString(sha256Sum[:]))
+	if err != nil || algorithm != "sha256" {
+		t.Fatalf("VerifyDigest gave %s, %v\n", algorithm, err)
+	}
+	if _, err = VerifyDigest(strings.NewReader("other"), hex.EncodeToString(md5Sum[:])); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("VerifyDigest on other data gave %v\n", err)
+	}
+}
@@ -0,0 +1,414 @@
+// package digestfile reads and writes a compact, self-describing binary
+// container of digests for archival: the size of the data, its digests
+// under any number of named algorithms, an optional map of per-block
+// digests for locating damage, and any number of signatures. It is kept as
+// a file beside the data or appended to the data itself, and is a durable
+// alternative to loose text checksum files, whose format must be guessed
+// and whose damage goes unnoticed.
+//
+// The encoding is, with every integer an unsigned varint and every string
+// or byte field prefixed with its length:
+//
+//	"MHDC"                      magic
+//	1 byte                      version, 1
+//	size                        bytes of data described
+//	count, then count names     algorithms
+//	count digests               one per algorithm
+//	block size                  0 if there is no block map
+//	  algorithm index           if a block size was given
+//	  count, then count digests
+//	count signatures            each a key ID and a signature over all of
+//	                            the above, from the magic on
+//	4 bytes                     big-endian CRC-32C of all the above
+//
+// A container appended to data is followed by an 8-byte big-endian length
+// of the container and the magic "MHDE", so that it can be found from the
+// end of the file.
+package digestfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"io"
+	"math"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/audit"
+)
+
+const (
+	magic         = "MHDC"
+	embeddedMagic = "MHDE"
+	version       = 1
+	footerSize    = 8 + len(embeddedMagic)
+)
+
+// Container describes one piece of data.
+type Container struct {
+	Size       int64
+	Algorithms []string
+	// Digests are of the whole data, in the order of Algorithms.
+	Digests [][]byte
+	// Blocks, if set, holds per-block digests.
+	Blocks     *Blocks
+	Signatures []Signature
+}
+
+// Blocks is a map of the digests of consecutive blocks of the data, the last
+// of which may be short.
+type Blocks struct {
+	Size int64
+	// Algorithm indexes Container.Algorithms.
+	Algorithm int
+	Digests   [][]byte
+}
+
+// Signature is a signature over a container's contents, as returned by
+// Payload.
+type Signature struct {
+	KeyID     string
+	Signature []byte
+}
+
+// Compute reads data and returns a container of its size and digests under
+// algorithms, with a block map under the first algorithm if blockSize is
+// positive.
+func Compute(data io.Reader, blockSize int64, algorithms ...string) (*Container, error) {
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	c := &Container{Algorithms: algorithms}
+	var blocks *blockHasher
+	if blockSize > 0 {
+		if len(algorithms) == 0 {
+			return nil, MalformedContainerError{Reason: "a block map needs an algorithm"}
+		}
+		first, _ := multihash.New(algorithms[0])
+		blocks = &blockHasher{size: blockSize, h: first}
+		data = io.TeeReader(data, blocks)
+	}
+	counter := &countingReader{r: data}
+	if c.Digests, err = multihash.FromReader(counter, hashes...); err != nil {
+		return nil, err
+	}
+	c.Size = counter.n
+	if blocks != nil {
+		blocks.flush()
+		c.Blocks = &Blocks{Size: blockSize, Digests: blocks.digests}
+	}
+	return c, nil
+}
+
+// Payload returns the encoding of c without its signatures and checksum:
+// the bytes its signatures are over.
+func (c *Container) Payload() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	buf.WriteByte(version)
+	putUvarint(&buf, uint64(c.Size))
+	putUvarint(&buf, uint64(len(c.Algorithms)))
+	for _, algorithm := range c.Algorithms {
+		putBytes(&buf, []byte(algorithm))
+	}
+	for _, digest := range c.Digests {
+		putBytes(&buf, digest)
+	}
+	if c.Blocks == nil {
+		putUvarint(&buf, 0)
+	} else {
+		putUvarint(&buf, uint64(c.Blocks.Size))
+		putUvarint(&buf, uint64(c.Blocks.Algorithm))
+		putUvarint(&buf, uint64(len(c.Blocks.Digests)))
+		for _, digest := range c.Blocks.Digests {
+			putBytes(&buf, digest)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Sign appends a signature by signer, under keyID, over c's payload.
+func (c *Container) Sign(keyID string, signer audit.Signer) error {
+	signature, err := signer.Sign(c.Payload())
+	if err != nil {
+		return err
+	}
+	c.Signatures = append(c.Signatures, Signature{KeyID: keyID, Signature: signature})
+	return nil
+}
+
+// VerifySignature checks the signature under keyID with verifier. A
+// container with no signature under keyID is reported as ErrUnsigned.
+func (c *Container) VerifySignature(keyID string, verifier audit.Verifier) error {
+	payload := c.Payload()
+	for _, s := range c.Signatures {
+		if s.KeyID == keyID {
+			return verifier.Verify(payload, s.Signature)
+		}
+	}
+	return ErrUnsigned
+}
+
+// MarshalBinary encodes c in the format described in the package comment.
+func (c *Container) MarshalBinary() ([]byte, error) {
+	if len(c.Digests) != len(c.Algorithms) {
+		return nil, MalformedContainerError{Reason: "digest count does not match algorithms"}
+	}
+	if c.Blocks != nil && (c.Blocks.Algorithm < 0 || c.Blocks.Algorithm >= len(c.Algorithms)) {
+		return nil, MalformedContainerError{Reason: "block map algorithm out of range"}
+	}
+	buf := bytes.NewBuffer(c.Payload())
+	putUvarint(buf, uint64(len(c.Signatures)))
+	for _, s := range c.Signatures {
+		putBytes(buf, []byte(s.KeyID))
+		putBytes(buf, s.Signature)
+	}
+	crc := multihash.NewCRC32C()
+	crc.Write(buf.Bytes())
+	buf.Write(crc.Sum(nil))
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a container encoded by MarshalBinary. Damage is
+// detected by the checksum and reported as a MalformedContainerError.
+func (c *Container) UnmarshalBinary(data []byte) error {
+	if len(data) < len(magic)+1+4 || string(data[:len(magic)]) != magic {
+		return MalformedContainerError{Reason: "not a digest container"}
+	}
+	if data[len(magic)] != version {
+		return MalformedContainerError{Reason: "unsupported version"}
+	}
+	body, sum := data[:len(data)-4], data[len(data)-4:]
+	crc := multihash.NewCRC32C()
+	crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(sum) {
+		return MalformedContainerError{Reason: "checksum mismatch"}
+	}
+	d := decoder{data: body[len(magic)+1:]}
+	decoded := Container{Size: int64(d.uvarint())}
+	count := d.count()
+	for i := 0; i < count; i++ {
+		decoded.Algorithms = append(decoded.Algorithms, string(d.bytes()))
+	}
+	for i := 0; i < count; i++ {
+		decoded.Digests = append(decoded.Digests, d.bytes())
+	}
+	if blockSize := d.uvarint(); blockSize > 0 {
+		if blockSize > math.MaxInt64 {
+			d.fail("block size out of range")
+		}
+		// The index is checked before it is converted, so that a large one
+		// cannot wrap around to a negative int.
+		algorithm := d.uvarint()
+		if algorithm >= uint64(count) {
+			d.fail("block map algorithm out of range")
+		}
+		blocks := &Blocks{Size: int64(blockSize), Algorithm: int(algorithm)}
+		for i, n := 0, d.count(); i < n; i++ {
+			blocks.Digests = append(blocks.Digests, d.bytes())
+		}
+		decoded.Blocks = blocks
+	}
+	for i, n := 0, d.count(); i < n; i++ {
+		decoded.Signatures = append(decoded.Signatures, Signature{KeyID: string(d.bytes()), Signature: d.bytes()})
+	}
+	if d.err == nil && len(d.data) != 0 {
+		d.fail("trailing data")
+	}
+	if d.err != nil {
+		return d.err
+	}
+	*c = decoded
+	return nil
+}
+
+// Verify reads data and checks it against c: its size first, then the
+// block map, if any, as the data is read, and finally the whole digests. A
+// wrong size is a multihash.SizeMismatchError, a damaged block a
+// multihash.BlockMismatchError locating it, and any other difference a
+// multihash.DigestMismatchError.
+func (c *Container) Verify(data io.Reader) error {
+	hashes, err := multihash.NewAll(c.Algorithms...)
+	if err != nil {
+		return err
+	}
+	data = multihash.NewSizeCheckingReader(data, c.Size)
+	var digests [][]byte
+	if c.Blocks != nil {
+		name := c.Algorithms[c.Blocks.Algorithm]
+		digests, err = multihash.VerifyBlocks(data, multihash.BlockMap{
+			BlockSize: c.Blocks.Size,
+			NewHash: func() hash.Hash {
+				h, _ := multihash.New(name)
+				return h
+			},
+			Digests: c.Blocks.Digests,
+		}, hashes...)
+	} else {
+		digests, err = multihash.FromReader(data, hashes...)
+	}
+	if err != nil {
+		return err
+	}
+	for index, digest := range digests {
+		if !bytes.Equal(digest, c.Digests[index]) {
+			return multihash.DigestMismatchError{Expected: c.Digests[index], Actual: digest}
+		}
+	}
+	return nil
+}
+
+// Embed writes c to w followed by the footer that lets ReadEmbedded find
+// it, for appending to the data it describes.
+func Embed(w io.Writer, c *Container) error {
+	encoded, err := c.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	footer := make([]byte, footerSize)
+	binary.BigEndian.PutUint64(footer, uint64(len(encoded)))
+	copy(footer[8:], embeddedMagic)
+	if _, err = w.Write(encoded); err != nil {
+		return err
+	}
+	_, err = w.Write(footer)
+	return err
+}
+
+// ReadEmbedded reads the container appended by Embed to the end of r, of
+// size bytes, and returns it with the size of the data before it. Data
+// without one is reported as ErrNoContainer.
+func ReadEmbedded(r io.ReaderAt, size int64) (*Container, int64, error) {
+	if size < int64(footerSize) {
+		return nil, 0, ErrNoContainer
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-int64(footerSize)); err != nil {
+		return nil, 0, err
+	}
+	if string(footer[8:]) != embeddedMagic {
+		return nil, 0, ErrNoContainer
+	}
+	length := binary.BigEndian.Uint64(footer)
+	if length > uint64(size)-uint64(footerSize) {
+		return nil, 0, MalformedContainerError{Reason: "embedded length exceeds the file"}
+	}
+	dataSize := size - int64(footerSize) - int64(length)
+	encoded := make([]byte, length)
+	if _, err := r.ReadAt(encoded, dataSize); err != nil {
+		return nil, 0, err
+	}
+	c := &Container{}
+	if err := c.UnmarshalBinary(encoded); err != nil {
+		return nil, 0, err
+	}
+	if c.Size != dataSize {
+		return nil, 0, MalformedContainerError{Reason: "embedded container describes a different size"}
+	}
+	return c, dataSize, nil
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	buf.Write(scratch[:binary.PutUvarint(scratch[:], v)])
+}
+
+func putBytes(buf *bytes.Buffer, b []byte) {
+	putUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+// decoder reads fields, recording the first failure and returning zero
+// values after it.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(reason string) {
+	if d.err == nil {
+		d.err = MalformedContainerError{Reason: reason}
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("truncated")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// count reads a count of fields, each at least one byte long.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("count exceeds the data")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.data)) {
+		d.fail("truncated")
+		return nil
+	}
+	b := append([]byte(nil), d.data[:n]...)
+	d.data = d.data[n:]
+	return b
+}
+
+type blockHasher struct {
+	size    int64
+	h       hash.Hash
+	filled  int64
+	digests [][]byte
+}
+
+func (b *blockHasher) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		take := b.size - b.filled
+		if int64(len(p)) < take {
+			take = int64(len(p))
+		}
+		b.h.Write(p[:take])
+		b.filled += take
+		p = p[take:]
+		if b.filled == b.size {
+			b.flush()
+		}
+	}
+	return written, nil
+}
+
+func (b *blockHasher) flush() {
+	if b.filled == 0 {
+		return
+	}
+	b.digests = append(b.digests, b.h.Sum(nil))
+	b.h.Reset()
+	b.filled = 0
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
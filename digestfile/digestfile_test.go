@@ -0,0 +1,103 @@
+package digestfile
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/audit"
+
+	_ "crypto/md5"
+)
+
+func Test_Container(t *testing.T) {
+	data := bytes.Repeat([]byte("archived data "), 1000)
+	c, err := Compute(bytes.NewReader(data), 4096, "sha256", "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest := sha256.Sum256(data); c.Size != int64(len(data)) || !bytes.Equal(c.Digests[0], digest[:]) {
+		t.Fatalf("unexpected container %+v\n", c)
+	}
+	if len(c.Blocks.Digests) != 4 {
+		t.Fatalf("expected 4 blocks, got %d\n", len(c.Blocks.Digests))
+	}
+
+	public, private, _ := ed25519.GenerateKey(nil)
+	if err = c.Sign("archive-2024", audit.CryptoSigner{Signer: private}); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Container
+	if err = decoded.UnmarshalBinary(encoded); err != nil {
+		t.Fatal(err)
+	}
+	if err = decoded.VerifySignature("archive-2024", audit.Ed25519Verifier(public)); err != nil {
+		t.Fatal(err)
+	}
+	if err = decoded.VerifySignature("other", audit.Ed25519Verifier(public)); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("expected ErrUnsigned, got %v\n", err)
+	}
+	if err = decoded.Verify(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	damaged := append([]byte(nil), data...)
+	damaged[5000] ^= 1
+	var blockErr multihash.BlockMismatchError
+	if err = decoded.Verify(bytes.NewReader(damaged)); !errors.As(err, &blockErr) || blockErr.Index != 1 {
+		t.Fatalf("expected a mismatch in block 1, got %v\n", err)
+	}
+	if err = decoded.Verify(bytes.NewReader(data[:100])); !errors.Is(err, multihash.ErrSizeMismatch) {
+		t.Fatalf("expected a size mismatch, got %v\n", err)
+	}
+
+	encoded[10] ^= 1
+	if err = decoded.UnmarshalBinary(encoded); !errors.Is(err, ErrMalformedContainer) {
+		t.Fatalf("expected a damaged container to be rejected, got %v\n", err)
+	}
+}
+
+func Test_Embed(t *testing.T) {
+	data := []byte("payload with its own integrity information")
+	c, err := Compute(bytes.NewReader(data), 0, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := bytes.NewBuffer(append([]byte(nil), data...))
+	if err = Embed(file, c); err != nil {
+		t.Fatal(err)
+	}
+	found, size, err := ReadEmbedded(bytes.NewReader(file.Bytes()), int64(file.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(data)) || found.Verify(bytes.NewReader(file.Bytes()[:size])) != nil {
+		t.Fatalf("embedded container at %d does not verify its data\n", size)
+	}
+	if _, _, err = ReadEmbedded(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrNoContainer) {
+		t.Fatalf("expected ErrNoContainer, got %v\n", err)
+	}
+}
+
+func Test_Container_outOfRange(t *testing.T) {
+	// Payload encodes these negative fields as the largest varints, which
+	// MarshalBinary would refuse to write.
+	for _, blocks := range []*Blocks{{Size: 4096, Algorithm: -1}, {Size: -1, Algorithm: 0}} {
+		c := &Container{Algorithms: []string{"sha256"}, Digests: [][]byte{make([]byte, 32)}, Blocks: blocks}
+		encoded := append(c.Payload(), 0)
+		crc := multihash.NewCRC32C()
+		crc.Write(encoded)
+		encoded = crc.Sum(encoded)
+		var decoded Container
+		if err := decoded.UnmarshalBinary(encoded); !errors.Is(err, ErrMalformedContainer) {
+			t.Fatalf("block map %+v gave %v\n", blocks, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package digestfile
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrMalformedContainer = errcode.New(errcode.Malformed, "malformed digest container")
+var ErrNoContainer = errcode.New(errcode.NotFound, "no embedded digest container")
+var ErrUnsigned = errcode.New(errcode.NotFound, "no signature under that key")
+
+type MalformedContainerError struct {
+	Reason string
+}
+
+func (e MalformedContainerError) Error() string {
+	return "malformed digest container: " + e.Reason
+}
+
+func (e MalformedContainerError) Is(target error) bool {
+	return target == ErrMalformedContainer
+}
+
+func (e MalformedContainerError) Code() errcode.Code {
+	return errcode.Malformed
+}
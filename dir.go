@@ -0,0 +1,149 @@
+package multihash
+
+import (
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// DirOptions configure FromDir and Verify.
+type DirOptions struct {
+	// Workers is the number of files hashed at once, runtime.GOMAXPROCS(0)
+	// if not positive. The hashes of each file are computed concurrently
+	// too, as by FromFile, so a few workers are enough to keep a disk busy.
+	Workers int
+	// Skip, if set, is called for every file and directory below root with
+	// its slash-separated path relative to root. Returning true leaves out a
+	// file, or a directory and everything in it.
+	Skip func(relative string, d fs.DirEntry) bool
+}
+
+func (o DirOptions) workers() int {
+	if o.Workers < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Workers
+}
+
+// FromDir hashes every regular file under root with algorithms, returning a
+// manifest of them sorted by their slash-separated path relative to root.
+// Symbolic links and other special files are not followed or listed. The
+// manifest can be written out with WriteGNU or WriteBSD, for sha256sum -c
+// and the like to check, and read back with manifest.Parse for Verify. If
+// any file cannot be read, the error is that of the first in path order,
+// once every file has been tried.
+func FromDir(root string, opts DirOptions, algorithms ...string) (*manifest.Manifest, error) {
+	if _, err := NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	paths, err := walkFiles(root, opts.Skip)
+	if err != nil {
+		return nil, err
+	}
+	results := hashFiles(root, paths, algorithms, opts.workers())
+	m := &manifest.Manifest{Algorithms: append([]string(nil), algorithms...), Entries: make([]manifest.Entry, len(paths))}
+	for index, relative := range paths {
+		if results[index].err != nil {
+			return nil, results[index].err
+		}
+		m.Entries[index] = manifest.Entry{Path: relative, Digests: results[index].digests}
+	}
+	return m, nil
+}
+
+// Verify re-hashes the files m lists under root, in parallel as FromDir
+// does, and reports how they compare, in the manifest's order. Files found
+// under root that m does not list are reported as Extra, sorted; Skip
+// applies only to that search, so every file m lists is checked. A listed
+// path that would lie outside root, or that is otherwise not canonical as
+// manifest.CanonicalPath defines, is Failed with a manifest.InvalidPathError
+// and not read.
+func Verify(root string, m *manifest.Manifest, opts DirOptions) (report VerifyReport, err error) {
+	found, err := walkFiles(root, opts.Skip)
+	if err != nil {
+		return report, err
+	}
+	report, paths, err := verifyEntries(root, m.Algorithms, m.Entries, opts.workers())
+	if err != nil {
+		return report, err
+	}
+	listed := make(map[string]bool, len(paths))
+	for _, relative := range paths {
+		listed[relative] = true
+	}
+	for _, relative := range found {
+		if !listed[relative] {
+			report.Extra = append(report.Extra, relative)
+		}
+	}
+	return report, nil
+}
+
+// walkFiles returns the slash-separated paths relative to root of the
+// regular files under it that skip does not exclude, sorted.
+func walkFiles(root string, skip func(string, fs.DirEntry) bool) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if filename == root {
+			return nil
+		}
+		relative, err := filepath.Rel(root, filename)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if skip != nil && skip(relative, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, relative)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+type fileResult struct {
+	digests [][]byte
+	err     error
+}
+
+// hashFiles hashes the files at paths under root with algorithms, which
+// must all be available, on workers goroutines, returning the results in
+// the order of paths.
+func hashFiles(root string, paths []string, algorithms []string, workers int) []fileResult {
+	results := make([]fileResult, len(paths))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(paths); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indices {
+				hashes, _ := NewAll(algorithms...)
+				digests, err := FromFile(filepath.Join(root, filepath.FromSlash(paths[index])), hashes...)
+				results[index] = fileResult{digests, err}
+			}
+		}()
+	}
+	for index := range paths {
+		indices <- index
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
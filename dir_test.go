@@ -0,0 +1,80 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+func Test_FromDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":          "alpha",
+		"docs/b.txt":     "beta",
+		"docs/sub/c.txt": "gamma",
+		".git/HEAD":      "ref: refs/heads/main",
+	}
+	for name, contents := range files {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(filename), 0o755)
+		os.WriteFile(filename, []byte(contents), 0o644)
+	}
+	opts := DirOptions{
+		Workers: 2,
+		Skip:    func(relative string, d fs.DirEntry) bool { return d.IsDir() && d.Name() == ".git" },
+	}
+	m, err := FromDir(root, opts, "md5", "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, entry := range m.Entries {
+		paths = append(paths, entry.Path)
+	}
+	if !slicesEqual(paths, []string{"a.txt", "docs/b.txt", "docs/sub/c.txt"}) {
+		t.Fatalf("unexpected paths %q\n", paths)
+	}
+	sum := sha256.Sum256([]byte("gamma"))
+	if digest, _ := m.Digest("docs/sub/c.txt", "sha256"); !slicesEqual(digest, sum[:]) {
+		t.Fatalf("c.txt has sha256 %x, expected %x\n", digest, sum)
+	}
+
+	var sums bytes.Buffer
+	if err := m.WriteGNU(&sums, "sha256"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(sums.Bytes(), []byte(hex.EncodeToString(sum[:])+"  docs/sub/c.txt\n")) {
+		t.Fatalf("unexpected sha256sum output %q\n", sums.String())
+	}
+	parsed, err := manifest.Parse(&sums, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("changed"), 0o644)
+	os.Remove(filepath.Join(root, "docs", "b.txt"))
+	os.WriteFile(filepath.Join(root, "docs", "new.txt"), []byte("new"), 0o644)
+	report, err := Verify(root, parsed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicesEqual(report.Verified, []string{"docs/sub/c.txt"}) || !slicesEqual(report.Mismatched, []string{"a.txt"}) ||
+		!slicesEqual(report.Missing, []string{"docs/b.txt"}) || !slicesEqual(report.Extra, []string{"docs/new.txt"}) || report.OK() {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+
+	for _, outside := range []string{"../x", "/etc/x"} {
+		escaping := &manifest.Manifest{Algorithms: []string{"sha256"}, Entries: []manifest.Entry{{Path: outside, Digests: [][]byte{sum[:]}}}}
+		report, err = Verify(root, escaping, opts)
+		if err != nil || !errors.Is(report.Failed[outside], manifest.ErrInvalidPath) {
+			t.Fatalf("verifying %q gave %+v, %v, expected an InvalidPathError\n", outside, report, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// package diskimage hashes disk images partition by partition: a single
+// sequential pass over the image yields the digests of the whole image and
+// of each partition in its MBR or GPT partition table, as for validating
+// golden virtual machine images. Images in QEMU's qcow2 format are hashed
+// as the disk the guest sees, through OpenQCOW2.
+package diskimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strconv"
+	"unicode/utf16"
+
+	"github.com/trytriangles/multihash"
+)
+
+// SectorSize is the logical sector size partition tables are read with.
+const SectorSize = 512
+
+// chunkSize is the size of each read from the image.
+const chunkSize = 1 << 20
+
+// Partition describes one partition table entry and its digests.
+type Partition struct {
+	// Number is the entry's 1-based position in the table.
+	Number int
+	// Scheme is "mbr" or "gpt".
+	Scheme string
+	// Type is the MBR partition type as two hex digits, e.g. "83", or the
+	// GPT partition type GUID, e.g. "0fc63daf-8483-4772-8e79-3d69d8477de4".
+	Type string
+	// Name is the GPT partition name; MBR partitions have none.
+	Name   string
+	Offset int64
+	Size   int64
+	// Digests are of the partition's bytes, in the order of algorithms.
+	Digests [][]byte
+}
+
+// Report is the result of Hash.
+type Report struct {
+	Size int64
+	// Digests are of the whole image, in the order of algorithms.
+	Digests    [][]byte
+	Partitions []Partition
+}
+
+// Hash reads the partition table of the disk image r, of size bytes, and
+// then hashes the image from start to end once, feeding each partition's
+// byte range to its own hashes as it passes. An image with no partition
+// table is hashed whole, with no partitions. Partitions that extend past
+// the end of the image are reported as a MalformedTableError.
+//
+// Logical partitions inside an MBR extended partition are not listed
+// separately; the extended partition is hashed as one.
+func Hash(r io.ReaderAt, size int64, algorithms ...string) (*Report, error) {
+	whole, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := readTable(r, size)
+	if err != nil {
+		return nil, err
+	}
+	partitionHashes := make([][]hash.Hash, len(partitions))
+	for index := range partitions {
+		if partitionHashes[index], err = multihash.NewAll(algorithms...); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, chunkSize)
+	for offset := int64(0); offset < size; {
+		n := int64(len(buf))
+		if size-offset < n {
+			n = size - offset
+		}
+		read, err := r.ReadAt(buf[:n], offset)
+		if int64(read) < n {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		chunk := buf[:n]
+		for _, h := range whole {
+			h.Write(chunk)
+		}
+		for index, p := range partitions {
+			start, end := p.Offset, p.Offset+p.Size
+			if start < offset {
+				start = offset
+			}
+			if end > offset+n {
+				end = offset + n
+			}
+			if start >= end {
+				continue
+			}
+			for _, h := range partitionHashes[index] {
+				h.Write(chunk[start-offset : end-offset])
+			}
+		}
+		offset += n
+	}
+
+	report := &Report{Size: size, Digests: sums(whole), Partitions: partitions}
+	for index := range partitions {
+		report.Partitions[index].Digests = sums(partitionHashes[index])
+	}
+	return report, nil
+}
+
+func sums(hashes []hash.Hash) [][]byte {
+	digests := make([][]byte, len(hashes))
+	for index, h := range hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return digests
+}
+
+// readTable reads the partitions of a GPT, if the MBR is protective, or
+// else of the MBR.
+func readTable(r io.ReaderAt, size int64) ([]Partition, error) {
+	if size < 2*SectorSize {
+		return nil, nil
+	}
+	mbr := make([]byte, SectorSize)
+	if _, err := r.ReadAt(mbr, 0); err != nil {
+		return nil, err
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return nil, nil
+	}
+	var partitions []Partition
+	for index := 0; index < 4; index++ {
+		entry := mbr[446+16*index : 446+16*(index+1)]
+		kind := entry[4]
+		start := int64(binary.LittleEndian.Uint32(entry[8:]))
+		count := int64(binary.LittleEndian.Uint32(entry[12:]))
+		if kind == 0 || count == 0 {
+			continue
+		}
+		if kind == 0xee {
+			return readGPT(r, size)
+		}
+		partitions = append(partitions, Partition{
+			Number: index + 1,
+			Scheme: "mbr",
+			Type:   hex.EncodeToString([]byte{kind}),
+			Offset: start * SectorSize,
+			Size:   count * SectorSize,
+		})
+	}
+	return partitions, checkBounds(partitions, size)
+}
+
+func readGPT(r io.ReaderAt, size int64) ([]Partition, error) {
+	header := make([]byte, 92)
+	if _, err := r.ReadAt(header, SectorSize); err != nil {
+		return nil, err
+	}
+	if string(header[:8]) != "EFI PART" {
+		return nil, MalformedTableError{Reason: "protective MBR without a GPT header"}
+	}
+	// The header's fields are bounded before they are multiplied, so that
+	// no product can overflow past the checks.
+	sectors := uint64(size / SectorSize)
+	entriesAt := binary.LittleEndian.Uint64(header[72:])
+	count := int64(binary.LittleEndian.Uint32(header[80:]))
+	entrySize := int64(binary.LittleEndian.Uint32(header[84:]))
+	if entrySize < 128 || entrySize > 4096 || count > 1024 || entriesAt > sectors ||
+		int64(entriesAt)*SectorSize+count*entrySize > size {
+		return nil, MalformedTableError{Reason: "bad GPT partition entry array"}
+	}
+	entries := make([]byte, count*entrySize)
+	if _, err := r.ReadAt(entries, int64(entriesAt)*SectorSize); err != nil {
+		return nil, err
+	}
+	var partitions []Partition
+	for index := int64(0); index < count; index++ {
+		entry := entries[index*entrySize : (index+1)*entrySize]
+		if bytes.Equal(entry[:16], make([]byte, 16)) {
+			continue
+		}
+		first := binary.LittleEndian.Uint64(entry[32:])
+		last := binary.LittleEndian.Uint64(entry[40:])
+		if last < first {
+			return nil, MalformedTableError{Reason: "GPT entry " + strconv.FormatInt(index+1, 10) + " ends before it starts"}
+		}
+		if last >= sectors {
+			return nil, MalformedTableError{Reason: "partition " + strconv.FormatInt(index+1, 10) + " extends past the end of the image"}
+		}
+		partitions = append(partitions, Partition{
+			Number: int(index + 1),
+			Scheme: "gpt",
+			Type:   formatGUID(entry[:16]),
+			Name:   decodeName(entry[56:128]),
+			Offset: int64(first) * SectorSize,
+			Size:   int64(last-first+1) * SectorSize,
+		})
+	}
+	return partitions, checkBounds(partitions, size)
+}
+
+func checkBounds(partitions []Partition, size int64) error {
+	for _, p := range partitions {
+		if p.Offset+p.Size > size {
+			return MalformedTableError{Reason: "partition " + strconv.Itoa(p.Number) + " extends past the end of the image"}
+		}
+	}
+	return nil
+}
+
+// formatGUID formats a GUID stored in the mixed-endian layout GPT uses.
+func formatGUID(b []byte) string {
+	return hex.EncodeToString([]byte{b[3], b[2], b[1], b[0]}) + "-" +
+		hex.EncodeToString([]byte{b[5], b[4]}) + "-" +
+		hex.EncodeToString([]byte{b[7], b[6]}) + "-" +
+		hex.EncodeToString(b[8:10]) + "-" +
+		hex.EncodeToString(b[10:16])
+}
+
+func decodeName(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for index := 0; index+1 < len(b); index += 2 {
+		unit := binary.LittleEndian.Uint16(b[index:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,188 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// testDisk returns a 1 MiB disk image with distinct content in each of two
+// partitions, at sectors 64 to 191 and 256 to 1023.
+func testDisk(gpt bool) []byte {
+	disk := make([]byte, 1<<20)
+	for index := 64 * SectorSize; index < 192*SectorSize; index++ {
+		disk[index] = byte(index)
+	}
+	for index := 256 * SectorSize; index < 1024*SectorSize; index++ {
+		disk[index] = byte(index / 7)
+	}
+	mbr := disk[:SectorSize]
+	mbr[510], mbr[511] = 0x55, 0xaa
+	if !gpt {
+		mbr[446+4] = 0x83
+		binary.LittleEndian.PutUint32(mbr[446+8:], 64)
+		binary.LittleEndian.PutUint32(mbr[446+12:], 128)
+		mbr[462+4] = 0x07
+		binary.LittleEndian.PutUint32(mbr[462+8:], 256)
+		binary.LittleEndian.PutUint32(mbr[462+12:], 768)
+		return disk
+	}
+	mbr[446+4] = 0xee
+	binary.LittleEndian.PutUint32(mbr[446+8:], 1)
+	binary.LittleEndian.PutUint32(mbr[446+12:], 2047)
+	header := disk[SectorSize:]
+	copy(header, "EFI PART")
+	binary.LittleEndian.PutUint64(header[72:], 2)
+	binary.LittleEndian.PutUint32(header[80:], 128)
+	binary.LittleEndian.PutUint32(header[84:], 128)
+	for index, p := range [][3]interface{}{{uint64(64), uint64(191), "boot"}, {uint64(256), uint64(1023), "root"}} {
+		entry := disk[2*SectorSize+128*index:]
+		// The Linux filesystem data type GUID.
+		copy(entry, []byte{0xaf, 0x3d, 0xc6, 0x0f, 0x83, 0x84, 0x72, 0x47, 0x8e, 0x79, 0x3d, 0x69, 0xd8, 0x47, 0x7d, 0xe4})
+		entry[16] = byte(index + 1)
+		binary.LittleEndian.PutUint64(entry[32:], p[0].(uint64))
+		binary.LittleEndian.PutUint64(entry[40:], p[1].(uint64))
+		for i, unit := range utf16.Encode([]rune(p[2].(string))) {
+			binary.LittleEndian.PutUint16(entry[56+2*i:], unit)
+		}
+	}
+	return disk
+}
+
+func checkReport(t *testing.T, disk []byte, report *Report, scheme string) {
+	t.Helper()
+	if expected := sha256.Sum256(disk); !bytes.Equal(report.Digests[0], expected[:]) {
+		t.Fatalf("whole image digest %x, expected %x\n", report.Digests[0], expected)
+	}
+	if len(report.Partitions) != 2 {
+		t.Fatalf("found %d partitions\n", len(report.Partitions))
+	}
+	for index, bounds := range [][2]int{{64, 192}, {256, 1024}} {
+		p := report.Partitions[index]
+		expected := sha256.Sum256(disk[bounds[0]*SectorSize : bounds[1]*SectorSize])
+		if p.Scheme != scheme || !bytes.Equal(p.Digests[0], expected[:]) {
+			t.Fatalf("partition %d: %+v, expected digest %x\n", index+1, p, expected)
+		}
+	}
+}
+
+func Test_Hash(t *testing.T) {
+	disk := testDisk(false)
+	report, err := Hash(bytes.NewReader(disk), int64(len(disk)), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, disk, report, "mbr")
+	if report.Partitions[0].Type != "83" {
+		t.Fatalf("unexpected MBR type %s\n", report.Partitions[0].Type)
+	}
+
+	disk = testDisk(true)
+	if report, err = Hash(bytes.NewReader(disk), int64(len(disk)), "sha256"); err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, disk, report, "gpt")
+	if p := report.Partitions[1]; p.Type != "0fc63daf-8483-4772-8e79-3d69d8477de4" || p.Name != "root" {
+		t.Fatalf("unexpected GPT entry %+v\n", p)
+	}
+
+	if _, err = Hash(bytes.NewReader(disk[:512*1000]), 512*1000, "sha256"); !errors.Is(err, ErrMalformedTable) {
+		t.Fatalf("expected a partition past the end to be rejected, got %v\n", err)
+	}
+
+	// Header fields whose products would overflow int64 are rejected, not
+	// allocated for.
+	for _, corrupt := range []func([]byte){
+		func(d []byte) { binary.LittleEndian.PutUint64(d[SectorSize+72:], 1<<55-1<<33) },
+		func(d []byte) { binary.LittleEndian.PutUint32(d[SectorSize+84:], 1<<31) },
+		func(d []byte) { binary.LittleEndian.PutUint64(d[2*SectorSize+40:], 1<<62) },
+	} {
+		disk = testDisk(true)
+		corrupt(disk)
+		if _, err = Hash(bytes.NewReader(disk), int64(len(disk)), "sha256"); !errors.Is(err, ErrMalformedTable) {
+			t.Fatalf("expected a corrupt GPT to be rejected, got %v\n", err)
+		}
+	}
+}
+
+// testQCOW2 packs disk into a version 3 qcow2 image with 4 KiB clusters,
+// leaving all-zero clusters unallocated and compressing every third.
+func testQCOW2(disk []byte) []byte {
+	const clusterBits, clusterSize = 12, 4096
+	clusters := len(disk) / clusterSize
+	l2Entries := clusterSize / 8
+	l1Size := (clusters + l2Entries - 1) / l2Entries
+
+	header := make([]byte, clusterSize)
+	copy(header, qcow2Magic)
+	binary.BigEndian.PutUint32(header[4:], 3)
+	binary.BigEndian.PutUint32(header[20:], clusterBits)
+	binary.BigEndian.PutUint64(header[24:], uint64(len(disk)))
+	binary.BigEndian.PutUint32(header[36:], uint32(l1Size))
+	binary.BigEndian.PutUint64(header[40:], clusterSize)
+	binary.BigEndian.PutUint32(header[100:], 104)
+
+	image := append([]byte(nil), header...)
+	l1At := len(image)
+	image = append(image, make([]byte, clusterSize)...)
+	l2At := len(image)
+	image = append(image, make([]byte, l1Size*clusterSize)...)
+	for index := 0; index < l1Size; index++ {
+		binary.BigEndian.PutUint64(image[l1At+8*index:], uint64(l2At+index*clusterSize))
+	}
+	for cluster := 0; cluster < clusters; cluster++ {
+		data := disk[cluster*clusterSize : (cluster+1)*clusterSize]
+		if bytes.Equal(data, make([]byte, clusterSize)) {
+			continue
+		}
+		var entry uint64
+		if cluster%3 == 0 {
+			var compressed bytes.Buffer
+			fw, _ := flate.NewWriter(&compressed, flate.BestCompression)
+			fw.Write(data)
+			fw.Close()
+			offset := len(image)
+			sectors := (offset%512 + compressed.Len() + 511) / 512
+			image = append(image, compressed.Bytes()...)
+			shift := 62 - (clusterBits - 8)
+			entry = qcow2Compressed | uint64(sectors-1)<<shift | uint64(offset)
+		} else {
+			for len(image)%clusterSize != 0 {
+				image = append(image, 0)
+			}
+			entry = uint64(len(image))
+			image = append(image, data...)
+		}
+		binary.BigEndian.PutUint64(image[l2At+8*cluster:], entry)
+	}
+	return image
+}
+
+func Test_QCOW2(t *testing.T) {
+	disk := testDisk(true)
+	q, err := OpenQCOW2(bytes.NewReader(testQCOW2(disk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Hash(q, q.Size(), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, disk, report, "gpt")
+
+	backed := testQCOW2(disk)
+	binary.BigEndian.PutUint64(backed[8:], 512)
+	if _, err = OpenQCOW2(bytes.NewReader(backed)); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("expected an image with a backing file to be unsupported, got %v\n", err)
+	}
+
+	oversized := testQCOW2(disk)
+	binary.BigEndian.PutUint32(oversized[36:], 1<<31)
+	if _, err = OpenQCOW2(bytes.NewReader(oversized)); !errors.Is(err, ErrMalformedImage) {
+		t.Fatalf("expected an oversized L1 table to be malformed, got %v\n", err)
+	}
+}
@@ -0,0 +1,55 @@
+package diskimage
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrMalformedTable = errcode.New(errcode.Malformed, "malformed partition table")
+var ErrMalformedImage = errcode.New(errcode.Malformed, "malformed disk image")
+var ErrUnsupportedImage = errcode.New(errcode.UnsupportedFormat, "unsupported disk image")
+
+type MalformedTableError struct {
+	Reason string
+}
+
+func (e MalformedTableError) Error() string {
+	return "malformed partition table: " + e.Reason
+}
+
+func (e MalformedTableError) Is(target error) bool {
+	return target == ErrMalformedTable
+}
+
+func (e MalformedTableError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+type MalformedImageError struct {
+	Reason string
+}
+
+func (e MalformedImageError) Error() string {
+	return "malformed disk image: " + e.Reason
+}
+
+func (e MalformedImageError) Is(target error) bool {
+	return target == ErrMalformedImage
+}
+
+func (e MalformedImageError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+type UnsupportedImageError struct {
+	Reason string
+}
+
+func (e UnsupportedImageError) Error() string {
+	return "unsupported disk image: " + e.Reason
+}
+
+func (e UnsupportedImageError) Is(target error) bool {
+	return target == ErrUnsupportedImage
+}
+
+func (e UnsupportedImageError) Code() errcode.Code {
+	return errcode.UnsupportedFormat
+}
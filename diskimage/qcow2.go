@@ -0,0 +1,204 @@
+package diskimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+)
+
+const (
+	qcow2Magic = "QFI\xfb"
+	// qcow2OffsetMask selects the host offset from L1 and L2 entries.
+	qcow2OffsetMask = 0x00fffffffffffe00
+	qcow2Compressed = 1 << 62
+	qcow2ZeroFlag   = 1
+	// qcow2KnownIncompatible are the incompatible feature bits QCOW2
+	// handles: only the dirty bit, which concerns refcounts alone.
+	qcow2KnownIncompatible = 1 << 0
+)
+
+// QCOW2 presents the guest disk of a qcow2 image as an io.ReaderAt, for
+// Hash. Unallocated clusters read as zeros. Images with a backing file,
+// encryption, external data files, extended L2 entries or compression
+// other than deflate are reported as an UnsupportedImageError.
+type QCOW2 struct {
+	r           io.ReaderAt
+	size        int64
+	clusterBits uint
+	l1          []uint64
+
+	mu sync.Mutex
+	l2 map[uint64][]uint64
+}
+
+// OpenQCOW2 reads the header and L1 table of the qcow2 image r.
+func OpenQCOW2(r io.ReaderAt) (*QCOW2, error) {
+	header := make([]byte, 104)
+	if _, err := r.ReadAt(header[:72], 0); err != nil {
+		return nil, err
+	}
+	if string(header[:4]) != qcow2Magic {
+		return nil, MalformedImageError{Reason: "not a qcow2 image"}
+	}
+	version := binary.BigEndian.Uint32(header[4:])
+	if version != 2 && version != 3 {
+		return nil, UnsupportedImageError{Reason: "qcow2 version " + strconv.FormatUint(uint64(version), 10)}
+	}
+	if binary.BigEndian.Uint64(header[8:]) != 0 {
+		return nil, UnsupportedImageError{Reason: "backing file"}
+	}
+	clusterBits := binary.BigEndian.Uint32(header[20:])
+	if clusterBits < 9 || clusterBits > 21 {
+		return nil, MalformedImageError{Reason: "bad cluster size"}
+	}
+	if binary.BigEndian.Uint32(header[32:]) != 0 {
+		return nil, UnsupportedImageError{Reason: "encryption"}
+	}
+	if version == 3 {
+		if _, err := r.ReadAt(header[72:], 72); err != nil {
+			return nil, err
+		}
+		if binary.BigEndian.Uint64(header[72:])&^qcow2KnownIncompatible != 0 {
+			return nil, UnsupportedImageError{Reason: "incompatible features"}
+		}
+	}
+	size := binary.BigEndian.Uint64(header[24:])
+	if size > math.MaxInt64 {
+		return nil, MalformedImageError{Reason: "bad virtual size"}
+	}
+	// Each L1 entry maps an L2 table of a cluster's worth of 8-byte
+	// entries. The table is read whole, so it is bounded by what the
+	// virtual size needs, lest a bad header force a huge allocation.
+	l1Size := binary.BigEndian.Uint32(header[36:])
+	l1Bits := 2*clusterBits - 3
+	if uint64(l1Size) > (size+1<<l1Bits-1)>>l1Bits {
+		return nil, MalformedImageError{Reason: "L1 table larger than the disk"}
+	}
+	l1Offset := int64(binary.BigEndian.Uint64(header[40:]))
+	raw := make([]byte, 8*int64(l1Size))
+	if _, err := r.ReadAt(raw, l1Offset); err != nil {
+		return nil, err
+	}
+	q := &QCOW2{
+		r:           r,
+		size:        int64(size),
+		clusterBits: uint(clusterBits),
+		l1:          decodeTable(raw),
+		l2:          map[uint64][]uint64{},
+	}
+	return q, nil
+}
+
+func decodeTable(raw []byte) []uint64 {
+	table := make([]uint64, len(raw)/8)
+	for index := range table {
+		table[index] = binary.BigEndian.Uint64(raw[8*index:])
+	}
+	return table
+}
+
+// Size returns the size of the guest disk.
+func (q *QCOW2) Size() int64 {
+	return q.size
+}
+
+// ReadAt reads the guest disk at offset.
+func (q *QCOW2) ReadAt(p []byte, offset int64) (int, error) {
+	if offset >= q.size {
+		return 0, io.EOF
+	}
+	n := 0
+	clusterSize := int64(1) << q.clusterBits
+	for n < len(p) && offset < q.size {
+		within := offset & (clusterSize - 1)
+		length := clusterSize - within
+		if remaining := int64(len(p) - n); length > remaining {
+			length = remaining
+		}
+		if remaining := q.size - offset; length > remaining {
+			length = remaining
+		}
+		if err := q.readCluster(p[n:n+int(length)], offset>>q.clusterBits, within); err != nil {
+			return n, err
+		}
+		n += int(length)
+		offset += length
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (q *QCOW2) readCluster(p []byte, cluster, within int64) error {
+	l2Entries := int64(1) << (q.clusterBits - 3)
+	l1Index := cluster / l2Entries
+	if l1Index >= int64(len(q.l1)) {
+		return MalformedImageError{Reason: "offset beyond L1 table"}
+	}
+	l2Offset := q.l1[l1Index] & qcow2OffsetMask
+	if l2Offset == 0 {
+		zero(p)
+		return nil
+	}
+	table, err := q.l2Table(l2Offset)
+	if err != nil {
+		return err
+	}
+	entry := table[cluster%l2Entries]
+	switch {
+	case entry&qcow2Compressed != 0:
+		return q.readCompressed(p, entry, within)
+	case entry&qcow2ZeroFlag != 0 || entry&qcow2OffsetMask == 0:
+		zero(p)
+		return nil
+	}
+	_, err = q.r.ReadAt(p, int64(entry&qcow2OffsetMask)+within)
+	return err
+}
+
+func (q *QCOW2) l2Table(offset uint64) ([]uint64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if table, ok := q.l2[offset]; ok {
+		return table, nil
+	}
+	raw := make([]byte, 1<<q.clusterBits)
+	if _, err := q.r.ReadAt(raw, int64(offset)); err != nil {
+		return nil, err
+	}
+	table := decodeTable(raw)
+	q.l2[offset] = table
+	return table, nil
+}
+
+// readCompressed inflates a compressed cluster, whose descriptor holds its
+// host offset and, above it, the number of 512-byte sectors it spans beyond
+// the first.
+func (q *QCOW2) readCompressed(p []byte, entry uint64, within int64) error {
+	shift := 62 - (q.clusterBits - 8)
+	hostOffset := int64(entry & (1<<shift - 1))
+	sectors := int64(entry>>shift&(1<<(q.clusterBits-8)-1)) + 1
+	length := sectors*512 - hostOffset&511
+	raw := make([]byte, length)
+	n, err := q.r.ReadAt(raw, hostOffset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	cluster := make([]byte, 1<<q.clusterBits)
+	if _, err = io.ReadFull(flate.NewReader(bytes.NewReader(raw[:n])), cluster); err != nil {
+		return MalformedImageError{Reason: "bad compressed cluster: " + err.Error()}
+	}
+	copy(p, cluster[within:])
+	return nil
+}
+
+func zero(p []byte) {
+	for index := range p {
+		p[index] = 0
+	}
+}
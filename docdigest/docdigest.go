@@ -0,0 +1,29 @@
+// package docdigest computes digests of office documents that ignore what
+// changes when a document is merely opened and saved: for OOXML (.docx,
+// .xlsx, .pptx) the parts of the package other than its metadata
+// properties, regardless of how the zip was compressed or ordered, and for
+// PDF each revision's byte range and the decoded content streams of the
+// original revision, ignoring incremental-save tails.
+package docdigest
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// writeField writes b to h prefixed with its length, so that the boundaries
+// between concatenated fields are part of the digest.
+func writeField(h hash.Hash, b []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(b)))
+	h.Write(length[:])
+	h.Write(b)
+}
+
+func sums(hashes []hash.Hash) [][]byte {
+	digests := make([][]byte, len(hashes))
+	for index, h := range hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return digests
+}
@@ -0,0 +1,21 @@
+package docdigest
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrMalformedPDF = errcode.New(errcode.Malformed, "malformed PDF")
+
+type MalformedPDFError struct {
+	Reason string
+}
+
+func (e MalformedPDFError) Error() string {
+	return "malformed PDF: " + e.Reason
+}
+
+func (e MalformedPDFError) Is(target error) bool {
+	return target == ErrMalformedPDF
+}
+
+func (e MalformedPDFError) Code() errcode.Code {
+	return errcode.Malformed
+}
@@ -0,0 +1,69 @@
+package docdigest
+
+import (
+	"archive/zip"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+)
+
+// volatileParts are the OOXML package parts rewritten on every save, with
+// authors, timestamps, revision counts and application versions, or
+// regenerated as previews.
+var volatileParts = []string{
+	"docProps/core.xml",
+	"docProps/app.xml",
+	"docProps/custom.xml",
+	"docProps/thumbnail.",
+}
+
+func volatile(name string) bool {
+	for _, part := range volatileParts {
+		if strings.HasPrefix(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// OOXML hashes the logical content of the OOXML package in r, of size
+// bytes: the name and uncompressed bytes of each part, sorted by name,
+// leaving out the document properties and thumbnail. Packages with the same
+// parts therefore hash the same however their zip entries were compressed,
+// ordered or timestamped. Digests are in the order of algorithms.
+func OOXML(r io.ReaderAt, size int64, algorithms ...string) ([][]byte, error) {
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*zip.File, 0, len(archive.File))
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() && !volatile(f.Name) {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	for _, f := range files {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hashes {
+			writeField(h, []byte(f.Name))
+			writeField(h, content)
+		}
+	}
+	return sums(hashes), nil
+}
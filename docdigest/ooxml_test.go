@@ -0,0 +1,43 @@
+package docdigest
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	_ "crypto/sha256"
+)
+
+func testPackage(parts [][2]string, method uint16) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, part := range parts {
+		w, _ := zw.CreateHeader(&zip.FileHeader{Name: part[0], Method: method, Modified: time.Now()})
+		w.Write([]byte(part[1]))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func Test_OOXML(t *testing.T) {
+	document := [2]string{"word/document.xml", "<w:document><w:p>Hello</w:p></w:document>"}
+	types := [2]string{"[Content_Types].xml", "<Types/>"}
+	original := testPackage([][2]string{types, document, {"docProps/core.xml", "<dc:creator>alice</dc:creator>"}}, zip.Deflate)
+	resaved := testPackage([][2]string{{"docProps/core.xml", "<dc:creator>bob</dc:creator>"}, {"docProps/app.xml", "<Application/>"}, document, types}, zip.Store)
+	edited := testPackage([][2]string{types, {"word/document.xml", "<w:document><w:p>Goodbye</w:p></w:document>"}}, zip.Deflate)
+
+	digest := func(data []byte) []byte {
+		digests, err := OOXML(bytes.NewReader(data), int64(len(data)), "sha256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return digests[0]
+	}
+	if !bytes.Equal(digest(original), digest(resaved)) {
+		t.Fatal("properties, part order or compression changed the digest")
+	}
+	if bytes.Equal(digest(original), digest(edited)) {
+		t.Fatal("edited document gave the same digest")
+	}
+}
@@ -0,0 +1,206 @@
+package docdigest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Revision is one revision of a PDF: the file up to and including one of
+// its end-of-file markers. The first is the document as originally written;
+// each incremental save appends another.
+type Revision struct {
+	// End is the offset just past the revision's %%EOF marker and the
+	// end of line following it.
+	End int64
+	// Digests are of the bytes 0 to End, in the order of algorithms.
+	Digests [][]byte
+}
+
+var eofMarker = []byte("%%EOF")
+
+// PDFRevisions reads a PDF from r once and returns the digests of each of
+// its revisions. Comparing the first revision's digest against a recorded
+// one detects tampering with the original document even after later
+// incremental saves, such as signatures or form fills, were appended.
+func PDFRevisions(r io.Reader, algorithms ...string) ([]Revision, error) {
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	var revisions []Revision
+	var offset int64
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			err = nil
+		}
+		// A marker may follow a lone CR, which does not end the line for
+		// ReadSlice, so look for it anywhere a line could begin.
+		start, pos := 0, 0
+		for {
+			at := bytes.Index(line[pos:], eofMarker)
+			if at < 0 {
+				break
+			}
+			at += pos
+			pos = at + len(eofMarker)
+			if at > 0 && line[at-1] != '\r' && line[at-1] != '\n' {
+				continue
+			}
+			end := pos
+			if end < len(line) && line[end] == '\r' {
+				end++
+			}
+			if end < len(line) && line[end] == '\n' {
+				end++
+			}
+			for _, h := range hashes {
+				h.Write(line[start:end])
+			}
+			offset += int64(end - start)
+			revisions = append(revisions, Revision{End: offset, Digests: sums(hashes)})
+			start, pos = end, end
+		}
+		for _, h := range hashes {
+			h.Write(line[start:])
+		}
+		offset += int64(len(line) - start)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(revisions) == 0 {
+		return nil, MalformedPDFError{Reason: "no %%EOF marker"}
+	}
+	return revisions, nil
+}
+
+var (
+	streamStart   = regexp.MustCompile(`>>\s*stream(\r\n|\n|\r)`)
+	streamEnd     = []byte("endstream")
+	objKeyword    = []byte(" obj")
+	lengthPattern = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	typePattern   = regexp.MustCompile(`/Type\s*/(\w+)`)
+	flatePattern  = regexp.MustCompile(`/Filter\s*/FlateDecode\b`)
+	filterPattern = regexp.MustCompile(`/Filter\b`)
+)
+
+// skippedStreams are the stream types that hold metadata or file structure
+// rather than content.
+var skippedStreams = map[string]bool{"Metadata": true, "XRef": true}
+
+// PDFContent hashes the streams of a PDF's original revision in the order
+// they appear: page contents, fonts, images and the rest, but not the XMP
+// metadata or cross-reference streams. Streams compressed with FlateDecode
+// alone are hashed decompressed, so recompressing them does not change the
+// digest; others are hashed as stored. The document-information dictionary
+// and other non-stream objects are left out.
+//
+// The file is scanned for stream objects rather than parsed from its
+// cross-reference table, so streams in object streams are covered as the
+// object stream itself, and objects left unused in the revision still
+// count. The original revision ends at the first %%EOF starting a line, or
+// in a linearized file the second, the first closing the first-page
+// trailer that precedes the pages.
+func PDFContent(data []byte, algorithms ...string) ([][]byte, error) {
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	markers := eofMarkers(data)
+	if len(markers) == 0 {
+		return nil, MalformedPDFError{Reason: "no %%EOF marker"}
+	}
+	end := markers[0]
+	if len(markers) > 1 && linearized(data) {
+		end = markers[1]
+	}
+	data = data[:end]
+	for {
+		loc := streamStart.FindIndex(data)
+		if loc == nil {
+			break
+		}
+		// The stream's dictionary may contain nested ones, so its keys are
+		// looked for from the start of the object.
+		dict := data[:loc[0]+2]
+		if obj := bytes.LastIndex(dict, objKeyword); obj >= 0 {
+			dict = dict[obj:]
+		}
+		body := data[loc[1]:]
+		length := -1
+		if m := lengthPattern.FindSubmatch(dict); m != nil && m[2] == nil {
+			length, _ = strconv.Atoi(string(m[1]))
+		}
+		if length < 0 || length > len(body) {
+			// An indirect or wrong length: fall back to the end marker.
+			length = bytes.Index(body, streamEnd)
+			if length < 0 {
+				return nil, MalformedPDFError{Reason: "stream without endstream"}
+			}
+			length = len(bytes.TrimRight(body[:length], "\r\n"))
+		}
+		stream := body[:length]
+		data = body[length:]
+
+		if m := typePattern.FindSubmatch(dict); m != nil && skippedStreams[string(m[1])] {
+			continue
+		}
+		if flatePattern.Match(dict) && len(filterPattern.FindAllIndex(dict, -1)) == 1 {
+			if decoded, err := inflate(stream); err == nil {
+				stream = decoded
+			}
+		}
+		for _, h := range hashes {
+			writeField(h, stream)
+		}
+	}
+	return sums(hashes), nil
+}
+
+// eofMarkers returns the offsets of the %%EOF markers in data that start a
+// line, as PDFRevisions recognizes them; others are within stream data.
+func eofMarkers(data []byte) []int {
+	var markers []int
+	for pos := 0; ; {
+		at := bytes.Index(data[pos:], eofMarker)
+		if at < 0 {
+			return markers
+		}
+		at += pos
+		if at == 0 || data[at-1] == '\r' || data[at-1] == '\n' {
+			markers = append(markers, at)
+		}
+		pos = at + len(eofMarker)
+	}
+}
+
+// linearizedPattern matches the key of a linearization parameter
+// dictionary, which must lie within a linearized file's first 1024 bytes.
+var linearizedPattern = regexp.MustCompile(`/Linearized\s`)
+
+func linearized(data []byte) bool {
+	if len(data) > 1024 {
+		data = data[:1024]
+	}
+	return linearizedPattern.Match(data)
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,107 @@
+package docdigest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+func testPDF(content []byte, compress bool, producer string) []byte {
+	stream, filter := content, ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(content)
+		zw.Close()
+		stream, filter = buf.Bytes(), " /Filter /FlateDecode"
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.7\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("4 0 obj\n<< /Length " + strconv.Itoa(len(stream)) + filter + " >>\nstream\n")
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n")
+	xmp := "<x:xmpmeta><pdf:Producer>" + producer + "</pdf:Producer></x:xmpmeta>"
+	pdf.WriteString("5 0 obj\n<< /Type /Metadata /Subtype /XML /Length " + strconv.Itoa(len(xmp)) + " >>\nstream\n" + xmp + "\nendstream\nendobj\n")
+	pdf.WriteString("6 0 obj\n<< /Producer (" + producer + ") >>\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Info 6 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func Test_PDFContent(t *testing.T) {
+	content := []byte("BT /F1 12 Tf 72 712 Td (Hello) Tj ET")
+	original := testPDF(content, true, "Writer 1.0")
+	digest := func(data []byte) []byte {
+		digests, err := PDFContent(data, "sha256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return digests[0]
+	}
+
+	resaved := append(testPDF(content, false, "Other Writer 2.0"), "7 0 obj\n<< /Annot true >>\nendobj\n%%EOF\n"...)
+	if !bytes.Equal(digest(original), digest(resaved)) {
+		t.Fatal("metadata, compression or an incremental save changed the content digest")
+	}
+	if bytes.Equal(digest(original), digest(testPDF([]byte("BT (Tampered) Tj ET"), true, "Writer 1.0"))) {
+		t.Fatal("changed page content gave the same digest")
+	}
+	if _, err := PDFContent([]byte("%PDF-1.7\n"), "sha256"); !errors.Is(err, ErrMalformedPDF) {
+		t.Fatalf("expected ErrMalformedPDF, got %v\n", err)
+	}
+}
+
+// testLinearizedPDF lays out a PDF as linearized files are, with a
+// first-page trailer and its %%EOF before the page objects.
+func testLinearizedPDF(content []byte) []byte {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.7\n")
+	pdf.WriteString("8 0 obj\n<< /Linearized 1 /L 1000 /N 1 >>\nendobj\n")
+	pdf.WriteString("xref\n8 1\ntrailer\n<< /Root 1 0 R >>\nstartxref\n0\n%%EOF\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("4 0 obj\n<< /Length " + strconv.Itoa(len(content)) + " >>\nstream\n")
+	pdf.Write(content)
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func Test_PDFContentLinearized(t *testing.T) {
+	digest := func(data []byte) []byte {
+		digests, err := PDFContent(data, "sha256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return digests[0]
+	}
+	if bytes.Equal(digest(testLinearizedPDF([]byte("BT (Hello) Tj ET"))), digest(testLinearizedPDF([]byte("BT (Other) Tj ET")))) {
+		t.Fatal("linearized files with different pages gave the same digest")
+	}
+
+	// A marker within stream data does not end the document.
+	embedded := []byte("binary %%EOF data")
+	if bytes.Equal(digest(testPDF(embedded, false, "Writer 1.0")), digest(testPDF([]byte("binary "), false, "Writer 1.0"))) {
+		t.Fatal("an end-of-file marker inside a stream cut the document short")
+	}
+}
+
+func Test_PDFRevisions(t *testing.T) {
+	original := testPDF([]byte("BT (Hello) Tj ET"), true, "Writer 1.0")
+	update := []byte("7 0 obj\r<< /Annot true >>\rendobj\r%%EOF\r")
+	revisions, err := PDFRevisions(bytes.NewReader(append(append([]byte(nil), original...), update...)), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].End != int64(len(original)) || revisions[1].End != int64(len(original)+len(update)) {
+		t.Fatalf("unexpected revisions %+v\n", revisions)
+	}
+	expected := sha256.Sum256(original)
+	if !bytes.Equal(revisions[0].Digests[0], expected[:]) {
+		t.Fatalf("first revision digest %x, expected %x\n", revisions[0].Digests[0], expected)
+	}
+}
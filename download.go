@@ -0,0 +1,89 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// ChecksumSource describes a published checksum file, such as the
+// SHA256SUMS distributed alongside an operating system installer.
+type ChecksumSource struct {
+	// URL of the checksum file, in either GNU or BSD format.
+	URL string
+	// Hash is the algorithm the checksum file's GNU-style lines are in.
+	// BSD-style lines name their own algorithm, and the one matching Hash is
+	// used.
+	Hash crypto.Hash
+	// SignatureURL, if set, is fetched and passed to VerifySignature along
+	// with the checksum file's contents before any of it is trusted.
+	SignatureURL string
+	// VerifySignature checks a detached signature over the checksum file.
+	// It must be set when SignatureURL is; it is where an OpenPGP, minisign
+	// or Ed25519 verifier is plugged in.
+	VerifySignature func(checksums, signature []byte) error
+	// Client is used for both fetches; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// VerifyDownload fetches the checksum file described by source, checks its
+// signature if one is configured, and hashes filename against the entry
+// listed for its base name. It returns nil only when the digests match; a
+// mismatch is reported as a DigestMismatchError.
+func VerifyDownload(filename string, source ChecksumSource) error {
+	if !source.Hash.Available() {
+		return UnavailableHashFunctionError{Hash: source.Hash}
+	}
+	client := source.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	checksums, err := fetch(client, source.URL)
+	if err != nil {
+		return err
+	}
+	if source.SignatureURL != "" {
+		if source.VerifySignature == nil {
+			return ErrNoSignatureVerifier
+		}
+		signature, err := fetch(client, source.SignatureURL)
+		if err != nil {
+			return err
+		}
+		if err = source.VerifySignature(checksums, signature); err != nil {
+			return err
+		}
+	}
+	m, err := manifest.Parse(bytes.NewReader(checksums), hashName(source.Hash))
+	if err != nil {
+		return err
+	}
+	expected, ok := m.Digest(filepath.Base(filename), hashName(source.Hash))
+	if !ok {
+		return ErrNoChecksum
+	}
+	hashset, err := FromFile(filename, source.Hash.New())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hashset[0], expected) {
+		return DigestMismatchError{Expected: expected, Actual: hashset[0]}
+	}
+	return nil
+}
+
+func fetch(client *http.Client, url string) ([]byte, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, HTTPStatusError{URL: url, StatusCode: response.StatusCode}
+	}
+	return io.ReadAll(response.Body)
+}
@@ -0,0 +1,55 @@
+package multihash
+
+import (
+	"crypto"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+func Test_VerifyDownload(t *testing.T) {
+	signatureChecked := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			w.Write([]byte("8bb5bc05618f1036a063bbf83cf74cca163a60343791c0c930acc31bf0c090ea *text1.txt\n"))
+		case "/BADSUMS":
+			w.Write([]byte("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  text1.txt\n"))
+		case "/SHA256SUMS.sig":
+			w.Write([]byte("signature"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	err := VerifyDownload("testing/text1.txt", ChecksumSource{
+		URL:          server.URL + "/SHA256SUMS",
+		Hash:         crypto.SHA256,
+		SignatureURL: server.URL + "/SHA256SUMS.sig",
+		VerifySignature: func(checksums, signature []byte) error {
+			signatureChecked = string(signature) == "signature"
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signatureChecked {
+		t.Fatal("signature verifier was not called with the signature")
+	}
+
+	err = VerifyDownload("testing/text1.txt", ChecksumSource{URL: server.URL + "/BADSUMS", Hash: crypto.SHA256})
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected a digest mismatch, got %v\n", err)
+	}
+
+	err = VerifyDownload("testing/text1.txt", ChecksumSource{URL: server.URL + "/MISSING", Hash: crypto.SHA256})
+	var statusError HTTPStatusError
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 status error, got %v\n", err)
+	}
+}
@@ -0,0 +1,109 @@
+package multihash
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// EqualReaders reports whether a and b have the same digest under the
+// algorithm registered as alg, reading both concurrently. A fast
+// non-cryptographic algorithm such as "crc32c" is enough to rule out
+// equality; a match under one only means the contents are probably equal.
+func EqualReaders(a, b io.Reader, alg string) (bool, error) {
+	hashA, err := New(alg)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := New(alg)
+	if err != nil {
+		return false, err
+	}
+	var sumB [][]byte
+	errB := make(chan error, 1)
+	go func() {
+		var err error
+		sumB, err = FromReader(b, hashB)
+		errB <- err
+	}()
+	sumA, err := FromReader(a, hashA)
+	if errFromB := <-errB; err == nil {
+		err = errFromB
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(sumA[0], sumB[0]), nil
+}
+
+// EqualFiles reports whether the named files have the same contents. Files
+// of different sizes are unequal without being read. Otherwise both are
+// hashed with alg as in EqualReaders, and if the digests match and
+// compareBytes is set, the files are read again and compared byte for byte,
+// so that a match is certain rather than merely probable.
+func EqualFiles(a, b string, alg string, compareBytes bool) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	equal, err := withFiles(a, b, func(fa, fb *os.File) (bool, error) {
+		return EqualReaders(fa, fb, alg)
+	})
+	if err != nil || !equal || !compareBytes {
+		return equal, err
+	}
+	return withFiles(a, b, func(fa, fb *os.File) (bool, error) {
+		return equalBytes(fa, fb)
+	})
+}
+
+func withFiles(a, b string, f func(fa, fb *os.File) (bool, error)) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+	return f(fa, fb)
+}
+
+func equalBytes(a, b io.Reader) (bool, error) {
+	bufferA, ok := (bufferPool.Get()).(*[]byte)
+	if !ok {
+		return false, ErrBufferGetFailed
+	}
+	defer bufferPool.Put(bufferA)
+	bufferB, ok := (bufferPool.Get()).(*[]byte)
+	if !ok {
+		return false, ErrBufferGetFailed
+	}
+	defer bufferPool.Put(bufferB)
+	for {
+		n, errA := io.ReadFull(a, *bufferA)
+		m, errB := io.ReadFull(b, *bufferB)
+		for _, err := range []error{errA, errB} {
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return false, err
+			}
+		}
+		if !bytes.Equal((*bufferA)[:n], (*bufferB)[:m]) {
+			return false, nil
+		}
+		// ReadFull only falls short at the end of input, and n == m here, so
+		// both inputs end together.
+		if errA != nil {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package multihash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+func Test_EqualReaders(t *testing.T) {
+	data := bytes.Repeat([]byte("equal "), 50000)
+	equal, err := EqualReaders(bytes.NewReader(data), bytes.NewReader(data), "crc32c")
+	if err != nil || !equal {
+		t.Fatalf("identical readers compared %v, %v\n", equal, err)
+	}
+	equal, err = EqualReaders(bytes.NewReader(data), bytes.NewReader(data[1:]), "sha256")
+	if err != nil || equal {
+		t.Fatalf("different readers compared %v, %v\n", equal, err)
+	}
+}
+
+func Test_EqualFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("file contents "), 20000)
+	different := append([]byte(nil), data...)
+	different[len(different)-1] ^= 1
+	for name, contents := range map[string][]byte{"a": data, "b": data, "c": different} {
+		if err := os.WriteFile(filepath.Join(dir, name), contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, test := range []struct {
+		other    string
+		expected bool
+	}{{"b", true}, {"c", false}} {
+		for _, compareBytes := range []bool{false, true} {
+			equal, err := EqualFiles(filepath.Join(dir, "a"), filepath.Join(dir, test.other), "crc32c", compareBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if equal != test.expected {
+				t.Fatalf("a and %s compared %v with compareBytes %v\n", test.other, equal, compareBytes)
+			}
+		}
+	}
+}
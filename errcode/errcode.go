@@ -0,0 +1,119 @@
+// package errcode attaches stable, machine-readable codes to the errors
+// returned throughout multihash, so that automation can branch on the kind
+// of failure without matching message text, which may change. Codes are
+// short snake_case strings, safe to log, compare and serialize, and are
+// never renamed once published.
+package errcode
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Code identifies a kind of failure.
+type Code string
+
+const (
+	// NotFound: a file, object, checksum or manifest entry does not exist.
+	NotFound Code = "not_found"
+	// OpenFailed: a file exists but could not be opened.
+	OpenFailed Code = "open_failed"
+	// ReadFailed: reading input failed partway.
+	ReadFailed Code = "read_failed"
+	// WriteFailed: writing output failed.
+	WriteFailed Code = "write_failed"
+	// Mismatch: data does not match an expected digest, size or signature.
+	Mismatch Code = "mismatch"
+	// UnsupportedAlgorithm: a hash algorithm is unknown or not linked into
+	// the binary.
+	UnsupportedAlgorithm Code = "unsupported_algorithm"
+	// UnsupportedFormat: input uses an encoding, such as a compression
+	// format, that is recognized but cannot be handled.
+	UnsupportedFormat Code = "unsupported_format"
+	// Malformed: a digest, checksum line or other input could not be
+	// parsed.
+	Malformed Code = "malformed"
+	// Ambiguous: input could be read more than one way, and needs to be
+	// made explicit.
+	Ambiguous Code = "ambiguous"
+	// InvalidArgument: the caller asked for something that cannot be done
+	// as asked, such as an impossible truncation or an unsafe path.
+	InvalidArgument Code = "invalid_argument"
+	// Conflict: two sources of truth disagree, such as merged manifests or
+	// colliding short IDs.
+	Conflict Code = "conflict"
+	// Incomplete: output or input was cut short, with no data known to be
+	// wrong.
+	Incomplete Code = "incomplete"
+	// Unavailable: a remote service failed or did not provide what was
+	// needed.
+	Unavailable Code = "unavailable"
+	// Cancelled: the operation was cancelled by its caller.
+	Cancelled Code = "cancelled"
+	// Timeout: the operation ran out of time.
+	Timeout Code = "timeout"
+	// Internal: an invariant of the library itself failed.
+	Internal Code = "internal"
+	// Unknown: the error carries no code and is not one Of recognizes.
+	Unknown Code = "unknown"
+)
+
+// Coder is implemented by errors that carry a Code. Every error type in
+// multihash and its subpackages implements it.
+type Coder interface {
+	Code() Code
+}
+
+// New returns an error with the given text and code, for sentinel errors.
+// Like errors.New, each call returns a distinct error, so sentinels remain
+// usable as errors.Is targets.
+func New(code Code, text string) error {
+	return &codedError{code: code, text: text}
+}
+
+type codedError struct {
+	code Code
+	text string
+}
+
+func (e *codedError) Error() string { return e.text }
+
+func (e *codedError) Code() Code { return e.code }
+
+// Of returns the code for err: that of the first error in its chain
+// implementing Coder, or else one derived from the standard library errors
+// multihash passes through, such as fs.ErrNotExist or context.Canceled. It
+// returns "" for a nil error and Unknown for anything else.
+func Of(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.Code()
+	}
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Cancelled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return Timeout
+	case errors.As(err, &timeout) && timeout.Timeout():
+		return Timeout
+	case errors.Is(err, fs.ErrNotExist):
+		return NotFound
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		switch pathErr.Op {
+		case "open":
+			return OpenFailed
+		case "write", "sync", "close":
+			return WriteFailed
+		}
+		return ReadFailed
+	}
+	return Unknown
+}
@@ -0,0 +1,37 @@
+package errcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+var errSample = New(Mismatch, "sample mismatch")
+
+func Test_Of(t *testing.T) {
+	_, openErr := os.Open("/nonexistent/file")
+	for _, tc := range []struct {
+		err  error
+		code Code
+	}{
+		{nil, ""},
+		{errSample, Mismatch},
+		{fmt.Errorf("checking: %w", errSample), Mismatch},
+		{openErr, NotFound},
+		{&fs.PathError{Op: "open", Path: "x", Err: fs.ErrPermission}, OpenFailed},
+		{&fs.PathError{Op: "read", Path: "x", Err: errors.New("I/O error")}, ReadFailed},
+		{fmt.Errorf("hashing: %w", context.Canceled), Cancelled},
+		{context.DeadlineExceeded, Timeout},
+		{errors.New("other"), Unknown},
+	} {
+		if code := Of(tc.err); code != tc.code {
+			t.Fatalf("%v has code %q, expected %q\n", tc.err, code, tc.code)
+		}
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", errSample), errSample) {
+		t.Fatal("coded sentinel does not match itself through wrapping")
+	}
+}
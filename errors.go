@@ -2,11 +2,19 @@ package multihash
 
 import (
 	"crypto"
-	"errors"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trytriangles/multihash/errcode"
 )
 
-var ErrBufferGetFailed = errors.New("buffer could not be asserted as *[]byte")
-var ErrHashFunctionNotAvailable = errors.New("hash function not available")
+var ErrBufferGetFailed = errcode.New(errcode.Internal, "buffer could not be asserted as *[]byte")
+var ErrHashFunctionNotAvailable = errcode.New(errcode.UnsupportedAlgorithm, "hash function not available")
+var ErrPoolClosed = errcode.New(errcode.Unavailable, "hashing pool closed")
+var ErrConcurrentWrite = errcode.New(errcode.Conflict, "MultiHasher written to concurrently")
 
 type UnavailableHashFunctionError struct {
 	Hash crypto.Hash
@@ -19,3 +27,472 @@ func (e UnavailableHashFunctionError) Error() string {
 func (e UnavailableHashFunctionError) Is(target error) bool {
 	return target == ErrHashFunctionNotAvailable
 }
+
+func (e UnavailableHashFunctionError) Code() errcode.Code {
+	return errcode.UnsupportedAlgorithm
+}
+
+type UnknownAlgorithmError struct {
+	Name string
+}
+
+func (e UnknownAlgorithmError) Error() string {
+	return "unknown hash algorithm " + strconv.Quote(e.Name)
+}
+
+func (e UnknownAlgorithmError) Is(target error) bool {
+	return target == ErrHashFunctionNotAvailable
+}
+
+func (e UnknownAlgorithmError) Code() errcode.Code {
+	return errcode.UnsupportedAlgorithm
+}
+
+var ErrDigestMismatch = errcode.New(errcode.Mismatch, "digest mismatch")
+
+type DigestMismatchError struct {
+	Expected []byte
+	Actual   []byte
+}
+
+func (e DigestMismatchError) Error() string {
+	return "digest mismatch: expected " + hex.EncodeToString(e.Expected) + ", got " + hex.EncodeToString(e.Actual)
+}
+
+func (e DigestMismatchError) Is(target error) bool {
+	return target == ErrDigestMismatch
+}
+
+func (e DigestMismatchError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+type BlockMismatchError struct {
+	Index    int
+	Offset   int64
+	Expected []byte
+	Actual   []byte
+}
+
+func (e BlockMismatchError) Error() string {
+	return "block " + strconv.Itoa(e.Index) + " at offset " + strconv.FormatInt(e.Offset, 10) + ": " +
+		DigestMismatchError{Expected: e.Expected, Actual: e.Actual}.Error()
+}
+
+func (e BlockMismatchError) Is(target error) bool {
+	return target == ErrDigestMismatch
+}
+
+func (e BlockMismatchError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+type BlockCountError struct {
+	Expected int
+	Actual   int
+}
+
+func (e BlockCountError) Error() string {
+	return "expected " + strconv.Itoa(e.Expected) + " blocks, got " + strconv.Itoa(e.Actual)
+}
+
+func (e BlockCountError) Is(target error) bool {
+	return target == ErrDigestMismatch
+}
+
+func (e BlockCountError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+var ErrInvalidBlockSize = errcode.New(errcode.InvalidArgument, "invalid block size")
+var ErrNoBlockHash = errcode.New(errcode.InvalidArgument, "block map has no NewHash")
+
+type BlockSizeError struct {
+	Size int64
+}
+
+func (e BlockSizeError) Error() string {
+	return "invalid block size " + strconv.FormatInt(e.Size, 10) + ", must be positive"
+}
+
+func (e BlockSizeError) Is(target error) bool {
+	return target == ErrInvalidBlockSize
+}
+
+func (e BlockSizeError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+var ErrInvalidChunkSize = errcode.New(errcode.InvalidArgument, "invalid chunk size")
+
+type ChunkSizeError struct {
+	Size int64
+}
+
+func (e ChunkSizeError) Error() string {
+	return "invalid chunk size " + strconv.FormatInt(e.Size, 10) + ", must be positive"
+}
+
+func (e ChunkSizeError) Is(target error) bool {
+	return target == ErrInvalidChunkSize
+}
+
+func (e ChunkSizeError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+type PartMismatchError struct {
+	// Part is the index of the mismatching part, or WholeStream.
+	Part     int
+	Hash     int
+	Expected []byte
+	Actual   []byte
+}
+
+func (e PartMismatchError) Error() string {
+	part := "whole stream"
+	if e.Part != WholeStream {
+		part = "part " + strconv.Itoa(e.Part)
+	}
+	return part + ", hash " + strconv.Itoa(e.Hash) + ": " +
+		DigestMismatchError{Expected: e.Expected, Actual: e.Actual}.Error()
+}
+
+func (e PartMismatchError) Is(target error) bool {
+	return target == ErrDigestMismatch
+}
+
+func (e PartMismatchError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+var ErrInvalidTruncation = errcode.New(errcode.InvalidArgument, "invalid digest truncation")
+
+type TruncationError struct {
+	Bits int
+	Size int
+}
+
+func (e TruncationError) Error() string {
+	return "cannot truncate a " + strconv.Itoa(e.Size) + "-bit digest to " + strconv.Itoa(e.Bits) + " bits"
+}
+
+func (e TruncationError) Is(target error) bool {
+	return target == ErrInvalidTruncation
+}
+
+func (e TruncationError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+type TruncatedNameError struct {
+	Name string
+}
+
+func (e TruncatedNameError) Error() string {
+	return e.Name + " names another algorithm, not a truncation"
+}
+
+func (e TruncatedNameError) Is(target error) bool {
+	return target == ErrInvalidTruncation
+}
+
+func (e TruncatedNameError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+var ErrIDCollision = errcode.New(errcode.Conflict, "short ID collision")
+
+type IDCollisionError struct {
+	ID string
+}
+
+func (e IDCollisionError) Error() string {
+	return "short ID " + e.ID + " already assigned to a different digest"
+}
+
+func (e IDCollisionError) Is(target error) bool {
+	return target == ErrIDCollision
+}
+
+func (e IDCollisionError) Code() errcode.Code {
+	return errcode.Conflict
+}
+
+var ErrMalformedDigest = errcode.New(errcode.Malformed, "malformed digest")
+
+type MalformedDigestError struct {
+	Text string
+}
+
+func (e MalformedDigestError) Error() string {
+	return "malformed digest " + strconv.Quote(e.Text)
+}
+
+func (e MalformedDigestError) Is(target error) bool {
+	return target == ErrMalformedDigest
+}
+
+func (e MalformedDigestError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+var ErrNoChecksum = errcode.New(errcode.NotFound, "no checksum listed for file")
+
+var ErrNoSignatureVerifier = errcode.New(errcode.InvalidArgument, "signature URL given without a signature verifier")
+
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e HTTPStatusError) Error() string {
+	return "fetching " + e.URL + ": " + strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+}
+
+func (e HTTPStatusError) Code() errcode.Code {
+	return errcode.Unavailable
+}
+
+var ErrReplayDiverged = errcode.New(errcode.Mismatch, "replay diverged from its log")
+
+type ReplayDivergedError struct {
+	Sequence int
+	Reason   string
+}
+
+func (e ReplayDivergedError) Error() string {
+	return "replay diverged from its log at read " + strconv.Itoa(e.Sequence) + ": " + e.Reason
+}
+
+func (e ReplayDivergedError) Is(target error) bool {
+	return target == ErrReplayDiverged
+}
+
+func (e ReplayDivergedError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+var ErrReplayedRead = errcode.New(errcode.ReadFailed, "replayed read error")
+
+type ReplayedReadError struct {
+	Sequence int
+	Text     string
+}
+
+func (e ReplayedReadError) Error() string {
+	return e.Text
+}
+
+func (e ReplayedReadError) Is(target error) bool {
+	return target == ErrReplayedRead
+}
+
+func (e ReplayedReadError) Code() errcode.Code {
+	return errcode.ReadFailed
+}
+
+var ErrMalformedReplayLog = errcode.New(errcode.Malformed, "malformed replay log")
+
+type MalformedReplayLogError struct {
+	Sequence int
+	Reason   string
+}
+
+func (e MalformedReplayLogError) Error() string {
+	return "malformed replay log at read " + strconv.Itoa(e.Sequence) + ": " + e.Reason
+}
+
+func (e MalformedReplayLogError) Is(target error) bool {
+	return target == ErrMalformedReplayLog
+}
+
+func (e MalformedReplayLogError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+var ErrAmbiguousDigest = errcode.New(errcode.Ambiguous, "ambiguous digest")
+
+type AmbiguousDigestError struct {
+	Text       string
+	Candidates []string
+}
+
+func (e AmbiguousDigestError) Error() string {
+	return "ambiguous digest " + strconv.Quote(e.Text) + ": could be any of " + strings.Join(e.Candidates, ", ")
+}
+
+func (e AmbiguousDigestError) Is(target error) bool {
+	return target == ErrAmbiguousDigest
+}
+
+func (e AmbiguousDigestError) Code() errcode.Code {
+	return errcode.Ambiguous
+}
+
+var ErrSizeMismatch = errcode.New(errcode.Mismatch, "size mismatch")
+
+type SizeMismatchError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e SizeMismatchError) Error() string {
+	if e.Actual > e.Expected {
+		return "size mismatch: expected " + strconv.FormatInt(e.Expected, 10) + " bytes, got more"
+	}
+	return "size mismatch: expected " + strconv.FormatInt(e.Expected, 10) + " bytes, got " + strconv.FormatInt(e.Actual, 10)
+}
+
+func (e SizeMismatchError) Is(target error) bool {
+	return target == ErrSizeMismatch
+}
+
+func (e SizeMismatchError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+var ErrInvalidSize = errcode.New(errcode.InvalidArgument, "invalid size")
+
+type InvalidSizeError struct {
+	Size int64
+}
+
+func (e InvalidSizeError) Error() string {
+	return "invalid size " + strconv.FormatInt(e.Size, 10) + ", must not be negative"
+}
+
+func (e InvalidSizeError) Is(target error) bool {
+	return target == ErrInvalidSize
+}
+
+func (e InvalidSizeError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+var ErrMalformedState = errcode.New(errcode.Malformed, "malformed hash state")
+
+type MalformedStateError struct {
+	Reason string
+}
+
+func (e MalformedStateError) Error() string {
+	return "malformed hash state: " + e.Reason
+}
+
+func (e MalformedStateError) Is(target error) bool {
+	return target == ErrMalformedState
+}
+
+func (e MalformedStateError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+var ErrUnresumableHash = errcode.New(errcode.UnsupportedAlgorithm, "hash state cannot be marshaled")
+
+type UnresumableHashError struct {
+	Algorithm string
+}
+
+func (e UnresumableHashError) Error() string {
+	return "state of hash algorithm " + strconv.Quote(e.Algorithm) + " cannot be marshaled"
+}
+
+func (e UnresumableHashError) Is(target error) bool {
+	return target == ErrUnresumableHash
+}
+
+func (e UnresumableHashError) Code() errcode.Code {
+	return errcode.UnsupportedAlgorithm
+}
+
+var ErrVolumeName = errcode.New(errcode.InvalidArgument, "not a numbered volume name")
+
+type VolumeNameError struct {
+	Name string
+}
+
+func (e VolumeNameError) Error() string {
+	return strconv.Quote(e.Name) + " does not end in a volume number"
+}
+
+func (e VolumeNameError) Is(target error) bool {
+	return target == ErrVolumeName
+}
+
+func (e VolumeNameError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+var ErrMalformedTrailer = errcode.New(errcode.Malformed, "malformed digest trailer")
+
+type MalformedTrailerError struct {
+	Reason string
+}
+
+func (e MalformedTrailerError) Error() string {
+	return "malformed digest trailer: " + e.Reason
+}
+
+func (e MalformedTrailerError) Is(target error) bool {
+	return target == ErrMalformedTrailer
+}
+
+func (e MalformedTrailerError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+var ErrDigestCount = errcode.New(errcode.InvalidArgument, "wrong number of expected digests")
+
+type DigestCountError struct {
+	Digests int
+	Hashes  int
+}
+
+func (e DigestCountError) Error() string {
+	return strconv.Itoa(e.Digests) + " expected digests given for " + strconv.Itoa(e.Hashes) + " hashes"
+}
+
+func (e DigestCountError) Is(target error) bool {
+	return target == ErrDigestCount
+}
+
+func (e DigestCountError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+var ErrIdleTimeout = errcode.New(errcode.Timeout, "read idle timeout")
+
+type IdleTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e IdleTimeoutError) Error() string {
+	return "no data read within " + e.Timeout.String()
+}
+
+func (e IdleTimeoutError) Is(target error) bool {
+	return target == ErrIdleTimeout
+}
+
+func (e IdleTimeoutError) Code() errcode.Code {
+	return errcode.Timeout
+}
+
+var ErrInvalidPercent = errcode.New(errcode.InvalidArgument, "invalid sample percent")
+
+type PercentError struct {
+	Percent float64
+}
+
+func (e PercentError) Error() string {
+	return "invalid sample percent " + strconv.FormatFloat(e.Percent, 'g', -1, 64) + ", must be from 0 to 100"
+}
+
+func (e PercentError) Is(target error) bool {
+	return target == ErrInvalidPercent
+}
+
+func (e PercentError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
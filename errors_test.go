@@ -0,0 +1,30 @@
+package multihash
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/trytriangles/multihash/errcode"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+func Test_errorCodes(t *testing.T) {
+	_, unknown := New("nonexistent")
+	_, fileErr := FromFile("testing/nonexistent.txt")
+	_, parseErr := manifest.ParseGNU(strings.NewReader("not a checksum line\n"), "md5")
+	for _, tc := range []struct {
+		err  error
+		code errcode.Code
+	}{
+		{unknown, errcode.UnsupportedAlgorithm},
+		{fileErr, errcode.NotFound},
+		{parseErr, errcode.Malformed},
+		{fmt.Errorf("checking: %w", DigestMismatchError{}), errcode.Mismatch},
+		{ErrNoChecksum, errcode.NotFound},
+	} {
+		if code := errcode.Of(tc.err); code != tc.code {
+			t.Fatalf("%v has code %q, expected %q\n", tc.err, code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package extract
+
+import (
+	"strconv"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+var ErrNoManifest = errcode.New(errcode.NotFound, "no manifest to verify archive members against")
+var ErrManifestNotFirst = errcode.New(errcode.Malformed, "embedded manifest follows members it describes")
+var ErrUnsafePath = errcode.New(errcode.InvalidArgument, "archive member path escapes destination")
+
+type UnsafePathError struct {
+	Name string
+}
+
+func (e UnsafePathError) Error() string {
+	return "unsafe archive member path " + strconv.Quote(e.Name)
+}
+
+func (e UnsafePathError) Is(target error) bool {
+	return target == ErrUnsafePath
+}
+
+func (e UnsafePathError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
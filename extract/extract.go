@@ -0,0 +1,205 @@
+// package extract unpacks tar and zip archives while verifying every member
+// against a checksum manifest in the same pass, so that nothing whose digest
+// does not match is ever written under its real name.
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/atomicfile"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// DefaultManifestName is the member treated as an embedded manifest when
+// Options.Manifest is nil and Options.ManifestName is empty.
+const DefaultManifestName = "SHA256SUMS"
+
+// Options configure an extraction.
+type Options struct {
+	// Manifest lists the expected digests of members, by their path within
+	// the archive. If nil, the manifest is read from the archive itself.
+	Manifest *manifest.Manifest
+	// ManifestName is the archive member holding the embedded manifest,
+	// DefaultManifestName if empty. In a tar stream it must precede every
+	// member it describes.
+	ManifestName string
+	// Algorithm is the algorithm of GNU-style lines in an embedded
+	// manifest, "sha256" if empty.
+	Algorithm string
+	// Durability controls syncing as each verified member is renamed into
+	// place; see atomicfile. The zero value syncs every member first.
+	Durability atomicfile.Durability
+}
+
+func (o Options) manifestName() string {
+	if o.ManifestName == "" {
+		return DefaultManifestName
+	}
+	return o.ManifestName
+}
+
+func (o Options) algorithm() string {
+	if o.Algorithm == "" {
+		return "sha256"
+	}
+	return o.Algorithm
+}
+
+// Tar extracts the tar stream r under dest. Each regular file is written to
+// a temporary name while being hashed and renamed into place only if it
+// matches the manifest; mismatching members are discarded and reported, as
+// are members the manifest does not list (Extra), which are never written.
+// Manifest entries with no member in the archive are reported as Missing.
+// Files get the permission bits of their headers, less setuid, setgid,
+// sticky and group and world write bits, as under the usual umask.
+// Directories are created as needed; other member types are skipped.
+func Tar(r io.Reader, dest string, opts Options) (report multihash.VerifyReport, err error) {
+	x := &extractor{dest: dest, opts: opts, manifest: opts.Manifest, seen: make(map[string]bool)}
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return x.report, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err = x.member(header.Name, header.FileInfo().Mode(), archive); err != nil {
+			return x.report, err
+		}
+	}
+	return x.finish()
+}
+
+// Zip extracts the zip archive in r, of the given size, under dest in the
+// same way as Tar. Since zip archives are random access, an embedded
+// manifest may be anywhere in the archive.
+func Zip(r io.ReaderAt, size int64, dest string, opts Options) (report multihash.VerifyReport, err error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return report, err
+	}
+	x := &extractor{dest: dest, opts: opts, manifest: opts.Manifest, seen: make(map[string]bool)}
+	if x.manifest == nil {
+		for _, file := range archive.File {
+			if manifest.MemberPath(file.Name) == x.opts.manifestName() {
+				if err = x.openAndRead(file); err != nil {
+					return report, err
+				}
+				break
+			}
+		}
+	}
+	for _, file := range archive.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		member, err := file.Open()
+		if err != nil {
+			return x.report, err
+		}
+		err = x.member(file.Name, file.Mode(), member)
+		member.Close()
+		if err != nil {
+			return x.report, err
+		}
+	}
+	return x.finish()
+}
+
+type extractor struct {
+	dest     string
+	opts     Options
+	manifest *manifest.Manifest
+	seen     map[string]bool
+	report   multihash.VerifyReport
+}
+
+func (x *extractor) openAndRead(file *zip.File) error {
+	member, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer member.Close()
+	return x.readManifest(member)
+}
+
+func (x *extractor) readManifest(r io.Reader) (err error) {
+	x.manifest, err = manifest.Parse(r, x.opts.algorithm())
+	return err
+}
+
+// member handles one regular file from the archive, with the mode its
+// header gives.
+func (x *extractor) member(header string, mode fs.FileMode, contents io.Reader) error {
+	name := manifest.MemberPath(header)
+	if name == "" {
+		return UnsafePathError{Name: header}
+	}
+	if x.opts.Manifest == nil && name == x.opts.manifestName() {
+		if x.manifest != nil {
+			// Already loaded, as happens for zip archives.
+			return nil
+		}
+		if len(x.seen) > 0 {
+			return ErrManifestNotFirst
+		}
+		return x.readManifest(contents)
+	}
+	if x.manifest == nil {
+		return ErrNoManifest
+	}
+	x.seen[name] = true
+	entry, ok := x.manifest.Lookup(name)
+	if !ok {
+		x.report.Extra = append(x.report.Extra, name)
+		return nil
+	}
+	target := filepath.Join(x.dest, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	temp, err := atomicfile.CreateMode(target, mode.Perm()&^0o022, x.opts.Durability)
+	if err != nil {
+		return err
+	}
+	defer temp.Abort()
+	hashes, err := multihash.NewAll(x.manifest.Algorithms...)
+	if err != nil {
+		return err
+	}
+	digests, err := multihash.FromReader(io.TeeReader(contents, temp), hashes...)
+	if err != nil {
+		return err
+	}
+	if !multihash.DigestsMatch(entry.Digests, digests) {
+		x.report.Mismatched = append(x.report.Mismatched, name)
+		return nil
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	x.report.Verified = append(x.report.Verified, name)
+	return nil
+}
+
+func (x *extractor) finish() (multihash.VerifyReport, error) {
+	if x.manifest == nil {
+		return x.report, ErrNoManifest
+	}
+	for _, entry := range x.manifest.Entries {
+		if !x.seen[manifest.MemberPath(entry.Path)] {
+			x.report.Missing = append(x.report.Missing, entry.Path)
+		}
+	}
+	return x.report, nil
+}
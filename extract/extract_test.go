@@ -0,0 +1,119 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type member struct {
+	name     string
+	contents string
+}
+
+func sumsFor(members ...member) string {
+	var sums bytes.Buffer
+	for _, m := range members {
+		sum := sha256.Sum256([]byte(m.contents))
+		sums.WriteString(hex.EncodeToString(sum[:]) + "  " + m.name + "\n")
+	}
+	return sums.String()
+}
+
+var (
+	good     = member{"bin/tool", "trusted binary"}
+	tampered = member{"lib/data", "tampered data"}
+	extra    = member{"extra.txt", "not in manifest"}
+	missing  = member{"docs/missing", "never shipped"}
+	sums     = member{"SHA256SUMS", sumsFor(good, member{"lib/data", "original data"}, missing)}
+)
+
+func Test_Tar(t *testing.T) {
+	var archive bytes.Buffer
+	w := tar.NewWriter(&archive)
+	for _, m := range []member{sums, good, tampered, extra} {
+		mode := int64(0o644)
+		if m == good {
+			mode = 0o4777
+		}
+		w.WriteHeader(&tar.Header{Name: m.name, Mode: mode, Size: int64(len(m.contents)), Typeflag: tar.TypeReg})
+		w.Write([]byte(m.contents))
+	}
+	w.Close()
+
+	dest := t.TempDir()
+	report, err := Tar(&archive, dest, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Verified) != 1 || len(report.Mismatched) != 1 || len(report.Extra) != 1 || len(report.Missing) != 1 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	if written, err := os.ReadFile(filepath.Join(dest, "bin", "tool")); err != nil || string(written) != good.contents {
+		t.Fatalf("verified member was not extracted: %q, %v\n", written, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "bin", "tool")); err != nil || info.Mode() != 0o755 {
+		t.Fatalf("verified member has mode %v, expected -rwxr-xr-x (%v)\n", info.Mode(), err)
+	}
+	for _, name := range []string{tampered.name, extra.name} {
+		if _, err := os.Stat(filepath.Join(dest, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s was written despite failing verification\n", name)
+		}
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dest, "*", ".*"))
+	if len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v\n", leftovers)
+	}
+}
+
+func Test_TarUnsafePath(t *testing.T) {
+	var archive bytes.Buffer
+	w := tar.NewWriter(&archive)
+	w.WriteHeader(&tar.Header{Name: "..", Mode: 0o644, Typeflag: tar.TypeReg})
+	w.Close()
+	var unsafe UnsafePathError
+	if _, err := Tar(&archive, t.TempDir(), Options{}); !errors.As(err, &unsafe) || unsafe.Name != ".." {
+		t.Fatalf("member named \"..\" gave %v\n", err)
+	}
+}
+
+func Test_TarManifestNotFirst(t *testing.T) {
+	var archive bytes.Buffer
+	w := tar.NewWriter(&archive)
+	for _, m := range []member{good, sums} {
+		w.WriteHeader(&tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.contents)), Typeflag: tar.TypeReg})
+		w.Write([]byte(m.contents))
+	}
+	w.Close()
+	if _, err := Tar(&archive, t.TempDir(), Options{}); err != ErrNoManifest {
+		t.Fatalf("expected ErrNoManifest, got %v\n", err)
+	}
+}
+
+func Test_Zip(t *testing.T) {
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for _, m := range []member{good, {"../../lib/data", "original data"}, sums} {
+		f, _ := w.Create(m.name)
+		f.Write([]byte(m.contents))
+	}
+	w.Close()
+
+	dest := t.TempDir()
+	report, err := Zip(bytes.NewReader(archive.Bytes()), int64(archive.Len()), dest, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Verified) != 2 || len(report.Missing) != 1 || report.OK() {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "lib", "data")); err != nil {
+		t.Fatalf("climbing member was not confined to the destination: %v\n", err)
+	}
+}
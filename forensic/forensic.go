@@ -0,0 +1,237 @@
+// package forensic acquires an image of a device or file the way forensic
+// imaging tools do: the source is read once, written to a sequence of raw
+// segment files (image.001, image.002, ...) with per-segment and
+// whole-image digests computed as it goes, unreadable blocks are retried,
+// zero-filled and mapped rather than aborting the acquisition, and the
+// result is described by a plain-text acquisition log.
+//
+// The segments are raw images, not the EWF (E01) container format; the log
+// records what an E01 header would, so that the acquisition can be
+// verified, and converted, with standard tools.
+package forensic
+
+import (
+	"hash"
+	"io"
+	"time"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/atomicfile"
+)
+
+// Default values used for zero Options fields.
+const (
+	DefaultSegmentSize = 2 << 30
+	DefaultBlockSize   = 512
+	DefaultReadSize    = 1 << 20
+	DefaultRetries     = 2
+)
+
+// DefaultAlgorithms are those forensic practice records, when
+// Options.Algorithms is empty.
+var DefaultAlgorithms = []string{"md5", "sha1", "sha256"}
+
+// Case identifies the acquisition in its log.
+type Case struct {
+	Number      string
+	Evidence    string
+	Examiner    string
+	Description string
+	Notes       string
+}
+
+// Options configures an acquisition. The zero value writes 2 GiB segments,
+// reads 1 MiB at a time and handles errors in 512-byte blocks, retried
+// twice.
+type Options struct {
+	Case Case
+	// Source names the device or file being acquired, for the log.
+	Source     string
+	Algorithms []string
+	// SegmentSize is rounded down to a multiple of BlockSize.
+	SegmentSize int64
+	// BlockSize is the granularity at which read errors are retried and
+	// mapped: the device's sector size.
+	BlockSize int
+	ReadSize  int
+	// Retries is the number of further attempts at a failed block; -1
+	// disables them.
+	Retries int
+	// Durability controls syncing as each segment is atomically written.
+	Durability atomicfile.Durability
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+func (o Options) withDefaults() Options {
+	if len(o.Algorithms) == 0 {
+		o.Algorithms = DefaultAlgorithms
+	}
+	if o.SegmentSize <= 0 {
+		o.SegmentSize = DefaultSegmentSize
+	}
+	if o.BlockSize <= 0 {
+		o.BlockSize = DefaultBlockSize
+	}
+	if o.ReadSize < o.BlockSize {
+		o.ReadSize = DefaultReadSize
+	}
+	// Reads and segments are whole blocks, so that a failed read maps onto
+	// the device's sectors. Reads are rounded up, so that a block larger
+	// than DefaultReadSize is still read whole.
+	o.ReadSize = (o.ReadSize + o.BlockSize - 1) / o.BlockSize * o.BlockSize
+	if o.SegmentSize < int64(o.BlockSize) {
+		o.SegmentSize = int64(o.BlockSize)
+	}
+	o.SegmentSize -= o.SegmentSize % int64(o.BlockSize)
+	switch {
+	case o.Retries < 0:
+		o.Retries = 0
+	case o.Retries == 0:
+		o.Retries = DefaultRetries
+	}
+	if o.now == nil {
+		o.now = time.Now
+	}
+	return o
+}
+
+// BadRange is a run of blocks that could not be read. They were written to
+// the image, and hashed, as zeros.
+type BadRange struct {
+	Offset int64
+	Length int64
+	// Err is the last error reading the range's first block.
+	Err string
+}
+
+// Acquisition is the record of an acquisition, as written by WriteLog.
+type Acquisition struct {
+	Options   Options
+	Size      int64
+	Started   time.Time
+	Finished  time.Time
+	Segments  []multihash.Chunk
+	Digests   [][]byte
+	BadRanges []BadRange
+}
+
+// Acquire reads size bytes from source and writes them to segment files
+// named by name(0), name(1), and so on. Each segment is written atomically,
+// so a segment file that exists is complete. Read errors do not stop the
+// acquisition; they are retried block by block and mapped in BadRanges.
+// Errors writing the image do, returning the record so far with the error.
+func Acquire(source io.ReaderAt, size int64, name func(index int) string, opts Options) (*Acquisition, error) {
+	opts = opts.withDefaults()
+	whole, err := multihash.NewAll(opts.Algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	a := &Acquisition{Options: opts, Size: size, Started: opts.now()}
+	buf := make([]byte, opts.ReadSize)
+	for offset, index := int64(0), 0; offset < size; index++ {
+		length := opts.SegmentSize
+		if size-offset < length {
+			length = size - offset
+		}
+		segment, err := a.writeSegment(source, offset, length, name(index), buf, whole)
+		if err != nil {
+			return a, err
+		}
+		a.Segments = append(a.Segments, segment)
+		offset += length
+	}
+	a.Digests = sums(whole)
+	a.Finished = opts.now()
+	return a, nil
+}
+
+func (a *Acquisition) writeSegment(
+	source io.ReaderAt,
+	offset, length int64,
+	path string,
+	buf []byte,
+	whole []hash.Hash,
+) (multihash.Chunk, error) {
+	f, err := atomicfile.Create(path, a.Options.Durability)
+	if err != nil {
+		return multihash.Chunk{}, err
+	}
+	defer f.Abort()
+	hashes, err := multihash.NewAll(a.Options.Algorithms...)
+	if err != nil {
+		return multihash.Chunk{}, err
+	}
+	for done := int64(0); done < length; {
+		n := int64(len(buf))
+		if length-done < n {
+			n = length - done
+		}
+		chunk := buf[:n]
+		a.read(source, chunk, offset+done)
+		if _, err = f.Write(chunk); err != nil {
+			return multihash.Chunk{}, err
+		}
+		for _, h := range hashes {
+			h.Write(chunk)
+		}
+		for _, h := range whole {
+			h.Write(chunk)
+		}
+		done += n
+	}
+	if err = f.Close(); err != nil {
+		return multihash.Chunk{}, err
+	}
+	return multihash.Chunk{Path: path, Size: length, Digests: sums(hashes)}, nil
+}
+
+// read fills p from source at offset, falling back to block-sized reads
+// when the whole read fails, and zero-filling the blocks that still fail
+// after the retries.
+func (a *Acquisition) read(source io.ReaderAt, p []byte, offset int64) {
+	if n, err := source.ReadAt(p, offset); n == len(p) && (err == nil || err == io.EOF) {
+		return
+	}
+	blockSize := a.Options.BlockSize
+	for start := 0; start < len(p); start += blockSize {
+		end := start + blockSize
+		if end > len(p) {
+			end = len(p)
+		}
+		block := p[start:end]
+		var err error
+		for attempt := 0; attempt <= a.Options.Retries; attempt++ {
+			var n int
+			if n, err = source.ReadAt(block, offset+int64(start)); n == len(block) {
+				err = nil
+				break
+			}
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		if err != nil {
+			for index := range block {
+				block[index] = 0
+			}
+			a.markBad(offset+int64(start), int64(len(block)), err)
+		}
+	}
+}
+
+func (a *Acquisition) markBad(offset, length int64, err error) {
+	if last := len(a.BadRanges) - 1; last >= 0 && a.BadRanges[last].Offset+a.BadRanges[last].Length == offset {
+		a.BadRanges[last].Length += length
+		return
+	}
+	a.BadRanges = append(a.BadRanges, BadRange{Offset: offset, Length: length, Err: err.Error()})
+}
+
+func sums(hashes []hash.Hash) [][]byte {
+	digests := make([][]byte, len(hashes))
+	for index, h := range hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return digests
+}
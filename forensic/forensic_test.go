@@ -0,0 +1,117 @@
+package forensic
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	_ "crypto/sha1"
+)
+
+// badReader fails reads touching the bytes from bad to bad+length.
+type badReader struct {
+	data        []byte
+	bad, length int64
+}
+
+func (b badReader) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < b.bad+b.length && offset+int64(len(p)) > b.bad {
+		return 0, errors.New("I/O error")
+	}
+	return bytes.NewReader(b.data).ReadAt(p, offset)
+}
+
+func Test_Acquire(t *testing.T) {
+	data := make([]byte, 10000)
+	for index := range data {
+		data[index] = byte(index * 31)
+	}
+	source := badReader{data: data, bad: 4096, length: 1000}
+	dir := t.TempDir()
+	name := func(index int) string {
+		return filepath.Join(dir, "image."+strconv.Itoa(1001 + index)[1:])
+	}
+	clock := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	opts := Options{
+		Case:        Case{Number: "2024-17", Examiner: "J. Doe"},
+		Source:      "/dev/sdb",
+		SegmentSize: 4096,
+		ReadSize:    2048,
+		now:         func() time.Time { return clock },
+	}
+	a, err := Acquire(source, int64(len(data)), name, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sectors 8 to 9 overlap the bad bytes and are zero-filled.
+	expected := append([]byte(nil), data...)
+	for index := 8 * 512; index < 10*512; index++ {
+		expected[index] = 0
+	}
+	if len(a.BadRanges) != 1 || a.BadRanges[0].Offset != 8*512 || a.BadRanges[0].Length != 2*512 {
+		t.Fatalf("unexpected bad ranges %+v\n", a.BadRanges)
+	}
+	var image []byte
+	for _, segment := range a.Segments {
+		content, err := os.ReadFile(segment.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digest := md5.Sum(content); !bytes.Equal(segment.Digests[0], digest[:]) {
+			t.Fatalf("%s: md5 %x, expected %x\n", segment.Path, segment.Digests[0], digest)
+		}
+		image = append(image, content...)
+	}
+	if len(a.Segments) != 3 || !bytes.Equal(image, expected) {
+		t.Fatalf("%d segments do not hold the expected image\n", len(a.Segments))
+	}
+	if digest := sha256.Sum256(expected); !bytes.Equal(a.Digests[2], digest[:]) {
+		t.Fatalf("image sha256 %x, expected %x\n", a.Digests[2], digest)
+	}
+
+	var log bytes.Buffer
+	if err = a.WriteLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Case number: 2024-17",
+		"Source: /dev/sdb",
+		"Acquisition started: 2024-03-01T12:00:00Z",
+		"image.002 (4096 bytes)",
+		"Unreadable ranges: 1",
+		"sectors 8 to 9 (offset 4096, 1024 bytes): I/O error",
+	} {
+		if !strings.Contains(log.String(), line) {
+			t.Fatalf("log lacks %q:\n%s\n", line, log.String())
+		}
+	}
+
+	// A range ending partway through a sector still covers that sector.
+	a.BadRanges = []BadRange{{Offset: 1024, Length: 100, Err: "short read"}}
+	log.Reset()
+	if err = a.WriteLog(&log); err != nil {
+		t.Fatal(err)
+	}
+	if line := "sectors 2 to 2 (offset 1024, 100 bytes)"; !strings.Contains(log.String(), line) {
+		t.Fatalf("log lacks %q:\n%s\n", line, log.String())
+	}
+}
+
+func Test_Options_largeBlocks(t *testing.T) {
+	opts := Options{BlockSize: 2 << 20}.withDefaults()
+	if opts.ReadSize != 2<<20 {
+		t.Fatalf("read size %d for a 2 MiB block\n", opts.ReadSize)
+	}
+	opts = Options{BlockSize: 4096, ReadSize: 5000}.withDefaults()
+	if opts.ReadSize != 8192 {
+		t.Fatalf("read size %d, expected 8192\n", opts.ReadSize)
+	}
+}
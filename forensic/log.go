@@ -0,0 +1,67 @@
+package forensic
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// WriteLog writes the acquisition log: the case details, the source and
+// its geometry, the times, the digests of each segment and of the whole
+// image, and the map of unreadable ranges, in the "Key: value" layout of
+// the logs imaging tools keep beside their images.
+func (a *Acquisition) WriteLog(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	field := func(key, value string) {
+		if value != "" {
+			bw.WriteString(key + ": " + value + "\n")
+		}
+	}
+	c := a.Options.Case
+	bw.WriteString("Acquisition log\n\n")
+	field("Case number", c.Number)
+	field("Evidence number", c.Evidence)
+	field("Examiner", c.Examiner)
+	field("Description", c.Description)
+	field("Notes", c.Notes)
+	bw.WriteString("\n")
+	field("Source", a.Options.Source)
+	field("Size", strconv.FormatInt(a.Size, 10)+" bytes")
+	field("Sector size", strconv.Itoa(a.Options.BlockSize)+" bytes")
+	field("Sector count", strconv.FormatInt((a.Size+int64(a.Options.BlockSize)-1)/int64(a.Options.BlockSize), 10))
+	field("Segment size", strconv.FormatInt(a.Options.SegmentSize, 10)+" bytes")
+	field("Read retries", strconv.Itoa(a.Options.Retries))
+	field("Acquisition started", a.Started.UTC().Format(time.RFC3339))
+	if !a.Finished.IsZero() {
+		field("Acquisition finished", a.Finished.UTC().Format(time.RFC3339))
+	} else {
+		field("Acquisition finished", "incomplete")
+	}
+
+	bw.WriteString("\nSegments:\n")
+	for _, segment := range a.Segments {
+		bw.WriteString(filepath.Base(segment.Path) + " (" + strconv.FormatInt(segment.Size, 10) + " bytes)\n")
+		for index, algorithm := range a.Options.Algorithms {
+			bw.WriteString("\t" + algorithm + ": " + hex.EncodeToString(segment.Digests[index]) + "\n")
+		}
+	}
+
+	bw.WriteString("\nImage digests:\n")
+	for index, algorithm := range a.Options.Algorithms {
+		if index < len(a.Digests) {
+			bw.WriteString("\t" + algorithm + ": " + hex.EncodeToString(a.Digests[index]) + "\n")
+		}
+	}
+
+	bw.WriteString("\nUnreadable ranges: " + strconv.Itoa(len(a.BadRanges)) + "\n")
+	block := int64(a.Options.BlockSize)
+	for _, r := range a.BadRanges {
+		bw.WriteString("\tsectors " + strconv.FormatInt(r.Offset/block, 10) + " to " +
+			strconv.FormatInt((r.Offset+r.Length+block-1)/block-1, 10) + " (offset " +
+			strconv.FormatInt(r.Offset, 10) + ", " + strconv.FormatInt(r.Length, 10) + " bytes): " + r.Err + "\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,137 @@
+package multihash
+
+import (
+	"hash"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelWriteSize is the smallest write a MultiHasher spreads over
+// goroutines. Starting and waiting for them costs a few microseconds per
+// Write, about what SHA-256 takes over 4 KiB on hardware with SHA
+// extensions, so only writes some times larger gain from it. It matches the
+// buffer io.Copy uses, so that copies into a MultiHasher, directly or
+// through an io.MultiWriter, are hashed in parallel.
+const parallelWriteSize = 32 * 1024
+
+// MultiHasher is an io.Writer computing several hashes of everything written
+// to it, for data that is already flowing through an io.Copy pipeline and
+// cannot be handed to FromReader, e.g. as one destination of an
+// io.MultiWriter or the side of an io.TeeReader. Large writes are hashed by
+// all the hashes concurrently, grouped over at most runtime.GOMAXPROCS(0)
+// goroutines as in FromReader; each Write returns only once every hash has
+// consumed it, so p is never retained. Smaller writes are hashed serially.
+//
+// A MultiHasher is not safe for concurrent use: the order in which writes
+// reach the hashes is the data they digest. A Write or ReadFrom made while
+// another is in progress fails with ErrConcurrentWrite rather than
+// interleaving, though Sums and Reset are not checked.
+type MultiHasher struct {
+	hashes []hash.Hash
+	groups [][]hash.Hash
+	errs   []error
+	// busy is 1 while a Write or ReadFrom is in progress.
+	busy int32
+}
+
+// NewMultiHasher returns a MultiHasher writing to hashes. Sums returns their
+// digests in the same order.
+func NewMultiHasher(hashes ...hash.Hash) *MultiHasher {
+	groups := groupHashes(hashes, runtime.GOMAXPROCS(0))
+	return &MultiHasher{hashes: hashes, groups: groups, errs: make([]error, len(groups))}
+}
+
+// Write hashes p with every hash. It fails only if one of the hashes does,
+// which those in the standard library never do.
+func (m *MultiHasher) Write(p []byte) (int, error) {
+	if !atomic.CompareAndSwapInt32(&m.busy, 0, 1) {
+		return 0, ErrConcurrentWrite
+	}
+	defer atomic.StoreInt32(&m.busy, 0)
+	return m.write(p)
+}
+
+func (m *MultiHasher) write(p []byte) (int, error) {
+	if len(p) < parallelWriteSize || len(m.groups) < 2 {
+		for _, h := range m.hashes {
+			if _, err := h.Write(p); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	var wg sync.WaitGroup
+	for index := 1; index < len(m.groups); index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			m.errs[index] = writeGroup(m.groups[index], p)
+		}(index)
+	}
+	m.errs[0] = writeGroup(m.groups[0], p)
+	wg.Wait()
+	for _, err := range m.errs {
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func writeGroup(hashes []hash.Hash, p []byte) error {
+	for _, h := range hashes {
+		if _, err := h.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadFrom hashes r to its end, reading into a pooled buffer, so that
+// io.Copy(m, r) does not allocate one of its own.
+func (m *MultiHasher) ReadFrom(r io.Reader) (n int64, err error) {
+	if !atomic.CompareAndSwapInt32(&m.busy, 0, 1) {
+		return 0, ErrConcurrentWrite
+	}
+	defer atomic.StoreInt32(&m.busy, 0)
+	buffer, ok := (bufferPool.Get()).(*[]byte)
+	if !ok {
+		return 0, ErrBufferGetFailed
+	}
+	defer bufferPool.Put(buffer)
+	for {
+		bytesRead, readErr := r.Read(*buffer)
+		if bytesRead > 0 {
+			if _, err = m.write((*buffer)[:bytesRead]); err != nil {
+				return n, err
+			}
+			n += int64(bytesRead)
+		}
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return n, readErr
+		}
+	}
+}
+
+// Sums returns the digests of everything written since the MultiHasher was
+// created or last Reset, in the order the hashes were given. Writing may
+// continue afterwards.
+func (m *MultiHasher) Sums() [][]byte {
+	sums := make([][]byte, len(m.hashes))
+	for index, h := range m.hashes {
+		sums[index] = h.Sum(nil)
+	}
+	return sums
+}
+
+// Reset resets every hash, so the MultiHasher can be reused for new data.
+func (m *MultiHasher) Reset() {
+	for _, h := range m.hashes {
+		h.Reset()
+	}
+}
@@ -0,0 +1,76 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+func Test_MultiHasher(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	data := bytes.Repeat([]byte("multihash"), 20000)
+	algorithms := []crypto.Hash{crypto.MD5, crypto.SHA1, crypto.SHA256}
+	m := NewMultiHasher(algorithms[0].New(), algorithms[1].New(), algorithms[2].New())
+
+	var copied bytes.Buffer
+	if _, err := io.Copy(io.MultiWriter(&copied, m), bytes.NewReader(data[:100])); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(io.MultiWriter(&copied, m), bytes.NewReader(data[100:])); err != nil {
+		t.Fatal(err)
+	}
+	for index, sum := range m.Sums() {
+		expected := algorithms[index].New()
+		expected.Write(data)
+		if !slicesEqual(sum, expected.Sum(nil)) {
+			t.Fatalf("digest %d was %x, expected %x\n", index, sum, expected.Sum(nil))
+		}
+	}
+
+	m.Reset()
+	if n, err := m.ReadFrom(bytes.NewReader(data[:10])); n != 10 || err != nil {
+		t.Fatalf("ReadFrom returned %d, %v\n", n, err)
+	}
+	expected := algorithms[2].New()
+	expected.Write(data[:10])
+	if sum := m.Sums()[2]; !slicesEqual(sum, expected.Sum(nil)) {
+		t.Fatalf("digest after Reset was %x, expected %x\n", sum, expected.Sum(nil))
+	}
+}
+
+// gatedReader returns EOF once its gate is closed, signalling on started
+// when it is first read.
+type gatedReader struct {
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (r gatedReader) Read(p []byte) (int, error) {
+	close(r.started)
+	<-r.gate
+	return 0, io.EOF
+}
+
+func Test_MultiHasherConcurrentWrite(t *testing.T) {
+	m := NewMultiHasher(crypto.SHA256.New())
+	r := gatedReader{started: make(chan struct{}), gate: make(chan struct{})}
+	done := make(chan error)
+	go func() {
+		_, err := m.ReadFrom(r)
+		done <- err
+	}()
+	<-r.started
+	if _, err := m.Write([]byte("interleaved")); !errors.Is(err, ErrConcurrentWrite) {
+		t.Fatalf("Write during ReadFrom gave %v, expected ErrConcurrentWrite\n", err)
+	}
+	close(r.gate)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Write([]byte("after")); err != nil {
+		t.Fatalf("Write after ReadFrom gave %v\n", err)
+	}
+}
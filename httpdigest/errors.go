@@ -0,0 +1,47 @@
+package httpdigest
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/errcode"
+)
+
+type UploadMismatchError struct {
+	FormName  string
+	FileName  string
+	Algorithm string
+	Expected  []byte
+	Actual    []byte
+}
+
+func (e UploadMismatchError) Error() string {
+	return "upload " + strconv.Quote(e.FileName) + " in field " + strconv.Quote(e.FormName) + ": " +
+		e.Algorithm + " digest mismatch: expected " + hex.EncodeToString(e.Expected) + ", got " + hex.EncodeToString(e.Actual)
+}
+
+func (e UploadMismatchError) Is(target error) bool {
+	return target == multihash.ErrDigestMismatch
+}
+
+func (e UploadMismatchError) Code() errcode.Code {
+	return errcode.Mismatch
+}
+
+type MalformedExpectedDigestError struct {
+	Field string
+	Value string
+}
+
+func (e MalformedExpectedDigestError) Error() string {
+	return "malformed expected digest " + strconv.Quote(e.Value) + " in " + e.Field
+}
+
+func (e MalformedExpectedDigestError) Is(target error) bool {
+	return target == multihash.ErrMalformedDigest
+}
+
+func (e MalformedExpectedDigestError) Code() errcode.Code {
+	return errcode.Malformed
+}
@@ -0,0 +1,153 @@
+// package httpdigest wraps http.FileServer so that served files carry
+// digests of their content: a strong ETag, the Repr-Digest and
+// Content-Digest fields of RFC 9530, and optionally a Subresource Integrity
+// value. Digests are computed on first request and cached until the file's
+// modification time or size changes.
+package httpdigest
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trytriangles/multihash"
+)
+
+// DefaultAlgorithm is used when Options.Algorithms is empty.
+const DefaultAlgorithm = "sha256"
+
+// fieldNames maps multihash algorithms to their RFC 9530 digest algorithm
+// keys. Algorithms without one are not sent in digest fields.
+var fieldNames = map[string]string{
+	"sha256": "sha-256",
+	"sha512": "sha-512",
+}
+
+// sriAlgorithms are the algorithms Subresource Integrity defines.
+var sriAlgorithms = map[string]bool{"sha256": true, "sha384": true, "sha512": true}
+
+// Options configures the digests a FileServer computes and the headers it
+// sets. The zero value sends a sha256 ETag and digest fields.
+type Options struct {
+	// Algorithms lists the registered multihash algorithms to compute. The
+	// first is used for the ETag.
+	Algorithms []string
+	// SRIHeader, if set, names a header to carry the Subresource Integrity
+	// value of each algorithm SRI defines, space-separated, e.g.
+	// "X-Integrity". Browsers do not read such a header; it is for clients
+	// and build tools that generate integrity attributes.
+	SRIHeader string
+}
+
+func (o Options) algorithms() []string {
+	if len(o.Algorithms) == 0 {
+		return []string{DefaultAlgorithm}
+	}
+	return o.Algorithms
+}
+
+type cacheEntry struct {
+	modTime time.Time
+	size    int64
+	digests [][]byte
+}
+
+type fileServer struct {
+	fsys       http.FileSystem
+	next       http.Handler
+	algorithms []string
+	sriHeader  string
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// FileServer returns a handler serving fsys as http.FileServer does, with
+// digest headers added to every regular file. Because the ETag is set before
+// the file is served, conditional and range requests are answered against
+// it.
+func FileServer(fsys http.FileSystem, opts Options) (http.Handler, error) {
+	algorithms := opts.algorithms()
+	if _, err := multihash.NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	return &fileServer{
+		fsys:       fsys,
+		next:       http.FileServer(fsys),
+		algorithms: algorithms,
+		sriHeader:  opts.SRIHeader,
+		cache:      map[string]cacheEntry{},
+	}, nil
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	if digests := s.digests(name); digests != nil {
+		s.setHeaders(w.Header(), r, digests)
+	}
+	s.next.ServeHTTP(w, r)
+}
+
+// digests returns the cached digests of name, computing them if the file is
+// new or has changed. It returns nil for directories and for files that
+// cannot be read, leaving the response to the wrapped file server.
+func (s *fileServer) digests(name string) [][]byte {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+
+	s.mu.Lock()
+	entry, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.digests
+	}
+
+	hashes, err := multihash.NewAll(s.algorithms...)
+	if err != nil {
+		return nil
+	}
+	digests, err := multihash.FromReader(f, hashes...)
+	if err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.cache[name] = cacheEntry{modTime: info.ModTime(), size: info.Size(), digests: digests}
+	s.mu.Unlock()
+	return digests
+}
+
+func (s *fileServer) setHeaders(header http.Header, r *http.Request, digests [][]byte) {
+	header.Set("Etag", `"`+hex.EncodeToString(digests[0])+`"`)
+
+	var fields, sri []string
+	for i, algorithm := range s.algorithms {
+		if key, ok := fieldNames[algorithm]; ok {
+			fields = append(fields, key+"=:"+base64.StdEncoding.EncodeToString(digests[i])+":")
+		}
+		if sriAlgorithms[algorithm] {
+			sri = append(sri, multihash.FormatSRI(algorithm, digests[i]))
+		}
+	}
+	if len(fields) > 0 {
+		header.Set("Repr-Digest", strings.Join(fields, ", "))
+		// Content-Digest covers the bytes actually sent, which for a range
+		// request are not the whole file.
+		if r.Header.Get("Range") == "" {
+			header.Set("Content-Digest", strings.Join(fields, ", "))
+		}
+	}
+	if s.sriHeader != "" && len(sri) > 0 {
+		header.Set(s.sriHeader, strings.Join(sri, " "))
+	}
+}
@@ -0,0 +1,77 @@
+package httpdigest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "crypto/sha512"
+)
+
+func Test_FileServer(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.js")
+	if err := os.WriteFile(filename, []byte("console.log(1)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := FileServer(http.Dir(dir), Options{Algorithms: []string{"sha256", "sha384"}, SRIHeader: "X-Integrity"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/app.js", nil)
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	digest := sha256.Sum256([]byte("console.log(1)"))
+	w := get()
+	if etag := w.Header().Get("Etag"); etag != `"`+hex.EncodeToString(digest[:])+`"` {
+		t.Fatalf("unexpected ETag %s\n", etag)
+	}
+	want := "sha-256=:" + base64.StdEncoding.EncodeToString(digest[:]) + ":"
+	if w.Header().Get("Content-Digest") != want || w.Header().Get("Repr-Digest") != want {
+		t.Fatalf("unexpected digest fields %q\n", w.Header())
+	}
+	if len(w.Header().Get("X-Integrity")) == 0 {
+		t.Fatal("no SRI header")
+	}
+
+	if w = get("If-None-Match", w.Header().Get("Etag")); w.Code != http.StatusNotModified {
+		t.Fatalf("conditional request got %d\n", w.Code)
+	}
+	if w = get("Range", "bytes=0-6"); w.Code != http.StatusPartialContent || w.Header().Get("Content-Digest") != "" {
+		t.Fatalf("range request got %d with Content-Digest %q\n", w.Code, w.Header().Get("Content-Digest"))
+	}
+
+	// Changing the file invalidates the cached digests.
+	if err = os.WriteFile(filename, []byte("console.log(2)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(filename, later, later); err != nil {
+		t.Fatal(err)
+	}
+	digest = sha256.Sum256([]byte("console.log(2)"))
+	if etag := get().Header().Get("Etag"); etag != `"`+hex.EncodeToString(digest[:])+`"` {
+		t.Fatalf("stale ETag %s after change\n", etag)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Header().Get("Etag") != "" {
+		t.Fatal("directory listing given an ETag")
+	}
+}
@@ -0,0 +1,182 @@
+package httpdigest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+)
+
+// MaxValueSize bounds each non-file form field SaveUpload reads into memory.
+const MaxValueSize = 1 << 20
+
+// UploadedFile describes one file part saved by SaveUpload.
+type UploadedFile struct {
+	FormName string
+	FileName string
+	Size     int64
+	// Digests are in Options.Algorithms order.
+	Digests [][]byte
+}
+
+// Upload is the result of SaveUpload: the files saved and the other form
+// fields, read as http.Request.MultipartForm would.
+type Upload struct {
+	Files  []UploadedFile
+	Values map[string][]string
+}
+
+// SaveRequest calls SaveUpload on the multipart body of r.
+func SaveRequest(r *http.Request, opts Options, save func(part *multipart.Part) (io.WriteCloser, error)) (*Upload, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return SaveUpload(mr, opts, save)
+}
+
+// SaveUpload reads a multipart form, saving each file part to the writer
+// save returns for it and hashing it with opts.Algorithms as it is written,
+// so that no part is read twice.
+//
+// Expected digests are enforced from two places. A file part may carry its
+// own Content-Digest or Repr-Digest header, as in RFC 9530; and a form field
+// named after the file field and an algorithm, e.g. "upload.sha256", may
+// give one in hex, base64 or SRI form, before or after the file. Expected
+// digests for algorithms not in opts.Algorithms are ignored. A mismatch is
+// reported as an UploadMismatchError, along with the Upload so far, so that
+// the caller can delete what was saved.
+func SaveUpload(r *multipart.Reader, opts Options, save func(part *multipart.Part) (io.WriteCloser, error)) (*Upload, error) {
+	algorithms := opts.algorithms()
+	if _, err := multihash.NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	upload := &Upload{Values: map[string][]string{}}
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return upload, err
+		}
+		if part.FileName() == "" {
+			var value bytes.Buffer
+			if _, err = io.Copy(&value, io.LimitReader(part, MaxValueSize)); err != nil {
+				return upload, err
+			}
+			upload.Values[part.FormName()] = append(upload.Values[part.FormName()], value.String())
+			continue
+		}
+		file, err := saveFile(part, algorithms, save)
+		if err != nil {
+			return upload, err
+		}
+		upload.Files = append(upload.Files, file)
+		if err = checkPartHeader(file, algorithms, part.Header.Get("Content-Digest")); err != nil {
+			return upload, err
+		}
+		if err = checkPartHeader(file, algorithms, part.Header.Get("Repr-Digest")); err != nil {
+			return upload, err
+		}
+	}
+	for _, file := range upload.Files {
+		for i, algorithm := range algorithms {
+			field := file.FormName + "." + algorithm
+			for _, value := range upload.Values[field] {
+				expected, err := decodeExpected(value)
+				if err != nil {
+					return upload, MalformedExpectedDigestError{Field: field, Value: value}
+				}
+				if err = checkDigest(file, algorithm, expected, file.Digests[i]); err != nil {
+					return upload, err
+				}
+			}
+		}
+	}
+	return upload, nil
+}
+
+func saveFile(part *multipart.Part, algorithms []string, save func(part *multipart.Part) (io.WriteCloser, error)) (UploadedFile, error) {
+	file := UploadedFile{FormName: part.FormName(), FileName: part.FileName()}
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return file, err
+	}
+	w, err := save(part)
+	if err != nil {
+		return file, err
+	}
+	writers := []io.Writer{w}
+	for _, h := range hashes {
+		writers = append(writers, h)
+	}
+	file.Size, err = io.Copy(io.MultiWriter(writers...), part)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return file, err
+	}
+	for _, h := range hashes {
+		file.Digests = append(file.Digests, h.Sum(nil))
+	}
+	return file, nil
+}
+
+// checkPartHeader checks file against an RFC 9530 digest field such as
+// "sha-256=:<base64>:, sha-512=:<base64>:".
+func checkPartHeader(file UploadedFile, algorithms []string, field string) error {
+	if field == "" {
+		return nil
+	}
+	for _, member := range strings.Split(field, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(member), "=")
+		if !found || len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return MalformedExpectedDigestError{Field: "part header", Value: member}
+		}
+		expected, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+		if err != nil {
+			return MalformedExpectedDigestError{Field: "part header", Value: member}
+		}
+		for i, algorithm := range algorithms {
+			if fieldNames[algorithm] != strings.ToLower(key) {
+				continue
+			}
+			if err = checkDigest(file, algorithm, expected, file.Digests[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeExpected decodes a digest given in hex, base64 or SRI form.
+func decodeExpected(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if _, digest, err := multihash.ParseSRI(value); err == nil {
+		return digest, nil
+	}
+	if digest, err := hex.DecodeString(value); err == nil {
+		return digest, nil
+	}
+	return base64.StdEncoding.DecodeString(value)
+}
+
+func checkDigest(file UploadedFile, algorithm string, expected, actual []byte) error {
+	if !bytes.Equal(expected, actual) {
+		return UploadMismatchError{
+			FormName:  file.FormName,
+			FileName:  file.FileName,
+			Algorithm: algorithm,
+			Expected:  expected,
+			Actual:    actual,
+		}
+	}
+	return nil
+}
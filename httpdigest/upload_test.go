@@ -0,0 +1,74 @@
+package httpdigest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func Test_SaveUpload(t *testing.T) {
+	content := []byte("uploaded file contents")
+	digest := sha256.Sum256(content)
+
+	build := func(fieldDigest string, headerDigest string) *multipart.Reader {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="upload"; filename="a.txt"`)
+		if headerDigest != "" {
+			header.Set("Content-Digest", "sha-256=:"+headerDigest+":")
+		}
+		w, _ := mw.CreatePart(header)
+		w.Write(content)
+		if fieldDigest != "" {
+			mw.WriteField("upload.sha256", fieldDigest)
+		}
+		mw.Close()
+		return multipart.NewReader(&body, mw.Boundary())
+	}
+
+	var saved bytes.Buffer
+	save := func(part *multipart.Part) (io.WriteCloser, error) {
+		saved.Reset()
+		return nopWriteCloser{&saved}, nil
+	}
+
+	upload, err := SaveUpload(build(hex.EncodeToString(digest[:]), base64.StdEncoding.EncodeToString(digest[:])), Options{}, save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upload.Files) != 1 || !bytes.Equal(upload.Files[0].Digests[0], digest[:]) || upload.Files[0].Size != int64(len(content)) {
+		t.Fatalf("unexpected upload %v\n", upload.Files)
+	}
+	if !bytes.Equal(saved.Bytes(), content) {
+		t.Fatalf("saved %q\n", saved.Bytes())
+	}
+
+	wrong := sha256.Sum256([]byte("something else"))
+	_, err = SaveUpload(build(hex.EncodeToString(wrong[:]), ""), Options{}, save)
+	if !errors.Is(err, multihash.ErrDigestMismatch) {
+		t.Fatalf("expected a mismatch from the form field, got %v\n", err)
+	}
+	_, err = SaveUpload(build("", base64.StdEncoding.EncodeToString(wrong[:])), Options{}, save)
+	if !errors.Is(err, multihash.ErrDigestMismatch) {
+		t.Fatalf("expected a mismatch from the part header, got %v\n", err)
+	}
+	_, err = SaveUpload(build("not a digest!", ""), Options{}, save)
+	if !errors.Is(err, multihash.ErrMalformedDigest) {
+		t.Fatalf("expected a malformed digest error, got %v\n", err)
+	}
+}
@@ -0,0 +1,102 @@
+// package perf provides synthetic inputs and hash-set fixtures for the
+// benchmarks that track the performance of the multihash pipeline.
+package perf
+
+import (
+	"crypto"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Sizes are the input lengths benchmarks are run over. They straddle the
+// package's internal buffer size so that both the single-read and the
+// many-read paths are exercised.
+var Sizes = []int64{
+	1 << 10, // 1 KiB
+	1 << 16, // one buffer
+	1 << 20, // 1 MiB
+	1 << 26, // 64 MiB
+}
+
+// HashSet is a named combination of hash functions.
+type HashSet struct {
+	Name   string
+	hashes []crypto.Hash
+}
+
+// New returns freshly constructed hash.Hash values for the set, in order.
+func (s HashSet) New() []hash.Hash {
+	hashes := make([]hash.Hash, len(s.hashes))
+	for i, h := range s.hashes {
+		hashes[i] = h.New()
+	}
+	return hashes
+}
+
+func newHashSet(hashes ...crypto.Hash) HashSet {
+	names := make([]string, len(hashes))
+	for i, h := range hashes {
+		names[i] = strings.ToLower(strings.ReplaceAll(h.String(), "-", ""))
+	}
+	return HashSet{Name: strings.Join(names, "+"), hashes: hashes}
+}
+
+// HashSets are the hash combinations benchmarks are run over, from a single
+// cheap hash up to a set heavy enough to saturate several cores.
+var HashSets = []HashSet{
+	newHashSet(crypto.MD5),
+	newHashSet(crypto.SHA256),
+	newHashSet(crypto.MD5, crypto.SHA1, crypto.SHA256),
+	newHashSet(crypto.MD5, crypto.SHA1, crypto.SHA256, crypto.SHA512),
+}
+
+// SizeName formats a byte count for use in a benchmark name, e.g. "64MiB".
+func SizeName(size int64) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMiB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKiB", size>>10)
+	}
+	return fmt.Sprintf("%dB", size)
+}
+
+// Reader is an io.Reader producing size bytes of deterministic,
+// non-repeating-looking data without allocating the whole input up front.
+type Reader struct {
+	remaining int64
+	state     uint64
+}
+
+// NewReader returns a Reader that yields exactly size bytes.
+func NewReader(size int64) *Reader {
+	return &Reader{remaining: size, state: 0x9e3779b97f4a7c15}
+}
+
+func (r *Reader) Read(p []byte) (n int, err error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	var word [8]byte
+	for i := 0; i < len(p); i += 8 {
+		// xorshift64; cheap enough not to dominate the hashing being measured.
+		r.state ^= r.state << 13
+		r.state ^= r.state >> 7
+		r.state ^= r.state << 17
+		binary.LittleEndian.PutUint64(word[:], r.state)
+		copy(p[i:], word[:])
+	}
+	r.remaining -= int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,69 @@
+package perf
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+func Test_Reader(t *testing.T) {
+	for _, size := range []int64{0, 1, 65535, 65536, 65537} {
+		n, err := io.Copy(io.Discard, NewReader(size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != size {
+			t.Fatalf("reader for %d bytes produced %d\n", size, n)
+		}
+	}
+}
+
+// BenchmarkFromReader reports throughput for every combination of input
+// size and hash set, named so that benchstat can pivot on either key:
+//
+//	BenchmarkFromReader/size=1MiB/hashes=md5+sha1+sha256
+func BenchmarkFromReader(b *testing.B) {
+	for _, size := range Sizes {
+		for _, set := range HashSets {
+			name := fmt.Sprintf("size=%s/hashes=%s", SizeName(size), set.Name)
+			b.Run(name, func(b *testing.B) {
+				b.SetBytes(size)
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := multihash.FromReader(NewReader(size), set.New()...); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkSequential is the baseline FromReader should beat: the same hash
+// sets fed one after another from an io.MultiWriter on a single goroutine.
+func BenchmarkSequential(b *testing.B) {
+	for _, size := range Sizes {
+		for _, set := range HashSets {
+			name := fmt.Sprintf("size=%s/hashes=%s", SizeName(size), set.Name)
+			b.Run(name, func(b *testing.B) {
+				b.SetBytes(size)
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					hashes := set.New()
+					writers := make([]io.Writer, len(hashes))
+					for j, h := range hashes {
+						writers[j] = h
+					}
+					if _, err := io.Copy(io.MultiWriter(writers...), NewReader(size)); err != nil {
+						b.Fatal(err)
+					}
+					for _, h := range hashes {
+						h.Sum(nil)
+					}
+				}
+			})
+		}
+	}
+}
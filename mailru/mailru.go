@@ -0,0 +1,83 @@
+// package mailru implements the content hash used by Mail.ru Cloud, as
+// reported by rclone under the name "mailru". Importing it registers the
+// algorithm with multihash under that name.
+//
+// Contents of up to 20 bytes are their own hash, zero-padded to 20 bytes.
+// Longer contents hash to SHA-1 over "mrCloud", the contents, and the content
+// length in decimal.
+package mailru
+
+import (
+	"crypto/sha1"
+	"encoding"
+	"hash"
+	"strconv"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Size is the size of the hash in bytes.
+const Size = sha1.Size
+
+// BlockSize is the block size of the hash in bytes.
+const BlockSize = sha1.BlockSize
+
+const maxSmallSize = Size
+
+const prefix = "mrCloud"
+
+func init() {
+	multihash.Register("mailru", New)
+}
+
+type digest struct {
+	sha1  hash.Hash
+	small []byte
+	total int64
+}
+
+// New returns a new hash.Hash computing the Mail.ru Cloud hash.
+func New() hash.Hash {
+	d := &digest{sha1: sha1.New(), small: make([]byte, 0, maxSmallSize)}
+	d.Reset()
+	return d
+}
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Reset() {
+	d.sha1.Reset()
+	d.sha1.Write([]byte(prefix))
+	d.small = d.small[:0]
+	d.total = 0
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	if d.total+int64(len(p)) <= maxSmallSize {
+		d.small = append(d.small, p...)
+	}
+	d.total += int64(len(p))
+	return d.sha1.Write(p)
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	if d.total <= maxSmallSize {
+		padded := make([]byte, Size)
+		copy(padded, d.small)
+		return append(b, padded...)
+	}
+	// Finishing the hash must not disturb the running state, so the length
+	// suffix goes into a copy of it.
+	state, err := d.sha1.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		panic("mailru: " + err.Error())
+	}
+	final := sha1.New()
+	if err = final.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		panic("mailru: " + err.Error())
+	}
+	final.Write([]byte(strconv.FormatInt(d.total, 10)))
+	return final.Sum(b)
+}
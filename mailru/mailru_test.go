@@ -0,0 +1,47 @@
+package mailru
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"strconv"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+func Test_small(t *testing.T) {
+	h := New()
+	h.Write([]byte("Hello"))
+	expected := append([]byte("Hello"), make([]byte, Size-5)...)
+	if sum := h.Sum(nil); !bytes.Equal(sum, expected) {
+		t.Fatalf("hash of short content was %x, expected %x\n", sum, expected)
+	}
+}
+
+func Test_large(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	h := New()
+	h.Write(data[:7])
+	h.Write(data[7:])
+	reference := sha1.New()
+	reference.Write([]byte("mrCloud"))
+	reference.Write(data)
+	reference.Write([]byte(strconv.Itoa(len(data))))
+	expected := reference.Sum(nil)
+	if sum := h.Sum(nil); !bytes.Equal(sum, expected) {
+		t.Fatalf("hash of long content was %x, expected %x\n", sum, expected)
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, expected) {
+		t.Fatal("Sum changed the running state")
+	}
+}
+
+func Test_registered(t *testing.T) {
+	h, err := multihash.New("mailru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Size() != Size {
+		t.Fatalf("registered hash has size %d\n", h.Size())
+	}
+}
@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CaseCollisions returns the groups of entry paths that differ only by case,
+// such as "README" and "readme". A manifest containing any cannot be checked
+// out faithfully onto a case-insensitive filesystem, as on Windows and by
+// default on macOS: one file overwrites the other, and the manifest then
+// fails to verify there while verifying elsewhere. Generators should warn
+// about them before writing the manifest.
+//
+// Paths are compared after cleaning, with the simple case folding used by
+// strings.EqualFold. Each group lists its paths in manifest order, and groups
+// are ordered by their first path. Paths differing only by Unicode
+// normalization are not detected; see CanonicalPath.
+func (m *Manifest) CaseCollisions() [][]string {
+	groups := make(map[string]int)
+	var collisions [][]string
+	for _, entry := range m.Entries {
+		key := foldCase(cleanPath(entry.Path))
+		index, ok := groups[key]
+		if !ok {
+			groups[key] = len(collisions)
+			collisions = append(collisions, []string{entry.Path})
+			continue
+		}
+		collisions[index] = append(collisions[index], entry.Path)
+	}
+	result := collisions[:0]
+	for _, group := range collisions {
+		if len(group) > 1 {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// foldCase maps every rune of s to the smallest rune equivalent to it under
+// simple case folding, so that two strings have the same result exactly when
+// strings.EqualFold reports them equal.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		smallest := r
+		for folded := unicode.SimpleFold(r); folded != r; folded = unicode.SimpleFold(folded) {
+			if folded < smallest {
+				smallest = folded
+			}
+		}
+		return smallest
+	}, s)
+}
@@ -0,0 +1,27 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func Test_CaseCollisions(t *testing.T) {
+	m := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{
+		{Path: "docs/README"},
+		{Path: "src/main.go"},
+		{Path: "docs/readme"},
+		{Path: "Src/Main.go"},
+		{Path: "./docs/ReadMe"},
+		{Path: "straße"},
+		{Path: "docs/guide"},
+	}}
+	collisions := m.CaseCollisions()
+	if len(collisions) != 2 {
+		t.Fatalf("found collisions %q\n", collisions)
+	}
+	if len(collisions[0]) != 3 || collisions[0][0] != "docs/README" || collisions[0][2] != "./docs/ReadMe" {
+		t.Fatalf("first group was %q\n", collisions[0])
+	}
+	if len(collisions[1]) != 2 || collisions[1][0] != "src/main.go" || collisions[1][1] != "Src/Main.go" {
+		t.Fatalf("second group was %q\n", collisions[1])
+	}
+}
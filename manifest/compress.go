@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+
+	"github.com/trytriangles/multihash/atomicfile"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Decompress returns a reader of r's contents, decompressing them if they
+// start with a gzip header. Parsers in this package apply it to their input,
+// so compressed manifests can be read without the caller knowing. zstd
+// input is recognized but needs a decoder the standard library lacks, and
+// gives an UnsupportedCompressionError.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(header, zstdMagic):
+		return nil, UnsupportedCompressionError{Format: "zstd"}
+	}
+	return br, nil
+}
+
+// Create starts writing the named manifest file, compressing what is
+// written with gzip if the name ends in ".gz". The file is written
+// atomically with the given durability: nothing appears under name until
+// Close, which finishes any compressed stream and publishes the complete
+// file, so a crash mid-write never leaves a truncated manifest behind.
+// Names ending in ".zst" give an UnsupportedCompressionError rather than an
+// uncompressed file under a misleading name.
+func Create(name string, durability atomicfile.Durability) (io.WriteCloser, error) {
+	if strings.HasSuffix(name, ".zst") {
+		return nil, UnsupportedCompressionError{Format: "zstd"}
+	}
+	f, err := atomicfile.Create(name, durability)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return f, nil
+	}
+	return gzipFile{Writer: gzip.NewWriter(f), file: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Writer
+	file *atomicfile.File
+}
+
+func (g gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.file.Abort()
+		return err
+	}
+	return g.file.Close()
+}
@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trytriangles/multihash/atomicfile"
+)
+
+func Test_CompressedManifest(t *testing.T) {
+	m := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{
+		{Path: "one.txt", Digests: [][]byte{{0x01, 0x02}}},
+	}}
+	name := filepath.Join(t.TempDir(), "SHA256SUMS.gz")
+	w, err := Create(name, atomicfile.SyncFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.WriteGNU(w, "sha256"); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(compressed, gzipMagic) {
+		t.Fatalf("%s was not compressed: %x\n", name, compressed)
+	}
+	parsed, err := ParseGNU(bytes.NewReader(compressed), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest, ok := parsed.Digest("one.txt", "sha256"); !ok || !bytes.Equal(digest, []byte{0x01, 0x02}) {
+		t.Fatalf("compressed manifest parsed as %+v\n", parsed)
+	}
+
+	if _, err = ParseBSD(bytes.NewReader(append(zstdMagic, 0))); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Fatalf("zstd input gave %v\n", err)
+	}
+	if _, err = Create(name+".zst", atomicfile.SyncFile); !errors.Is(err, ErrUnsupportedCompression) {
+		t.Fatalf("creating a .zst manifest gave %v\n", err)
+	}
+}
@@ -0,0 +1,124 @@
+package manifest
+
+import (
+	"encoding/hex"
+	"strconv"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+var ErrMalformedLine = errcode.New(errcode.Malformed, "malformed checksum line")
+
+type MalformedLineError struct {
+	Line int
+	Text string
+}
+
+func (e MalformedLineError) Error() string {
+	return "malformed checksum line " + strconv.Itoa(e.Line) + ": " + strconv.Quote(e.Text)
+}
+
+func (e MalformedLineError) Is(target error) bool {
+	return target == ErrMalformedLine
+}
+
+func (e MalformedLineError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+var ErrMissingDigest = errcode.New(errcode.NotFound, "manifest lacks a digest")
+
+type MissingDigestError struct {
+	Path      string
+	Algorithm string
+}
+
+func (e MissingDigestError) Error() string {
+	if e.Path == "" {
+		return "manifest has no " + e.Algorithm + " digests"
+	}
+	return "manifest has no " + e.Algorithm + " digest for " + strconv.Quote(e.Path)
+}
+
+func (e MissingDigestError) Is(target error) bool {
+	return target == ErrMissingDigest
+}
+
+func (e MissingDigestError) Code() errcode.Code {
+	return errcode.NotFound
+}
+
+var ErrInvalidPath = errcode.New(errcode.InvalidArgument, "path not allowed in a canonical tree")
+
+type InvalidPathError struct {
+	Path string
+}
+
+func (e InvalidPathError) Error() string {
+	return "path " + strconv.Quote(e.Path) + " not allowed in a canonical tree"
+}
+
+func (e InvalidPathError) Is(target error) bool {
+	return target == ErrInvalidPath
+}
+
+func (e InvalidPathError) Code() errcode.Code {
+	return errcode.InvalidArgument
+}
+
+var ErrIncompleteManifest = errcode.New(errcode.Incomplete, "manifest is incomplete")
+
+type IncompleteManifestError struct {
+	Entries int
+	Footer  int
+}
+
+func (e IncompleteManifestError) Error() string {
+	if e.Footer < 0 {
+		return "manifest is incomplete: no footer after " + strconv.Itoa(e.Entries) + " entries"
+	}
+	return "manifest is incomplete: footer records " + strconv.Itoa(e.Footer) + " entries, found " + strconv.Itoa(e.Entries)
+}
+
+func (e IncompleteManifestError) Is(target error) bool {
+	return target == ErrIncompleteManifest
+}
+
+func (e IncompleteManifestError) Code() errcode.Code {
+	return errcode.Incomplete
+}
+
+var ErrUnsupportedCompression = errcode.New(errcode.UnsupportedFormat, "unsupported compression format")
+
+type UnsupportedCompressionError struct {
+	Format string
+}
+
+func (e UnsupportedCompressionError) Error() string {
+	return "unsupported compression format " + e.Format
+}
+
+func (e UnsupportedCompressionError) Is(target error) bool {
+	return target == ErrUnsupportedCompression
+}
+
+func (e UnsupportedCompressionError) Code() errcode.Code {
+	return errcode.UnsupportedFormat
+}
+
+var ErrConflict = errcode.New(errcode.Conflict, "manifests disagree")
+
+type ConflictError Conflict
+
+func (e ConflictError) Error() string {
+	return "manifests disagree on the " + e.Algorithm + " digest of " + strconv.Quote(e.Path) +
+		": " + hex.EncodeToString(e.Existing) + " and " + hex.EncodeToString(e.Incoming) + " in input " + strconv.Itoa(e.Input)
+}
+
+func (e ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e ConflictError) Code() errcode.Code {
+	return errcode.Conflict
+}
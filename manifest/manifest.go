@@ -0,0 +1,362 @@
+// package manifest reads and writes checksum manifests: lists of file paths
+// and their digests, in the line formats produced by GNU coreutils
+// (sha256sum and friends) and by BSD-style tools (sha256sum --tag, md5 -r).
+package manifest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"path"
+	"strings"
+)
+
+// Entry is a single file in a manifest. Digests are in the same order as the
+// Algorithms of the Manifest holding the entry; a nil digest means the
+// manifest has no value for that algorithm.
+type Entry struct {
+	Path    string
+	Digests [][]byte
+}
+
+// Manifest is an ordered list of entries, each carrying a digest for every
+// one of Algorithms. Algorithm names are lower case, e.g. "sha256".
+type Manifest struct {
+	Algorithms []string
+	Entries    []Entry
+}
+
+// AlgorithmIndex returns the position of algorithm within m.Algorithms, or
+// -1 if the manifest does not carry it. The comparison ignores case.
+func (m *Manifest) AlgorithmIndex(algorithm string) int {
+	for index, name := range m.Algorithms {
+		if strings.EqualFold(name, algorithm) {
+			return index
+		}
+	}
+	return -1
+}
+
+// Lookup returns the entry for filePath. Paths are compared after cleaning,
+// so "./foo.iso" and "foo.iso" refer to the same entry.
+func (m *Manifest) Lookup(filePath string) (entry Entry, ok bool) {
+	filePath = cleanPath(filePath)
+	for _, entry := range m.Entries {
+		if cleanPath(entry.Path) == filePath {
+			return entry, true
+		}
+	}
+	return entry, false
+}
+
+// Digest returns the digest recorded for filePath under algorithm.
+func (m *Manifest) Digest(filePath, algorithm string) (digest []byte, ok bool) {
+	index := m.AlgorithmIndex(algorithm)
+	if index < 0 {
+		return nil, false
+	}
+	entry, ok := m.Lookup(filePath)
+	if !ok || entry.Digests[index] == nil {
+		return nil, false
+	}
+	return entry.Digests[index], true
+}
+
+// ParseGNU parses GNU coreutils-style lines,
+//
+//	<hex digest>  <path>
+//	<hex digest> *<path>
+//	<hex digest> <path>
+//
+// the last as written by md5 -r, all of which are taken to be digests under
+// algorithm. A path after a single space that starts with '*' or a space is
+// read as GNU's. Empty lines and lines starting with '#' are skipped.
+func ParseGNU(r io.Reader, algorithm string) (*Manifest, error) {
+	m := &Manifest{Algorithms: []string{strings.ToLower(algorithm)}}
+	err := scanLines(r, func(line string) bool {
+		digest, filePath, ok := parseGNULine(line)
+		if !ok {
+			return false
+		}
+		m.Entries = append(m.Entries, Entry{Path: filePath, Digests: [][]byte{digest}})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseBSD parses BSD-style tagged lines,
+//
+//	SHA256 (<path>) = <hex digest>
+//
+// which may mix algorithms. Each distinct path becomes one entry, in order of
+// first appearance, holding a digest for every algorithm listed for it.
+func ParseBSD(r io.Reader) (*Manifest, error) {
+	b := newBuilder()
+	err := scanLines(r, func(line string) bool {
+		algorithm, filePath, digest, ok := parseBSDLine(line)
+		if !ok {
+			return false
+		}
+		b.add(filePath, algorithm, digest)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.manifest(), nil
+}
+
+// Parse accepts either format, line by line. GNU-style lines are attributed
+// to defaultAlgorithm; BSD-style lines carry their own.
+func Parse(r io.Reader, defaultAlgorithm string) (*Manifest, error) {
+	b := newBuilder()
+	err := scanLines(r, func(line string) bool {
+		if algorithm, filePath, digest, ok := parseBSDLine(line); ok {
+			b.add(filePath, algorithm, digest)
+			return true
+		}
+		if digest, filePath, ok := parseGNULine(line); ok && defaultAlgorithm != "" {
+			b.add(filePath, defaultAlgorithm, digest)
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.manifest(), nil
+}
+
+// WriteGNU writes the digests for algorithm in GNU coreutils format, readable
+// by e.g. `sha256sum -c`. Entries without a digest for algorithm are skipped.
+func (m *Manifest) WriteGNU(w io.Writer, algorithm string) error {
+	index := m.AlgorithmIndex(algorithm)
+	if index < 0 {
+		return nil
+	}
+	bw := bufio.NewWriter(w)
+	for _, entry := range m.Entries {
+		if entry.Digests[index] == nil {
+			continue
+		}
+		escaped, filePath := escapePath(entry.Path)
+		if escaped {
+			bw.WriteByte('\\')
+		}
+		bw.WriteString(hex.EncodeToString(entry.Digests[index]))
+		bw.WriteString("  ")
+		bw.WriteString(filePath)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// WriteBSD writes every digest in the manifest in BSD tagged format, one line
+// per entry and algorithm.
+func (m *Manifest) WriteBSD(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, entry := range m.Entries {
+		writeBSDEntry(bw, m.Algorithms, entry)
+	}
+	return bw.Flush()
+}
+
+// writeBSDEntry writes the BSD tagged lines for every digest of entry. Since
+// bufio.Writer errors are sticky, the error of the last write covers them
+// all.
+func writeBSDEntry(bw *bufio.Writer, algorithms []string, entry Entry) error {
+	var err error
+	escaped, filePath := escapePath(entry.Path)
+	for index, digest := range entry.Digests {
+		if digest == nil {
+			continue
+		}
+		if escaped {
+			bw.WriteByte('\\')
+		}
+		bw.WriteString(strings.ToUpper(algorithms[index]))
+		bw.WriteString(" (")
+		bw.WriteString(filePath)
+		bw.WriteString(") = ")
+		bw.WriteString(hex.EncodeToString(digest))
+		err = bw.WriteByte('\n')
+	}
+	return err
+}
+
+// scanLines calls parse for every line of r that is neither blank nor a
+// comment, returning a MalformedLineError for the first line parse rejects.
+// Compressed input is decompressed first; see Decompress.
+func scanLines(r io.Reader, parse func(line string) bool) error {
+	r, err := Decompress(r)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !parse(line) {
+			return MalformedLineError{Line: lineNumber, Text: line}
+		}
+	}
+	return scanner.Err()
+}
+
+func parseGNULine(line string) (digest []byte, filePath string, ok bool) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+	hexDigest, rest, found := strings.Cut(line, " ")
+	if !found || rest == "" {
+		return nil, "", false
+	}
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil || len(digest) == 0 {
+		return nil, "", false
+	}
+	// A single space with no mode character is md5 -r's separator.
+	filePath = rest
+	if rest[0] == ' ' || rest[0] == '*' {
+		if filePath = rest[1:]; filePath == "" {
+			return nil, "", false
+		}
+	}
+	if escaped {
+		if filePath, ok = unescapePath(filePath); !ok {
+			return nil, "", false
+		}
+	}
+	return digest, filePath, true
+}
+
+func parseBSDLine(line string) (algorithm, filePath string, digest []byte, ok bool) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+	algorithm, rest, found := strings.Cut(line, " (")
+	if !found || algorithm == "" || strings.ContainsAny(algorithm, " \t") {
+		return "", "", nil, false
+	}
+	separator := strings.LastIndex(rest, ") = ")
+	if separator < 0 {
+		return "", "", nil, false
+	}
+	digest, err := hex.DecodeString(rest[separator+len(") = "):])
+	if err != nil || len(digest) == 0 {
+		return "", "", nil, false
+	}
+	filePath = rest[:separator]
+	if escaped {
+		if filePath, ok = unescapePath(filePath); !ok {
+			return "", "", nil, false
+		}
+	}
+	return strings.ToLower(algorithm), filePath, digest, true
+}
+
+// escapePath applies coreutils' escaping for names containing a backslash or
+// newline; escaped lines are marked with a leading backslash.
+func escapePath(filePath string) (escaped bool, result string) {
+	if !strings.ContainsAny(filePath, "\\\n\r") {
+		return false, filePath
+	}
+	replacer := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+	return true, replacer.Replace(filePath)
+}
+
+func unescapePath(filePath string) (result string, ok bool) {
+	var b strings.Builder
+	for i := 0; i < len(filePath); i++ {
+		if filePath[i] != '\\' {
+			b.WriteByte(filePath[i])
+			continue
+		}
+		i++
+		if i == len(filePath) {
+			return "", false
+		}
+		switch filePath[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// MemberPath gives the name of an archive member or image file as a clean
+// path relative to the archive's root, resolving it as if rooted there so
+// that ".." components cannot climb out, or "" for the root itself.
+// Backslashes are separators, as in manifest paths.
+func MemberPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
+
+func cleanPath(filePath string) string {
+	return path.Clean(strings.ReplaceAll(filePath, "\\", "/"))
+}
+
+// builder accumulates digests by path and algorithm, preserving the order in
+// which paths and algorithms were first seen.
+type builder struct {
+	algorithms []string
+	paths      []string
+	digests    map[string]map[string][]byte
+}
+
+func newBuilder() *builder {
+	return &builder{digests: make(map[string]map[string][]byte)}
+}
+
+func (b *builder) add(filePath, algorithm string, digest []byte) {
+	algorithm = strings.ToLower(algorithm)
+	known := false
+	for _, name := range b.algorithms {
+		if name == algorithm {
+			known = true
+			break
+		}
+	}
+	if !known {
+		b.algorithms = append(b.algorithms, algorithm)
+	}
+	byAlgorithm, ok := b.digests[filePath]
+	if !ok {
+		byAlgorithm = make(map[string][]byte)
+		b.digests[filePath] = byAlgorithm
+		b.paths = append(b.paths, filePath)
+	}
+	byAlgorithm[algorithm] = digest
+}
+
+func (b *builder) digest(filePath, algorithm string) []byte {
+	return b.digests[filePath][strings.ToLower(algorithm)]
+}
+
+func (b *builder) manifest() *Manifest {
+	m := &Manifest{Algorithms: b.algorithms, Entries: make([]Entry, len(b.paths))}
+	for i, filePath := range b.paths {
+		digests := make([][]byte, len(b.algorithms))
+		for j, algorithm := range b.algorithms {
+			digests[j] = b.digests[filePath][algorithm]
+		}
+		m.Entries[i] = Entry{Path: filePath, Digests: digests}
+	}
+	return m
+}
@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const gnuFixture = `5530de3071a1a9035478defcc1d586e1  text1.txt
+# comment
+d41d8cd98f00b204e9800998ecf8427e *empty.bin
+\d41d8cd98f00b204e9800998ecf8427e  odd\\name\nwith newline
+`
+
+func Test_ParseGNU(t *testing.T) {
+	m, err := ParseGNU(strings.NewReader(gnuFixture), "MD5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 3 || m.Algorithms[0] != "md5" {
+		t.Fatalf("unexpected manifest %+v\n", m)
+	}
+	digest, ok := m.Digest("./text1.txt", "md5")
+	if !ok || hex.EncodeToString(digest) != "5530de3071a1a9035478defcc1d586e1" {
+		t.Fatalf("digest for text1.txt was %x\n", digest)
+	}
+	if m.Entries[1].Path != "empty.bin" {
+		t.Fatalf("binary-mode path parsed as %q\n", m.Entries[1].Path)
+	}
+	if m.Entries[2].Path != "odd\\name\nwith newline" {
+		t.Fatalf("escaped path parsed as %q\n", m.Entries[2].Path)
+	}
+
+	var out bytes.Buffer
+	if err = m.WriteGNU(&out, "md5"); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip, err := ParseGNU(&out, "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range roundTrip.Entries {
+		if entry.Path != m.Entries[i].Path || !bytes.Equal(entry.Digests[0], m.Entries[i].Digests[0]) {
+			t.Fatalf("entry %d did not survive a round trip: %+v\n", i, entry)
+		}
+	}
+}
+
+func Test_ParseReversed(t *testing.T) {
+	fixture := "5530de3071a1a9035478defcc1d586e1 text1.txt\n" +
+		"d41d8cd98f00b204e9800998ecf8427e dir/with space.txt\n"
+	m, err := Parse(strings.NewReader(fixture), "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 2 || m.Entries[1].Path != "dir/with space.txt" {
+		t.Fatalf("unexpected manifest %+v\n", m)
+	}
+	digest, ok := m.Digest("text1.txt", "md5")
+	if !ok || hex.EncodeToString(digest) != "5530de3071a1a9035478defcc1d586e1" {
+		t.Fatalf("digest for text1.txt was %x\n", digest)
+	}
+}
+
+func Test_ParseBSD(t *testing.T) {
+	fixture := "MD5 (text1.txt) = 5530de3071a1a9035478defcc1d586e1\n" +
+		"SHA256 (text1.txt) = 8bb5bc05618f1036a063bbf83cf74cca163a60343791c0c930acc31bf0c090ea\n" +
+		"SHA256 (a (b).txt) = e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n"
+	m, err := ParseBSD(strings.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 2 || len(m.Algorithms) != 2 {
+		t.Fatalf("unexpected manifest %+v\n", m)
+	}
+	if _, ok := m.Digest("a (b).txt", "SHA256"); !ok {
+		t.Fatal("path containing parentheses was not parsed")
+	}
+	if _, ok := m.Digest("a (b).txt", "md5"); ok {
+		t.Fatal("missing digest reported as present")
+	}
+
+	var out bytes.Buffer
+	if err = m.WriteBSD(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != fixture {
+		t.Fatalf("BSD output was\n%s\nexpected\n%s", out.String(), fixture)
+	}
+}
+
+func Test_ParseMalformed(t *testing.T) {
+	_, err := Parse(strings.NewReader("not a checksum\n"), "sha256")
+	var lineError MalformedLineError
+	if !errors.Is(err, ErrMalformedLine) || !errors.As(err, &lineError) || lineError.Line != 1 {
+		t.Fatalf("expected malformed line 1, got %v\n", err)
+	}
+}
+
+func Test_Rclone(t *testing.T) {
+	fixture := "5530de3071a1a9035478defcc1d586e1  dir/text1.txt\n"
+	m, err := ParseRclone(strings.NewReader(fixture), "MD5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = m.WriteRclone(&out, "md5", true); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "VTDeMHGhqQNUeN78wdWG4Q==  dir/text1.txt\n" {
+		t.Fatalf("base64 output was %q\n", out.String())
+	}
+	roundTrip, err := ParseRclone(&out, "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(roundTrip.Entries[0].Digests[0], m.Entries[0].Digests[0]) {
+		t.Fatal("base64 digest did not survive a round trip")
+	}
+	if algorithm, ok := RcloneAlgorithm("QuickXorHash"); !ok || algorithm != "quickxor" {
+		t.Fatalf("QuickXorHash mapped to %q\n", algorithm)
+	}
+}
@@ -0,0 +1,109 @@
+package manifest
+
+import (
+	"bytes"
+	"io"
+)
+
+// Strategy decides what Merge does when two inputs disagree about a digest:
+// the same path, under the same algorithm, with different values.
+type Strategy int
+
+const (
+	// RequireEqual fails the merge with a ConflictError at the first
+	// disagreement.
+	RequireEqual Strategy = iota
+	// LastWins keeps the value from the later input, so that passing scans
+	// oldest first leaves the newest result for every file.
+	LastWins
+	// KeepBoth keeps the earlier value in the entry for the path and adds a
+	// further entry with the same path holding the later input's digests,
+	// so that the disagreement survives into the output for review.
+	KeepBoth
+)
+
+// Conflict records a disagreement found by Merge. Input is the index of the
+// input that disagreed with the value merged from the inputs before it.
+type Conflict struct {
+	Path      string
+	Algorithm string
+	Input     int
+	Existing  []byte
+	Incoming  []byte
+}
+
+// Merge parses each input with ParseBSD, which covers manifests from
+// WriteBSD and Writer, and combines them with MergeManifests.
+func Merge(strategy Strategy, inputs ...io.Reader) (*Manifest, []Conflict, error) {
+	manifests := make([]*Manifest, len(inputs))
+	for index, input := range inputs {
+		m, err := ParseBSD(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		manifests[index] = m
+	}
+	return MergeManifests(strategy, manifests...)
+}
+
+// MergeManifests combines manifests from several scans or machines into one,
+// for aggregating distributed results. Entries are matched by cleaned path,
+// and the result carries every algorithm found in any input, so merging a
+// manifest of MD5 digests with one of SHA-256 digests of the same files
+// gives entries holding both. Paths and algorithms keep the order in which
+// they were first seen.
+//
+// Disagreements are settled by strategy and returned in input order, so
+// that callers can report them even when the strategy resolved them.
+func MergeManifests(strategy Strategy, manifests ...*Manifest) (*Manifest, []Conflict, error) {
+	var (
+		b          = newBuilder()
+		conflicts  []Conflict
+		alternates []*builder
+	)
+	for input, m := range manifests {
+		for _, entry := range m.Entries {
+			filePath := cleanPath(entry.Path)
+			conflicting := false
+			for index, digest := range entry.Digests {
+				if digest == nil {
+					continue
+				}
+				algorithm := m.Algorithms[index]
+				existing := b.digest(filePath, algorithm)
+				if existing == nil || bytes.Equal(existing, digest) {
+					b.add(filePath, algorithm, digest)
+					continue
+				}
+				conflict := Conflict{Path: filePath, Algorithm: algorithm, Input: input, Existing: existing, Incoming: digest}
+				if strategy == RequireEqual {
+					return nil, nil, ConflictError(conflict)
+				}
+				conflicts = append(conflicts, conflict)
+				conflicting = true
+				if strategy == LastWins {
+					b.add(filePath, algorithm, digest)
+				}
+			}
+			if conflicting && strategy == KeepBoth {
+				alternate := newBuilder()
+				for index, digest := range entry.Digests {
+					if digest != nil {
+						alternate.add(filePath, m.Algorithms[index], digest)
+					}
+				}
+				alternates = append(alternates, alternate)
+			}
+		}
+	}
+	merged := b.manifest()
+	for _, alternate := range alternates {
+		filePath := alternate.paths[0]
+		digests := make([][]byte, len(merged.Algorithms))
+		for index, algorithm := range merged.Algorithms {
+			digests[index] = alternate.digest(filePath, algorithm)
+		}
+		merged.Entries = append(merged.Entries, Entry{Path: filePath, Digests: digests})
+	}
+	return merged, conflicts, nil
+}
@@ -0,0 +1,47 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_Merge(t *testing.T) {
+	const (
+		first  = "MD5 (one.txt) = 01\nSHA256 (two.txt) = 02\n"
+		second = "SHA256 (./one.txt) = 03\nSHA256 (two.txt) = 04\n"
+	)
+	if _, _, err := Merge(RequireEqual, strings.NewReader(first), strings.NewReader(second)); !errors.Is(err, ErrConflict) {
+		t.Fatalf("RequireEqual gave %v\n", err)
+	}
+
+	m, conflicts, err := Merge(LastWins, strings.NewReader(first), strings.NewReader(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Path != "two.txt" || conflicts[0].Input != 1 {
+		t.Fatalf("LastWins reported conflicts %+v\n", conflicts)
+	}
+	if md5, _ := m.Digest("one.txt", "md5"); !bytes.Equal(md5, []byte{0x01}) {
+		t.Fatalf("merged md5 of one.txt was %x\n", md5)
+	}
+	if sha256, _ := m.Digest("one.txt", "sha256"); !bytes.Equal(sha256, []byte{0x03}) {
+		t.Fatalf("merged sha256 of one.txt was %x\n", sha256)
+	}
+	if sha256, _ := m.Digest("two.txt", "sha256"); len(m.Entries) != 2 || !bytes.Equal(sha256, []byte{0x04}) {
+		t.Fatalf("LastWins kept %x for two.txt in %+v\n", sha256, m)
+	}
+
+	m, _, err = Merge(KeepBoth, strings.NewReader(first), strings.NewReader(second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := m.AlgorithmIndex("sha256")
+	if len(m.Entries) != 3 || m.Entries[2].Path != "two.txt" || !bytes.Equal(m.Entries[2].Digests[index], []byte{0x04}) {
+		t.Fatalf("KeepBoth gave %+v\n", m)
+	}
+	if sha256, _ := m.Digest("two.txt", "sha256"); !bytes.Equal(sha256, []byte{0x02}) {
+		t.Fatalf("KeepBoth looked up %x for two.txt\n", sha256)
+	}
+}
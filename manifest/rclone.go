@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// rcloneNames maps the hash names rclone accepts, including the long forms
+// printed by older releases, to the algorithm names used by multihash.
+var rcloneNames = map[string]string{
+	"md5":          "md5",
+	"sha1":         "sha1",
+	"sha-1":        "sha1",
+	"sha256":       "sha256",
+	"sha-256":      "sha256",
+	"sha512":       "sha512",
+	"crc32":        "crc32",
+	"whirlpool":    "whirlpool",
+	"blake3":       "blake3",
+	"xxh3":         "xxh3",
+	"xxh128":       "xxh128",
+	"dropbox":      "dropbox",
+	"dropboxhash":  "dropbox",
+	"hidrive":      "hidrive",
+	"hidrivehash":  "hidrive",
+	"mailru":       "mailru",
+	"mailruhash":   "mailru",
+	"quickxor":     "quickxor",
+	"quickxorhash": "quickxor",
+}
+
+// RcloneAlgorithm maps a hash name as accepted by `rclone hashsum`, in any
+// case, to the corresponding multihash algorithm name. Whether that algorithm
+// can actually be computed depends on what is registered with multihash.
+func RcloneAlgorithm(name string) (algorithm string, ok bool) {
+	algorithm, ok = rcloneNames[strings.ToLower(name)]
+	return
+}
+
+// ParseRclone parses the output of `rclone hashsum <name>`, taking every
+// digest to be under the algorithm rclone calls name. Digests may be hex, as
+// rclone prints by default, or URL-safe base64, as printed with --base64.
+func ParseRclone(r io.Reader, name string) (*Manifest, error) {
+	algorithm, ok := RcloneAlgorithm(name)
+	if !ok {
+		algorithm = strings.ToLower(name)
+	}
+	m := &Manifest{Algorithms: []string{algorithm}}
+	err := scanLines(r, func(line string) bool {
+		encoded, filePath, found := strings.Cut(line, "  ")
+		if !found || filePath == "" {
+			return false
+		}
+		digest, err := hex.DecodeString(encoded)
+		if err != nil {
+			if digest, err = base64.URLEncoding.DecodeString(encoded); err != nil {
+				return false
+			}
+		}
+		m.Entries = append(m.Entries, Entry{Path: filePath, Digests: [][]byte{digest}})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// WriteRclone writes the digests for algorithm in the format printed by
+// `rclone hashsum`, hex encoded, or URL-safe base64 encoded if useBase64 is
+// set. Entries without a digest for algorithm are skipped.
+func (m *Manifest) WriteRclone(w io.Writer, algorithm string, useBase64 bool) error {
+	index := m.AlgorithmIndex(algorithm)
+	if index < 0 {
+		return nil
+	}
+	bw := bufio.NewWriter(w)
+	for _, entry := range m.Entries {
+		if entry.Digests[index] == nil {
+			continue
+		}
+		if useBase64 {
+			bw.WriteString(base64.URLEncoding.EncodeToString(entry.Digests[index]))
+		} else {
+			bw.WriteString(hex.EncodeToString(entry.Digests[index]))
+		}
+		bw.WriteString("  ")
+		bw.WriteString(entry.Path)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
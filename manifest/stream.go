@@ -0,0 +1,129 @@
+package manifest
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// footerPrefix starts the comment line a Writer ends its output with.
+// Parsers skip it like any other comment, so a streamed manifest remains
+// readable by ParseBSD and by BSD-style tools.
+const footerPrefix = "# complete: "
+
+// Writer writes manifest entries in BSD tagged format as they become
+// available, so that a scan of millions of files never holds more than one
+// entry in memory. Close ends the output with a footer recording the number
+// of entries, which CheckComplete uses to tell a finished manifest from one
+// cut short by a crash or an interrupted scan; everything written before the
+// interruption remains parseable.
+type Writer struct {
+	bw         *bufio.Writer
+	algorithms []string
+	entries    entryCounter
+}
+
+// NewWriter returns a Writer whose entries carry digests for algorithms, in
+// that order, as with Manifest.
+func NewWriter(w io.Writer, algorithms []string) *Writer {
+	return &Writer{bw: bufio.NewWriter(w), algorithms: algorithms}
+}
+
+// Write buffers the lines for entry, whose digests are in the order of the
+// Writer's algorithms. They reach the underlying writer when the buffer
+// fills, on Flush, or on Close.
+func (w *Writer) Write(entry Entry) error {
+	if err := writeBSDEntry(w.bw, w.algorithms, entry); err != nil {
+		return err
+	}
+	for index, digest := range entry.Digests {
+		if digest != nil {
+			w.entries.line(entry.Path, w.algorithms[index])
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered entries to the underlying writer. Callers that
+// want a bounded amount of work lost to a crash call it periodically, e.g.
+// every few thousand entries.
+func (w *Writer) Flush() error {
+	return w.bw.Flush()
+}
+
+// Close writes the footer and flushes. It does not close the underlying
+// writer.
+func (w *Writer) Close() error {
+	w.bw.WriteString(footerPrefix + strconv.Itoa(w.entries.entries) + " entries\n")
+	return w.bw.Flush()
+}
+
+// CheckComplete reads a manifest written by Writer and returns the number of
+// entries its footer records. Entries are counted as Writer counts them, so
+// a path listed twice, as Merge with KeepBoth produces, counts twice. If the
+// footer is missing, as when the writer was interrupted, or disagrees with
+// the entries present, the error is an IncompleteManifestError, with a
+// Footer of -1 for a missing footer; it matches ErrIncompleteManifest.
+func CheckComplete(r io.Reader) (entries int, err error) {
+	var (
+		footer  = -1
+		counter entryCounter
+	)
+	if r, err = Decompress(r); err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if count, ok := parseFooter(line); ok {
+			footer = count
+			continue
+		}
+		if algorithm, filePath, _, ok := parseBSDLine(line); ok {
+			counter.line(filePath, algorithm)
+			// Entries after the footer mean the file was appended to.
+			footer = -1
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, err
+	}
+	if footer < 0 || footer != counter.entries {
+		return counter.entries, IncompleteManifestError{Entries: counter.entries, Footer: footer}
+	}
+	return footer, nil
+}
+
+// entryCounter counts the entries in a sequence of BSD lines. A Writer
+// writes an entry's lines together, one per digest, so a line starts a new
+// entry if its path differs from the line before or its algorithm already
+// appeared in the current entry. Entries without digests write no lines
+// and are not counted.
+type entryCounter struct {
+	entries    int
+	path       string
+	algorithms map[string]bool
+}
+
+func (c *entryCounter) line(filePath, algorithm string) {
+	algorithm = strings.ToLower(algorithm)
+	if c.entries == 0 || filePath != c.path || c.algorithms[algorithm] {
+		c.entries++
+		c.path = filePath
+		c.algorithms = make(map[string]bool)
+	}
+	c.algorithms[algorithm] = true
+}
+
+func parseFooter(line string) (entries int, ok bool) {
+	if !strings.HasPrefix(line, footerPrefix) || !strings.HasSuffix(line, " entries") {
+		return 0, false
+	}
+	count, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, footerPrefix), " entries"))
+	if err != nil || count < 0 {
+		return 0, false
+	}
+	return count, true
+}
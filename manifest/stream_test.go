@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func Test_Writer(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, []string{"md5", "sha256"})
+	entries := []Entry{
+		{Path: "one.txt", Digests: [][]byte{{0x01}, {0x02}}},
+		{Path: "two.txt", Digests: [][]byte{nil, {0x03}}},
+	}
+	for _, entry := range entries {
+		if err := w.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	partial := out.String()
+	if _, err := CheckComplete(bytes.NewBufferString(partial)); !errors.Is(err, ErrIncompleteManifest) {
+		t.Fatalf("manifest without footer gave %v\n", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := CheckComplete(bytes.NewReader(out.Bytes()))
+	if err != nil || count != 2 {
+		t.Fatalf("complete manifest gave %d, %v\n", count, err)
+	}
+	m, err := ParseBSD(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest, ok := m.Digest("two.txt", "sha256"); len(m.Entries) != 2 || !ok || digest[0] != 0x03 {
+		t.Fatalf("streamed manifest parsed as %+v\n", m)
+	}
+
+	appended := out.String() + "SHA256 (three.txt) = 04\n"
+	if _, err = CheckComplete(bytes.NewBufferString(appended)); !errors.Is(err, ErrIncompleteManifest) {
+		t.Fatalf("manifest with entries after its footer gave %v\n", err)
+	}
+}
+
+func Test_CheckCompleteDuplicatePaths(t *testing.T) {
+	earlier := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{{Path: "one.txt", Digests: [][]byte{{0x01}}}}}
+	later := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{{Path: "one.txt", Digests: [][]byte{{0x02}}}}}
+	merged, _, err := MergeManifests(KeepBoth, earlier, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	w := NewWriter(&out, merged.Algorithms)
+	for _, entry := range merged.Entries {
+		if err = w.Write(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := CheckComplete(&out); err != nil || count != 2 || len(merged.Entries) != 2 {
+		t.Fatalf("merged manifest of %d entries gave %d, %v\n", len(merged.Entries), count, err)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func Test_WriterFailure(t *testing.T) {
+	w := NewWriter(failingWriter{}, []string{"sha256"})
+	long := Entry{Path: string(bytes.Repeat([]byte("a"), 8192)), Digests: [][]byte{{0x01}}}
+	if err := w.Write(long); err == nil {
+		t.Fatal("write to a failing writer succeeded")
+	}
+	if w.entries.entries != 0 {
+		t.Fatalf("failed write counted %d entries\n", w.entries.entries)
+	}
+}
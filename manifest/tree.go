@@ -0,0 +1,174 @@
+package manifest
+
+import (
+	"encoding/hex"
+	"hash"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TreeDigests computes a Merkle-style digest for every directory implied by
+// the manifest's paths, so that whole subtrees can be compared with a single
+// value, as git does with tree objects. The result maps each directory path
+// to its digest, with "." for the root.
+//
+// A directory's digest is newHash applied to the concatenation, for each
+// child in byte order of name, of
+//
+//	<kind> <hex digest> <name> NUL
+//
+// where kind is "file" or "dir", a file's digest is the manifest's value
+// under algorithm, and a subdirectory's is its own tree digest. NUL cannot
+// appear in file names, so the encoding is unambiguous. newHash should
+// compute algorithm, so that every level of the tree uses the same function.
+// Paths are made canonical first, as by CanonicalPath; one that has no
+// canonical form is an InvalidPathError.
+func (m *Manifest) TreeDigests(algorithm string, newHash func() hash.Hash) (map[string][]byte, error) {
+	index := m.AlgorithmIndex(algorithm)
+	if index < 0 {
+		return nil, MissingDigestError{Algorithm: algorithm}
+	}
+	type child struct {
+		kind, name string
+		digest     []byte
+	}
+	files := map[string][]child{".": nil}
+	subdirs := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, entry := range m.Entries {
+		if entry.Digests[index] == nil {
+			return nil, MissingDigestError{Path: entry.Path, Algorithm: algorithm}
+		}
+		canonical, err := CanonicalPath(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		dir, name := path.Split(canonical)
+		dir = path.Clean(dir)
+		files[dir] = append(files[dir], child{"file", name, entry.Digests[index]})
+		// Register every ancestor, so that directories holding only
+		// subdirectories still get a digest.
+		for dir != "." && dir != "/" {
+			parent := path.Dir(dir)
+			if !seen[dir] {
+				seen[dir] = true
+				subdirs[parent] = append(subdirs[parent], dir)
+			}
+			dir = parent
+		}
+	}
+
+	// Deepest directories first, so each subdirectory's digest is known
+	// before its parent is hashed.
+	dirs := []string{"."}
+	for dir := range seen {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return depth(dirs[i]) > depth(dirs[j]) })
+	digests := make(map[string][]byte, len(dirs))
+	for _, dir := range dirs {
+		children := files[dir]
+		for _, sub := range subdirs[dir] {
+			children = append(children, child{"dir", path.Base(sub), digests[sub]})
+		}
+		sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+		h := newHash()
+		for _, c := range children {
+			h.Write([]byte(c.kind + " " + hex.EncodeToString(c.digest) + " " + c.name + "\x00"))
+		}
+		digests[dir] = h.Sum(nil)
+	}
+	return digests, nil
+}
+
+func depth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// TreeVersion labels digests made with the tree encoding described at
+// RootDigest. A future encoding, if ever needed, gets a new label rather
+// than silently changing what existing digests mean.
+const TreeVersion = "tree1"
+
+// RootDigest returns the canonical root digest of the tree the manifest
+// describes, labeled with the encoding version and algorithm, e.g.
+//
+//	tree1:sha256:6b1f...
+//
+// Two machines holding the same files under the same relative names always
+// compute the same value, whatever order they list or scan files in, and
+// whatever platform they run on. The computation is:
+//
+//  1. Each path is made canonical: separators become '/', "." components
+//     and a leading "./" are dropped, and the path must then be non-empty,
+//     relative, and free of ".." components, NUL and newline. Any other
+//     path is an InvalidPathError, as is a path listed twice. Names are
+//     otherwise compared as bytes: no case folding or Unicode normalization
+//     is applied, so callers mixing platforms should normalize names before
+//     building the manifest.
+//  2. Each directory, starting from the deepest, is hashed as described at
+//     TreeDigests, over its files' digests under algorithm and its
+//     subdirectories' tree digests. Empty directories do not appear, since
+//     manifests only list files.
+//  3. The root digest is the lower-case hex digest of the top directory,
+//     prefixed with TreeVersion and the algorithm name.
+//
+// Only names and contents take part; file metadata such as modes and times
+// does not. Because the algorithm is part of the label, digests computed
+// with different algorithms can be recorded side by side and are never
+// compared with each other by mistake.
+func (m *Manifest) RootDigest(algorithm string, newHash func() hash.Hash) (string, error) {
+	seen := make(map[string]bool, len(m.Entries))
+	for _, entry := range m.Entries {
+		canonical, err := CanonicalPath(entry.Path)
+		if err != nil {
+			return "", err
+		}
+		if seen[canonical] {
+			return "", InvalidPathError{Path: entry.Path}
+		}
+		seen[canonical] = true
+	}
+	digests, err := m.TreeDigests(algorithm, newHash)
+	if err != nil {
+		return "", err
+	}
+	return TreeVersion + ":" + strings.ToLower(algorithm) + ":" + hex.EncodeToString(digests["."]), nil
+}
+
+// CanonicalPath applies the path rules of RootDigest, returning the
+// canonical form of filePath or an InvalidPathError.
+func CanonicalPath(filePath string) (string, error) {
+	if strings.ContainsAny(filePath, "\x00\n") {
+		return "", InvalidPathError{Path: filePath}
+	}
+	cleaned := cleanPath(filePath)
+	if strings.HasPrefix(cleaned, "/") || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", InvalidPathError{Path: filePath}
+	}
+	return cleaned, nil
+}
+
+// CanonicalizePaths rewrites every entry's path to its canonical form, as
+// returned by CanonicalPath, so that a manifest written on Windows uses '/'
+// separators and compares equal, line for line, with one written on Unix.
+// If any path has no canonical form, the manifest is left unchanged and the
+// InvalidPathError is returned.
+func (m *Manifest) CanonicalizePaths() error {
+	paths := make([]string, len(m.Entries))
+	for index, entry := range m.Entries {
+		canonical, err := CanonicalPath(entry.Path)
+		if err != nil {
+			return err
+		}
+		paths[index] = canonical
+	}
+	for index := range m.Entries {
+		m.Entries[index].Path = paths[index]
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func fileDigest(contents string) []byte {
+	sum := sha256.Sum256([]byte(contents))
+	return sum[:]
+}
+
+func Test_TreeDigests(t *testing.T) {
+	m := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{
+		{Path: "a/b/one.txt", Digests: [][]byte{fileDigest("one")}},
+		{Path: "a/two.txt", Digests: [][]byte{fileDigest("two")}},
+		{Path: "three.txt", Digests: [][]byte{fileDigest("three")}},
+	}}
+	digests, err := m.TreeDigests("sha256", sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digests) != 3 {
+		t.Fatalf("expected digests for ., a and a/b, got %d\n", len(digests))
+	}
+
+	leaf := sha256.Sum256([]byte("file " + hex.EncodeToString(fileDigest("one")) + " one.txt\x00"))
+	if !bytes.Equal(digests["a/b"], leaf[:]) {
+		t.Fatalf("a/b digest was %x, expected %x\n", digests["a/b"], leaf)
+	}
+
+	// The same content listed in a different order gives the same digests,
+	// and a change deep in the tree changes every digest above it.
+	reordered := &Manifest{Algorithms: m.Algorithms, Entries: []Entry{m.Entries[2], m.Entries[0], m.Entries[1]}}
+	again, _ := reordered.TreeDigests("sha256", sha256.New)
+	if !bytes.Equal(again["."], digests["."]) {
+		t.Fatal("root digest depends on manifest order")
+	}
+	m.Entries[0].Digests[0] = fileDigest("changed")
+	changed, _ := m.TreeDigests("sha256", sha256.New)
+	if bytes.Equal(changed["."], digests["."]) || bytes.Equal(changed["a"], digests["a"]) {
+		t.Fatal("a change to a/b/one.txt did not propagate to the root")
+	}
+
+	for _, invalid := range []string{"/etc/passwd", "../escape.txt", "."} {
+		outside := &Manifest{Algorithms: m.Algorithms, Entries: []Entry{{Path: invalid, Digests: [][]byte{fileDigest("")}}}}
+		if _, err = outside.TreeDigests("sha256", sha256.New); !errors.Is(err, ErrInvalidPath) {
+			t.Fatalf("TreeDigests of %q gave %v, expected an InvalidPathError\n", invalid, err)
+		}
+	}
+}
+
+func Test_RootDigest(t *testing.T) {
+	unix := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{
+		{Path: "src/main.go", Digests: [][]byte{fileDigest("package main")}},
+		{Path: "README", Digests: [][]byte{fileDigest("readme")}},
+	}}
+	windows := &Manifest{Algorithms: []string{"SHA256"}, Entries: []Entry{
+		{Path: ".\\README", Digests: [][]byte{fileDigest("readme")}},
+		{Path: "src\\main.go", Digests: [][]byte{fileDigest("package main")}},
+	}}
+	a, err := unix.RootDigest("sha256", sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := windows.RootDigest("sha256", sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b || a[:len("tree1:sha256:")] != "tree1:sha256:" {
+		t.Fatalf("root digests differ: %s, %s\n", a, b)
+	}
+
+	duplicate := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{
+		{Path: "a/b", Digests: [][]byte{fileDigest("x")}},
+		{Path: "a/./b", Digests: [][]byte{fileDigest("y")}},
+	}}
+	if _, err = duplicate.RootDigest("sha256", sha256.New); !errors.Is(err, ErrInvalidPath) {
+		t.Fatalf("duplicate path gave %v\n", err)
+	}
+
+	for _, bad := range []string{"../escape", "/absolute", "a\nb", "."} {
+		invalid := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{{Path: bad, Digests: [][]byte{fileDigest("x")}}}}
+		if _, err = invalid.RootDigest("sha256", sha256.New); !errors.Is(err, ErrInvalidPath) {
+			t.Fatalf("path %q gave %v\n", bad, err)
+		}
+	}
+}
+
+func Test_CanonicalizePaths(t *testing.T) {
+	m := &Manifest{Algorithms: []string{"sha256"}, Entries: []Entry{
+		{Path: ".\\dist\\app.exe", Digests: [][]byte{fileDigest("app")}},
+		{Path: "./README", Digests: [][]byte{fileDigest("readme")}},
+	}}
+	if err := m.CanonicalizePaths(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Entries[0].Path != "dist/app.exe" || m.Entries[1].Path != "README" {
+		t.Fatalf("paths were %q, %q\n", m.Entries[0].Path, m.Entries[1].Path)
+	}
+}
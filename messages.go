@@ -0,0 +1,65 @@
+package multihash
+
+import (
+	"hash"
+	"runtime"
+	"sync"
+)
+
+// minMessagesPerWorker keeps FromMessages from starting a goroutine for
+// fewer messages than are worth its cost.
+const minMessagesPerWorker = 64
+
+// FromMessages hashes each of msgs independently with every hash returned by
+// hashes, for workloads of many small messages such as records or packets,
+// where FromReader's per-stream goroutines and buffers would dominate. The
+// messages are split into contiguous runs, one per worker; each worker calls
+// hashes once and resets its hashes between messages, and the digests are
+// written into one allocation per worker.
+//
+// result[i][j] is the digest of msgs[i] under the jth hash.
+func FromMessages(msgs [][]byte, hashes func() []hash.Hash) [][][]byte {
+	result := make([][][]byte, len(msgs))
+	workers := runtime.GOMAXPROCS(0)
+	if limit := (len(msgs) + minMessagesPerWorker - 1) / minMessagesPerWorker; workers > limit {
+		workers = limit
+	}
+	if workers <= 1 {
+		hashMessages(msgs, result, hashes())
+		return result
+	}
+	var wg sync.WaitGroup
+	per := (len(msgs) + workers - 1) / workers
+	for start := 0; start < len(msgs); start += per {
+		end := start + per
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			hashMessages(msgs[start:end], result[start:end], hashes())
+		}(start, end)
+	}
+	wg.Wait()
+	return result
+}
+
+func hashMessages(msgs [][]byte, result [][][]byte, hashes []hash.Hash) {
+	size := 0
+	for _, h := range hashes {
+		size += h.Size()
+	}
+	slab := make([]byte, 0, size*len(msgs))
+	digests := make([][]byte, len(hashes)*len(msgs))
+	for index, msg := range msgs {
+		result[index] = digests[index*len(hashes) : (index+1)*len(hashes) : (index+1)*len(hashes)]
+		for j, h := range hashes {
+			h.Reset()
+			h.Write(msg)
+			start := len(slab)
+			slab = h.Sum(slab)
+			result[index][j] = slab[start:len(slab):len(slab)]
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"hash"
+	"strconv"
+	"testing"
+)
+
+func Test_FromMessages(t *testing.T) {
+	for _, count := range []int{0, 1, 1000} {
+		msgs := make([][]byte, count)
+		for index := range msgs {
+			msgs[index] = []byte("record " + strconv.Itoa(index))
+		}
+		result := FromMessages(msgs, func() []hash.Hash {
+			return []hash.Hash{md5.New(), sha256.New()}
+		})
+		if len(result) != count {
+			t.Fatalf("expected %d results, got %d\n", count, len(result))
+		}
+		for index, msg := range msgs {
+			md5Digest, sha256Digest := md5.Sum(msg), sha256.Sum256(msg)
+			if !digestsEqual(result[index], [][]byte{md5Digest[:], sha256Digest[:]}) {
+				t.Fatalf("wrong digests for message %d: %x\n", index, result[index])
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package multihash
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// MigrationReport lists the outcome of Migrate, by the paths recorded in
+// the manifest.
+type MigrationReport struct {
+	// Migrated files were read and given digests under the new algorithms.
+	Migrated []string
+	// Mismatched files no longer match the digests recorded under the old
+	// algorithms, and were given no new digests.
+	Mismatched []string
+	// Missing files are in the manifest but not on disk; their entries
+	// keep only their old digests.
+	Missing []string
+	// Unverified files have no digest under any of the old algorithms, so
+	// nothing vouches for their current content; they were not read, and
+	// were given no new digests.
+	Unverified []string
+	// Failed files could not be read, for reasons other than not existing.
+	Failed map[string]error
+}
+
+// Migrate moves m to new algorithms, as when replacing MD5 or SHA-1 with
+// SHA-256 across an archive: each file under root is read once, hashed with
+// both m's algorithms and the new ones, and the result is a manifest
+// carrying m's algorithms followed by those of algorithms it did not already
+// have. The old digests are kept as recorded, so that the upgraded manifest
+// can still be checked against copies hashed with the old algorithms, and can
+// be dropped later once nothing depends on them.
+//
+// A file is only given new digests if it still matches its old ones, in the
+// same read; otherwise it is reported as Mismatched, so that corruption that
+// happened under the old algorithms is not recorded anew as good. A file
+// with no old digests has nothing to match, and is reported as Unverified.
+// A path that could lie outside root is Failed with a
+// manifest.InvalidPathError, unread. A migrated file is given a digest under
+// every algorithm it had none for, including those of algorithms that m
+// already lists; files that are not migrated keep nil digests.
+func Migrate(root string, m *manifest.Manifest, algorithms ...string) (*manifest.Manifest, MigrationReport, error) {
+	var report MigrationReport
+	upgraded := &manifest.Manifest{Algorithms: append([]string(nil), m.Algorithms...)}
+	for _, algorithm := range algorithms {
+		if upgraded.AlgorithmIndex(algorithm) < 0 {
+			upgraded.Algorithms = append(upgraded.Algorithms, algorithm)
+		}
+	}
+	if _, err := NewAll(upgraded.Algorithms...); err != nil {
+		return nil, report, err
+	}
+
+	for _, entry := range m.Entries {
+		digests := make([][]byte, len(upgraded.Algorithms))
+		copy(digests, entry.Digests)
+		upgraded.Entries = append(upgraded.Entries, manifest.Entry{Path: entry.Path, Digests: digests})
+		if !hasDigest(entry.Digests) {
+			report.Unverified = append(report.Unverified, entry.Path)
+			continue
+		}
+		relative, err := manifest.CanonicalPath(entry.Path)
+		var hashset [][]byte
+		if err == nil {
+			hashes, _ := NewAll(upgraded.Algorithms...)
+			hashset, err = FromFile(filepath.Join(root, filepath.FromSlash(relative)), hashes...)
+		}
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			report.Missing = append(report.Missing, entry.Path)
+		case err != nil:
+			if report.Failed == nil {
+				report.Failed = make(map[string]error)
+			}
+			report.Failed[entry.Path] = err
+		case !DigestsMatch(digests, hashset):
+			report.Mismatched = append(report.Mismatched, entry.Path)
+		default:
+			for index, digest := range digests {
+				if digest == nil {
+					digests[index] = hashset[index]
+				}
+			}
+			report.Migrated = append(report.Migrated, entry.Path)
+		}
+	}
+	return upgraded, report, nil
+}
+
+func hasDigest(digests [][]byte) bool {
+	for _, digest := range digests {
+		if digest != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+func Test_Migrate(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("alpha"), 0o644)
+	oldA := md5.Sum([]byte("alpha"))
+	os.WriteFile(filepath.Join(root, "c.txt"), []byte("gamma, damaged"), 0o644)
+	oldB := md5.Sum([]byte("beta"))
+	oldC := md5.Sum([]byte("gamma"))
+	os.WriteFile(filepath.Join(root, "d.txt"), []byte("delta"), 0o644)
+	m := &manifest.Manifest{
+		Algorithms: []string{"md5"},
+		Entries: []manifest.Entry{
+			{Path: "a.txt", Digests: [][]byte{oldA[:]}},
+			{Path: "b.txt", Digests: [][]byte{oldB[:]}},
+			{Path: "c.txt", Digests: [][]byte{oldC[:]}},
+			{Path: "d.txt", Digests: [][]byte{nil}},
+			{Path: "../a.txt", Digests: [][]byte{oldA[:]}},
+		},
+	}
+
+	upgraded, report, err := Migrate(root, m, "sha256", "md5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicesEqual(upgraded.Algorithms, []string{"md5", "sha256"}) {
+		t.Fatalf("unexpected algorithms %v\n", upgraded.Algorithms)
+	}
+	newA := sha256.Sum256([]byte("alpha"))
+	if digest, _ := upgraded.Digest("a.txt", "sha256"); !slicesEqual(digest, newA[:]) {
+		t.Fatalf("a.txt sha256 %x, expected %x\n", digest, newA)
+	}
+	if digest, _ := upgraded.Digest("a.txt", "md5"); !slicesEqual(digest, oldA[:]) {
+		t.Fatal("the old digest was not kept")
+	}
+	if _, ok := upgraded.Digest("b.txt", "sha256"); ok {
+		t.Fatal("a missing file was given a new digest")
+	}
+	if _, ok := upgraded.Digest("c.txt", "sha256"); ok {
+		t.Fatal("a mismatched file was given a new digest")
+	}
+	if _, ok := upgraded.Digest("d.txt", "sha256"); ok {
+		t.Fatal("a file with no old digest was given a new digest")
+	}
+	if !slicesEqual(report.Migrated, []string{"a.txt"}) || !slicesEqual(report.Missing, []string{"b.txt"}) ||
+		!slicesEqual(report.Mismatched, []string{"c.txt"}) || !slicesEqual(report.Unverified, []string{"d.txt"}) {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	if !errors.Is(report.Failed["../a.txt"], manifest.ErrInvalidPath) {
+		t.Fatalf("a path outside root gave %v\n", report.Failed["../a.txt"])
+	}
+}
+
+func Test_Migrate_fillsExistingAlgorithms(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("alpha"), 0o644)
+	oldA := md5.Sum([]byte("alpha"))
+	m := &manifest.Manifest{
+		Algorithms: []string{"md5", "sha256"},
+		Entries:    []manifest.Entry{{Path: "a.txt", Digests: [][]byte{oldA[:], nil}}},
+	}
+
+	upgraded, report, err := Migrate(root, m, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newA := sha256.Sum256([]byte("alpha"))
+	if digest, _ := upgraded.Digest("a.txt", "sha256"); !slicesEqual(digest, newA[:]) {
+		t.Fatalf("a.txt sha256 %x, expected %x\n", digest, newA)
+	}
+	if !slicesEqual(report.Migrated, []string{"a.txt"}) {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
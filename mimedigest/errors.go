@@ -0,0 +1,46 @@
+package mimedigest
+
+import (
+	"strconv"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+var ErrMalformedMessage = errcode.New(errcode.Malformed, "malformed MIME message")
+var ErrUnsupportedEncoding = errcode.New(errcode.UnsupportedFormat, "unsupported content transfer encoding")
+
+type MalformedMessageError struct {
+	Section string
+	Reason  string
+}
+
+func (e MalformedMessageError) Error() string {
+	if e.Section == "" {
+		return "malformed MIME message: " + e.Reason
+	}
+	return "malformed MIME message: section " + e.Section + ": " + e.Reason
+}
+
+func (e MalformedMessageError) Is(target error) bool {
+	return target == ErrMalformedMessage
+}
+
+func (e MalformedMessageError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+type UnsupportedEncodingError struct {
+	Encoding string
+}
+
+func (e UnsupportedEncodingError) Error() string {
+	return "unsupported content transfer encoding " + strconv.Quote(e.Encoding)
+}
+
+func (e UnsupportedEncodingError) Is(target error) bool {
+	return target == ErrUnsupportedEncoding
+}
+
+func (e UnsupportedEncodingError) Code() errcode.Code {
+	return errcode.UnsupportedFormat
+}
@@ -0,0 +1,171 @@
+// package mimedigest fingerprints the attachments of a MIME message, such as
+// an email, in a single pass: each attachment's body is decoded from its
+// transfer encoding and hashed with the requested algorithms as the message
+// is read, without buffering it.
+package mimedigest
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Attachment describes one attachment of a message.
+type Attachment struct {
+	// Section is the part's IMAP section number, e.g. "2" or "3.1", which
+	// locates it within the message's MIME structure.
+	Section     string
+	FileName    string
+	ContentType string
+	// Size is the decoded size of the attachment's body.
+	Size int64
+	// Digests are of the decoded body, in the order algorithms were given.
+	Digests [][]byte
+}
+
+// Attachments reads the message from r and returns its attachments. A part
+// is an attachment if its Content-Disposition is "attachment" or it names a
+// file; inline text and HTML bodies are skipped. Attached messages
+// (message/rfc822) that are not themselves marked as attachments are
+// descended into.
+func Attachments(r io.Reader, algorithms ...string) ([]Attachment, error) {
+	if _, err := multihash.NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	w := walker{algorithms: algorithms}
+	if err = w.walk(textproto.MIMEHeader(msg.Header), msg.Body, "", true); err != nil {
+		return nil, err
+	}
+	return w.attachments, nil
+}
+
+type walker struct {
+	algorithms  []string
+	attachments []Attachment
+}
+
+// walk visits the entity with header and body at section. message marks a
+// message's own body, which IMAP numbers as the message's first part when it
+// is not multipart.
+func (w *walker) walk(header textproto.MIMEHeader, body io.Reader, section string, message bool) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	fileName := dispositionParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(fileName); err == nil {
+		fileName = decoded
+	}
+	attachment := disposition == "attachment" || fileName != ""
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return w.walkMultipart(body, params["boundary"], section)
+	case mediaType == "message/rfc822" && !attachment:
+		decoded, err := decodeBody(header, body)
+		if err != nil {
+			return err
+		}
+		msg, err := mail.ReadMessage(decoded)
+		if err != nil {
+			return err
+		}
+		return w.walk(textproto.MIMEHeader(msg.Header), msg.Body, section, true)
+	case !attachment:
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}
+
+	if message {
+		section = childSection(section, 1)
+	}
+	decoded, err := decodeBody(header, body)
+	if err != nil {
+		return err
+	}
+	hashes, err := multihash.NewAll(w.algorithms...)
+	if err != nil {
+		return err
+	}
+	counter := &countingReader{r: decoded}
+	digests, err := multihash.FromReader(counter, hashes...)
+	if err != nil {
+		return err
+	}
+	w.attachments = append(w.attachments, Attachment{
+		Section:     section,
+		FileName:    fileName,
+		ContentType: mediaType,
+		Size:        counter.n,
+		Digests:     digests,
+	})
+	return nil
+}
+
+func (w *walker) walkMultipart(body io.Reader, boundary, section string) error {
+	if boundary == "" {
+		return MalformedMessageError{Section: section, Reason: "multipart entity without a boundary"}
+	}
+	mr := multipart.NewReader(body, boundary)
+	for index := 1; ; index++ {
+		// NextRawPart leaves the transfer encoding to decodeBody, which
+		// also handles base64.
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = w.walk(part.Header, part, childSection(section, index), false); err != nil {
+			return err
+		}
+	}
+}
+
+func childSection(section string, index int) string {
+	if section == "" {
+		return strconv.Itoa(index)
+	}
+	return section + "." + strconv.Itoa(index)
+}
+
+func decodeBody(header textproto.MIMEHeader, body io.Reader) (io.Reader, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))); encoding {
+	case "", "7bit", "8bit", "binary":
+		return body, nil
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body), nil
+	case "quoted-printable":
+		return quotedprintable.NewReader(body), nil
+	default:
+		return nil, UnsupportedEncodingError{Encoding: encoding}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
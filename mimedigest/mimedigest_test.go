@@ -0,0 +1,75 @@
+package mimedigest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_Attachments(t *testing.T) {
+	pdf := []byte("%PDF-1.4 not really a pdf\x00\xff")
+	notes := []byte("caf\xc3\xa9 = 1\r\n")
+	message := strings.Join([]string{
+		"From: a@example.com",
+		"To: b@example.com",
+		"Subject: files",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="outer"`,
+		"",
+		"--outer",
+		"Content-Type: text/plain",
+		"",
+		"See attached.",
+		"--outer",
+		`Content-Type: application/pdf; name="report.pdf"`,
+		"Content-Disposition: attachment",
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString(pdf[:12]) + "\r",
+		base64.StdEncoding.EncodeToString(pdf[12:]),
+		"--outer",
+		"Content-Type: message/rfc822",
+		"",
+		"Subject: forwarded",
+		`Content-Type: multipart/mixed; boundary="inner"`,
+		"",
+		"--inner",
+		"Content-Type: text/plain",
+		"",
+		"inline body",
+		"--inner",
+		"Content-Type: text/plain; charset=utf-8",
+		`Content-Disposition: attachment; filename="=?utf-8?q?notes_=C3=A9.txt?="`,
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"caf=C3=A9 =3D 1",
+		"",
+		"--inner--",
+		"--outer--",
+		"",
+	}, "\r\n")
+
+	attachments, err := Attachments(strings.NewReader(message), "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 2 {
+		t.Fatalf("found %d attachments\n", len(attachments))
+	}
+	pdfDigest := sha256.Sum256(pdf)
+	if a := attachments[0]; a.Section != "2" || a.FileName != "report.pdf" || a.Size != int64(len(pdf)) || !bytes.Equal(a.Digests[0], pdfDigest[:]) {
+		t.Fatalf("unexpected first attachment %+v\n", a)
+	}
+	notesDigest := sha256.Sum256(notes)
+	if a := attachments[1]; a.Section != "3.2" || a.FileName != "notes é.txt" || !bytes.Equal(a.Digests[0], notesDigest[:]) {
+		t.Fatalf("unexpected second attachment %+v %q\n", a, a.FileName)
+	}
+
+	_, err = Attachments(strings.NewReader("Content-Type: application/zip; name=a.zip\r\nContent-Transfer-Encoding: uuencode\r\n\r\nbody"), "sha256")
+	if !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatalf("expected an unsupported encoding error, got %v\n", err)
+	}
+}
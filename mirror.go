@@ -0,0 +1,113 @@
+package multihash
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+// MirrorResult is the outcome of hashing one source in VerifyMirrors.
+type MirrorResult struct {
+	URL string
+	// Digests are in the same order as the algorithms passed to
+	// VerifyMirrors; nil if Err is set.
+	Digests [][]byte
+	// Trusted reports whether the source matched every trusted digest
+	// given. It is false when none were given, or all were nil.
+	Trusted bool
+	Err     error
+}
+
+// MirrorReport lists the results of VerifyMirrors, in the order the sources
+// were given.
+type MirrorReport struct {
+	Results []MirrorResult
+}
+
+// Agree reports whether every source was fetched and all of them served
+// content with the same digests.
+func (r MirrorReport) Agree() bool {
+	for _, result := range r.Results {
+		if result.Err != nil || !digestsEqual(result.Digests, r.Results[0].Digests) {
+			return false
+		}
+	}
+	return true
+}
+
+// Trusted returns the URLs of the sources that matched the trusted digests.
+func (r MirrorReport) Trusted() []string {
+	var urls []string
+	for _, result := range r.Results {
+		if result.Trusted {
+			urls = append(urls, result.URL)
+		}
+	}
+	return urls
+}
+
+// VerifyMirrors fetches the same object from every one of urls concurrently,
+// hashing each response as it streams in with algorithms, and reports how
+// they compare: whether all the mirrors agree, and which match trusted, the
+// digests under algorithms obtained from a source the caller trusts. A nil
+// trusted digest is not checked, and trusted may be nil altogether when
+// only agreement matters, e.g. when auditing mirrors of an object with no
+// published checksum.
+//
+// Failures to fetch a source are recorded in its result rather than
+// returned; the error is only for algorithms that cannot be constructed, or
+// a DigestCountError if trusted is not nil and does not hold one digest
+// for each of them. client is http.DefaultClient if nil.
+func VerifyMirrors(urls []string, client *http.Client, algorithms []string, trusted [][]byte) (MirrorReport, error) {
+	if _, err := NewAll(algorithms...); err != nil {
+		return MirrorReport{}, err
+	}
+	if trusted != nil && len(trusted) != len(algorithms) {
+		return MirrorReport{}, DigestCountError{Digests: len(trusted), Hashes: len(algorithms)}
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	checkTrusted := hasDigest(trusted)
+	report := MirrorReport{Results: make([]MirrorResult, len(urls))}
+	var wg sync.WaitGroup
+	for index, url := range urls {
+		wg.Add(1)
+		go func(result *MirrorResult, url string) {
+			defer wg.Done()
+			result.URL = url
+			result.Digests, result.Err = hashURL(client, url, algorithms)
+			result.Trusted = result.Err == nil && checkTrusted && DigestsMatch(trusted, result.Digests)
+		}(&report.Results[index], url)
+	}
+	wg.Wait()
+	return report, nil
+}
+
+func hashURL(client *http.Client, url string, algorithms []string) ([][]byte, error) {
+	hashes, err := NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, HTTPStatusError{URL: url, StatusCode: response.StatusCode}
+	}
+	return FromReader(response.Body, hashes...)
+}
+
+func digestsEqual(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if !bytes.Equal(a[index], b[index]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_VerifyMirrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a/file", "/b/file":
+			w.Write([]byte("Sample text file\n"))
+		case "/tampered/file":
+			w.Write([]byte("Sample text file!\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte("Sample text file\n"))
+	trusted := [][]byte{sum[:]}
+
+	report, err := VerifyMirrors([]string{server.URL + "/a/file", server.URL + "/b/file"}, nil, []string{"sha256"}, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Agree() || len(report.Trusted()) != 2 {
+		t.Fatalf("matching mirrors reported %+v\n", report)
+	}
+	report, err = VerifyMirrors([]string{server.URL + "/a/file"}, nil, []string{"sha256"}, [][]byte{nil})
+	if err != nil || len(report.Trusted()) != 0 {
+		t.Fatalf("no trusted digests gave %+v, %v\n", report, err)
+	}
+	if _, err = VerifyMirrors([]string{server.URL + "/a/file"}, nil, []string{"sha256", "md5"}, trusted); !errors.Is(err, ErrDigestCount) {
+		t.Fatalf("one trusted digest for two algorithms gave %v\n", err)
+	}
+
+	urls := []string{server.URL + "/a/file", server.URL + "/tampered/file", server.URL + "/missing/file"}
+	report, err = VerifyMirrors(urls, nil, []string{"sha256", "md5"}, [][]byte{sum[:], nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Agree() {
+		t.Fatal("disagreeing mirrors reported as agreeing")
+	}
+	if trustedURLs := report.Trusted(); len(trustedURLs) != 1 || trustedURLs[0] != urls[0] {
+		t.Fatalf("trusted mirrors were %q\n", trustedURLs)
+	}
+	var status HTTPStatusError
+	if !errors.As(report.Results[2].Err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing object gave %v\n", report.Results[2].Err)
+	}
+}
@@ -0,0 +1,185 @@
+// package msgsink keeps running digests over a stream of messages, such as
+// those consumed from a Kafka topic, for checking a replicated log pipeline
+// end to end: run a Sink at the source and at the destination and compare
+// their checkpoints.
+//
+// Each message value is hashed length-prefixed, as an 8-byte big-endian
+// length followed by the value, so that message boundaries are part of the
+// digest; splitting or joining messages changes it.
+package msgsink
+
+import (
+	"context"
+	"encoding/binary"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Message is one consumed message.
+type Message struct {
+	Key       []byte
+	Partition int32
+	Offset    int64
+	Value     []byte
+}
+
+// Iterator yields messages, returning io.EOF after the last. It is where a
+// client library's consumer is plugged in.
+type Iterator interface {
+	Next(ctx context.Context) (Message, error)
+}
+
+// Grouping decides which messages share a running digest. Only the order of
+// messages within a group is part of its digest, so a group should be
+// something the pipeline keeps in order.
+type Grouping int
+
+const (
+	// ByPartition keeps a digest per partition, which brokers keep in
+	// order.
+	ByPartition Grouping = iota
+	// ByKey keeps a digest per message key, for pipelines that repartition
+	// but preserve per-key order.
+	ByKey
+)
+
+// Checkpoint is the state of a Sink's digests.
+type Checkpoint struct {
+	Messages int64
+	// Offsets holds the offset of the last message seen in each partition.
+	Offsets map[int32]int64
+	// Groups holds each group's digests, in algorithm order. Partitions are
+	// named by their number in decimal; keys as themselves.
+	Groups map[string][][]byte
+	// Global is, for each algorithm, that algorithm's digest of the groups'
+	// names and digests in sorted order. It depends only on the contents of
+	// each group, not on how the groups' messages were interleaved, so two
+	// consumers of the same data agree on it.
+	Global [][]byte
+}
+
+// Sink maintains running digests of messages. It is not safe for
+// concurrent use.
+type Sink struct {
+	algorithms []string
+	grouping   Grouping
+	messages   int64
+	offsets    map[int32]int64
+	groups     map[string][]hash.Hash
+}
+
+// NewSink returns a Sink hashing with algorithms, which must be registered
+// multihash algorithms.
+func NewSink(grouping Grouping, algorithms ...string) (*Sink, error) {
+	if _, err := multihash.NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	return &Sink{
+		algorithms: algorithms,
+		grouping:   grouping,
+		offsets:    map[int32]int64{},
+		groups:     map[string][]hash.Hash{},
+	}, nil
+}
+
+// Add hashes m into its group's digests.
+func (s *Sink) Add(m Message) error {
+	group := string(m.Key)
+	if s.grouping == ByPartition {
+		group = strconv.FormatInt(int64(m.Partition), 10)
+	}
+	hashes, ok := s.groups[group]
+	if !ok {
+		var err error
+		if hashes, err = multihash.NewAll(s.algorithms...); err != nil {
+			return err
+		}
+		s.groups[group] = hashes
+	}
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(m.Value)))
+	for _, h := range hashes {
+		h.Write(length[:])
+		h.Write(m.Value)
+	}
+	s.messages++
+	s.offsets[m.Partition] = m.Offset
+	return nil
+}
+
+// Checkpoint returns the current digests. The sink carries on from them.
+func (s *Sink) Checkpoint() (Checkpoint, error) {
+	checkpoint := Checkpoint{
+		Messages: s.messages,
+		Offsets:  make(map[int32]int64, len(s.offsets)),
+		Groups:   make(map[string][][]byte, len(s.groups)),
+	}
+	for partition, offset := range s.offsets {
+		checkpoint.Offsets[partition] = offset
+	}
+	names := make([]string, 0, len(s.groups))
+	for name, hashes := range s.groups {
+		digests := make([][]byte, len(hashes))
+		for index, h := range hashes {
+			digests[index] = h.Sum(nil)
+		}
+		checkpoint.Groups[name] = digests
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	global, err := multihash.NewAll(s.algorithms...)
+	if err != nil {
+		return checkpoint, err
+	}
+	var length [8]byte
+	for index, h := range global {
+		for _, name := range names {
+			binary.BigEndian.PutUint64(length[:], uint64(len(name)))
+			h.Write(length[:])
+			h.Write([]byte(name))
+			h.Write(checkpoint.Groups[name][index])
+		}
+		checkpoint.Global = append(checkpoint.Global, h.Sum(nil))
+	}
+	return checkpoint, nil
+}
+
+// Consume adds every message from it to s, calling emit with a checkpoint
+// after each every messages, if every is positive, and once more at the end
+// of the stream. It stops at the first error from it, emit or ctx.
+func Consume(ctx context.Context, it Iterator, s *Sink, every int64, emit func(Checkpoint) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m, err := it.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.Add(m); err != nil {
+			return err
+		}
+		if every > 0 && s.messages%every == 0 {
+			if err = s.emit(emit); err != nil {
+				return err
+			}
+		}
+	}
+	return s.emit(emit)
+}
+
+func (s *Sink) emit(emit func(Checkpoint) error) error {
+	checkpoint, err := s.Checkpoint()
+	if err != nil {
+		return err
+	}
+	return emit(checkpoint)
+}
@@ -0,0 +1,67 @@
+package msgsink
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+type sliceIterator []Message
+
+func (s *sliceIterator) Next(context.Context) (Message, error) {
+	if len(*s) == 0 {
+		return Message{}, io.EOF
+	}
+	m := (*s)[0]
+	*s = (*s)[1:]
+	return m, nil
+}
+
+func Test_Sink(t *testing.T) {
+	source := sliceIterator{
+		{Partition: 0, Offset: 0, Value: []byte("a")},
+		{Partition: 1, Offset: 0, Value: []byte("b")},
+		{Partition: 0, Offset: 1, Value: []byte("c")},
+		{Partition: 1, Offset: 1, Value: []byte("d")},
+	}
+	// The replica saw the same partitions interleaved differently.
+	replica := sliceIterator{source[1], source[3], source[0], source[2]}
+
+	run := func(it Iterator) (checkpoints []Checkpoint) {
+		sink, err := NewSink(ByPartition, "sha256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Consume(context.Background(), it, sink, 2, func(c Checkpoint) error {
+			checkpoints = append(checkpoints, c)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return checkpoints
+	}
+	fromSource := run(&source)
+	fromReplica := run(&replica)
+	if len(fromSource) != 3 || fromSource[2].Messages != 4 || fromSource[2].Offsets[1] != 1 {
+		t.Fatalf("unexpected checkpoints %+v\n", fromSource)
+	}
+	if !bytes.Equal(fromSource[2].Global[0], fromReplica[2].Global[0]) {
+		t.Fatal("global digests differ for the same partitions")
+	}
+
+	// Joining two messages into one changes the partition's digest.
+	joined := sliceIterator{
+		{Partition: 0, Offset: 0, Value: []byte("ac")},
+		{Partition: 1, Offset: 0, Value: []byte("b")},
+		{Partition: 1, Offset: 1, Value: []byte("d")},
+	}
+	fromJoined := run(&joined)
+	last := fromJoined[len(fromJoined)-1]
+	if bytes.Equal(last.Groups["0"][0], fromSource[2].Groups["0"][0]) || !bytes.Equal(last.Groups["1"][0], fromSource[2].Groups["1"][0]) {
+		t.Fatal("message boundaries are not part of the digest")
+	}
+}
@@ -4,10 +4,12 @@
 package multihash
 
 import (
+	"context"
 	"errors"
 	"hash"
 	"io"
 	"os"
+	"runtime"
 	"sync"
 )
 
@@ -47,65 +49,203 @@ func FromFile(filename string, hashes ...hash.Hash) (hashset [][]byte, err error
 //	hashes := fromReader(data, crypto.MD5.New(), crypto.SHA1.New())
 //
 // hashes[0] will be the MD5 digest and hashes[1] the SHA1 digest.
+//
+// Reading and hashing overlap: while the hashes consume one buffer, the next
+// Read fills another, so neither the source nor the CPUs sit idle waiting
+// for the other. For sources that also benefit from concurrent reads, such
+// as object stores, see FromReaderAt.
 func FromReader(data io.Reader, hashFunctions ...hash.Hash) (hashset [][]byte, err error) {
-	buffer, ok := (bufferPool.Get()).(*[]byte)
-	if !ok {
-		return hashset, ErrBufferGetFailed
-	}
-	defer bufferPool.Put(buffer)
-	errorChannel := make(chan error)
-	readySignals := make(chan int)
-	returnChannels := make([]chan []byte, len(hashFunctions))
-	for index, hash := range hashFunctions {
-		returnChannel := make(chan []byte)
-		go hashFeeder(hash, errorChannel, readySignals, returnChannel, buffer)
-		returnChannels[index] = returnChannel
+	return fromReader(context.Background(), data, pipelineDepth, hashFunctions)
+}
+
+// FromFileContext is FromFile, stopping early if ctx is cancelled or its
+// deadline passes; see FromReaderContext.
+func FromFileContext(ctx context.Context, filename string, hashes ...hash.Hash) (hashset [][]byte, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
 	}
+	defer f.Close()
+	return FromReaderContext(ctx, f, hashes...)
+}
 
-	for {
-		bytesRead, err := data.Read(*buffer)
-		if err != nil {
-			if bytesRead == 0 && errors.Is(err, io.EOF) {
-				close(readySignals)
-				break
+// FromReaderContext is FromReader, stopping early with ctx.Err() if ctx is
+// cancelled or its deadline passes. The context is checked before every
+// Read, so a long hash stops within one buffer of being cancelled, and all
+// the goroutines hashing it have exited by the time FromReaderContext
+// returns. A Read that blocks is not interrupted; for sources that can hang,
+// such as pipes, see StallReader. To report progress, wrap data in a
+// ProgressReader.
+func FromReaderContext(ctx context.Context, data io.Reader, hashes ...hash.Hash) (hashset [][]byte, err error) {
+	return fromReader(ctx, data, pipelineDepth, hashes)
+}
+
+// pipelineDepth is the number of buffers FromReader cycles through: one
+// being filled by Read while the others are hashed.
+const pipelineDepth = 2
+
+// fromReader is FromReader with depth buffers in flight. With a depth of 1,
+// each Read waits until the previous chunk is hashed, as FromReader once
+// did; the benchmarks compare the two.
+func fromReader(ctx context.Context, data io.Reader, depth int, hashFunctions []hash.Hash) (hashset [][]byte, err error) {
+	buffers := make([]*[]byte, depth)
+	for index := range buffers {
+		buffer, ok := (bufferPool.Get()).(*[]byte)
+		if !ok {
+			for _, buffer := range buffers[:index] {
+				bufferPool.Put(buffer)
 			}
-			return hashset, err
+			return hashset, ErrBufferGetFailed
 		}
-		for i := 0; i < len(hashFunctions); i++ {
-			readySignals <- bytesRead
+		buffers[index] = buffer
+	}
+	groups := groupHashes(hashFunctions, runtime.GOMAXPROCS(0))
+	feeders := make([]feeder, len(groups))
+	for index, group := range groups {
+		feeders[index] = feeder{
+			chunks: make(chan []byte, depth),
+			// Buffered so that a feeder never blocks acknowledging a chunk,
+			// nor sending its sums if FromReader returns early and never
+			// collects them.
+			acks:   make(chan error, depth),
+			result: make(chan [][]byte, 1),
 		}
-		for i := 0; i < len(hashFunctions); i++ {
-			if err = <-errorChannel; err != nil {
-				return hashset, err
+		go hashFeeder(group, feeders[index])
+	}
+	// On every return the feeders are stopped, and only once they have
+	// acknowledged every chunk are the buffers put back in the pool, since
+	// until then a feeder may still be hashing one.
+	outstanding := 0
+	stopped := false
+	stop := func() {
+		for ; outstanding > 0; outstanding-- {
+			for _, f := range feeders {
+				<-f.acks
+			}
+		}
+		if !stopped {
+			for _, f := range feeders {
+				close(f.chunks)
+			}
+			stopped = true
+		}
+	}
+	defer func() {
+		stop()
+		for _, buffer := range buffers {
+			bufferPool.Put(buffer)
+		}
+	}()
+	// awaitOldest waits until every feeder has hashed the oldest chunk in
+	// flight, freeing its buffer, and returns the first error they met.
+	awaitOldest := func() error {
+		outstanding--
+		var writeErr error
+		for _, f := range feeders {
+			if err := <-f.acks; err != nil && writeErr == nil {
+				writeErr = err
 			}
 		}
+		return writeErr
 	}
 
-	for _, returnChannel := range returnChannels {
-		hashset = append(hashset, <-returnChannel)
+	done := ctx.Done()
+	// next is the buffer to read into. It advances only when a chunk is
+	// sent, so a Read returning no data reuses its buffer rather than one
+	// the feeders may still be hashing.
+	for next := 0; ; {
+		select {
+		case <-done:
+			return hashset, ctx.Err()
+		default:
+		}
+		if outstanding == depth {
+			if err = awaitOldest(); err != nil {
+				return hashset, err
+			}
+		}
+		buffer := *buffers[next]
+		bytesRead, readErr := data.Read(buffer)
+		// Readers may return data along with an error, including io.EOF, so
+		// the data is hashed before the error is looked at.
+		if bytesRead > 0 {
+			for _, f := range feeders {
+				f.chunks <- buffer[:bytesRead]
+			}
+			outstanding++
+			next = (next + 1) % depth
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			return hashset, readErr
+		}
+	}
+	for outstanding > 0 {
+		if err = awaitOldest(); err != nil {
+			return hashset, err
+		}
+	}
+	stop()
+	sums := make([][][]byte, len(groups))
+	for index, f := range feeders {
+		sums[index] = <-f.result
+	}
+	hashset = make([][]byte, len(hashFunctions))
+	for index := range hashFunctions {
+		hashset[index] = sums[index%len(groups)][index/len(groups)]
 	}
 	return hashset, nil
 }
 
-// hashFeeder writes to hash each time it receives a ready signal, and sends
-// the final hash digest when readySignals closes. It is intended to be run
-// in a goroutine as a subroutine of FromReader, once per hash it is producing.
-func hashFeeder(
-	hash hash.Hash,
-	errorChannel chan error,
-	// When the buffer has been populated with new data, readySignals will
-	// receive the number of bytes that were written into it. When readySignals
-	// closes, reading has ended, and hashFeeder should return.
-	readySignals chan int,
-	returnChannel chan []byte,
-	// We use a pointer to a byte slice rather than a byte slice proper to
-	// avoid allocations when retrieving it from and returning it to a
-	// sync.Pool.
-	buffer *[]byte,
-) {
-	for bytesRead := range readySignals {
-		_, err := hash.Write((*buffer)[:bytesRead])
-		errorChannel <- err
+// groupHashes distributes hashes round-robin over at most workers groups, so
+// that asking for more hashes than there are CPUs to run them does not
+// oversubscribe the scheduler with one goroutine per hash. Hash i lands in
+// group i % len(groups), at position i / len(groups).
+func groupHashes(hashes []hash.Hash, workers int) [][]hash.Hash {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(hashes) {
+		workers = len(hashes)
+	}
+	groups := make([][]hash.Hash, workers)
+	for index, hash := range hashes {
+		groups[index%workers] = append(groups[index%workers], hash)
+	}
+	return groups
+}
+
+// feeder holds the channels between fromReader and one hashFeeder.
+type feeder struct {
+	// chunks receives each chunk read, in order, and is closed when reading
+	// has ended.
+	chunks chan []byte
+	// acks receives, for each chunk in order, the error writing it to the
+	// hashes, or nil, once the chunk's buffer is no longer in use.
+	acks chan error
+	// result receives the final digests once chunks is closed.
+	result chan [][]byte
+}
+
+// hashFeeder writes each chunk it receives to each of hashes, and sends the
+// final hash digests when the chunks channel closes. It is intended to be
+// run in a goroutine as a subroutine of FromReader, once per group of hashes
+// it is producing.
+func hashFeeder(hashes []hash.Hash, f feeder) {
+	for chunk := range f.chunks {
+		var err error
+		for _, hash := range hashes {
+			if _, err = hash.Write(chunk); err != nil {
+				break
+			}
+		}
+		f.acks <- err
+	}
+	sums := make([][]byte, len(hashes))
+	for index, hash := range hashes {
+		sums[index] = hash.Sum(nil)
 	}
-	returnChannel <- hash.Sum(nil)
+	f.result <- sums
 }
@@ -1,13 +1,22 @@
 package multihash
 
 import (
+	"bytes"
+	"context"
 	"crypto"
-	"log"
+	"errors"
+	"hash"
+	"hash/fnv"
+	"io"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
 
 	_ "crypto/md5"
 	_ "crypto/sha1"
 	_ "crypto/sha256"
+	_ "crypto/sha512"
 )
 
 func Test_fromFile(t *testing.T) {
@@ -32,6 +41,26 @@ func Test_fromFile(t *testing.T) {
 	}
 }
 
+func Test_fromReaderMoreHashesThanWorkers(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	data := bytes.Repeat([]byte("multihash"), 20000)
+	hashes := make([]hash.Hash, 7)
+	for i := range hashes {
+		hashes[i] = []crypto.Hash{crypto.MD5, crypto.SHA1, crypto.SHA256}[i%3].New()
+	}
+	m, err := FromReader(bytes.NewReader(data), hashes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, sum := range m {
+		expected := []crypto.Hash{crypto.MD5, crypto.SHA1, crypto.SHA256}[i%3].New()
+		expected.Write(data)
+		if !slicesEqual(sum, expected.Sum(nil)) {
+			t.Fatalf("digest %d was %x, expected %x\n", i, sum, expected.Sum(nil))
+		}
+	}
+}
+
 func Benchmark_fromFile(b *testing.B) {
 	filenames := []string{
 		"errors.go",
@@ -41,15 +70,15 @@ func Benchmark_fromFile(b *testing.B) {
 		"multihash_test.go",
 	}
 	for _, filename := range filenames {
-		hashes, err := FromFile(filename, crypto.SHA1.New(), crypto.MD5.New(), crypto.SHA256.New())
-		if err != nil {
-			log.Fatal(err)
-		}
-		for _, hash := range hashes {
-			log.Printf("%x\n", hash)
-		}
+		b.Run(filename, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := FromFile(filename, crypto.SHA1.New(), crypto.MD5.New(), crypto.SHA256.New())
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
 	}
-
 }
 
 func slicesEqual[T comparable](a, b []T) bool {
@@ -63,3 +92,143 @@ func slicesEqual[T comparable](a, b []T) bool {
 	}
 	return true
 }
+
+// dataWithEOFReader returns its final chunk of data together with io.EOF,
+// as io.Reader permits and archive/tar does.
+type dataWithEOFReader struct {
+	data []byte
+}
+
+func (r *dataWithEOFReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	if len(r.data) == 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func Test_fromReaderDataWithEOF(t *testing.T) {
+	m, err := FromReader(&dataWithEOFReader{data: []byte("Sample text file\n")}, crypto.MD5.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x55, 0x30, 0xde, 0x30, 0x71, 0xa1, 0xa9, 0x03, 0x54, 0x78, 0xde, 0xfc, 0xc1, 0xd5, 0x86, 0xe1}
+	if !slicesEqual(m[0], expected) {
+		t.Fatalf("MD5 was %x, expected %x\n", m[0], expected)
+	}
+}
+
+// failingReader returns data for a number of reads, then an error.
+type failingReader struct {
+	reads int
+}
+
+var errFailingReader = errors.New("read failed")
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.reads == 0 {
+		return 0, errFailingReader
+	}
+	r.reads--
+	return len(p), nil
+}
+
+func Test_fromReaderError(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		if _, err := FromReader(&failingReader{reads: i}, crypto.MD5.New(), crypto.SHA256.New()); err != errFailingReader {
+			t.Fatalf("expected the read error, got %v\n", err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("%d goroutines before, %d after failed reads\n", before, after)
+	}
+}
+
+// latentReader imitates a disk, taking a fixed time for every read.
+type latentReader struct {
+	remaining int
+	latency   time.Duration
+}
+
+func (r *latentReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.latency)
+	n := len(p)
+	if n > r.remaining {
+		n = r.remaining
+	}
+	r.remaining -= n
+	return n, nil
+}
+
+// Benchmark_pipeline compares FromReader's overlapping of reads and
+// hashing with waiting for each chunk to be hashed before the next read.
+func Benchmark_pipeline(b *testing.B) {
+	for _, depth := range []int{1, pipelineDepth} {
+		b.Run("depth"+strconv.Itoa(depth), func(b *testing.B) {
+			b.SetBytes(16 << 20)
+			for i := 0; i < b.N; i++ {
+				data := &latentReader{remaining: 16 << 20, latency: 50 * time.Microsecond}
+				if _, err := fromReader(context.Background(), data, depth, []hash.Hash{crypto.SHA512.New()}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Test_fromFileContextDeadline(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if _, err := FromFileContext(ctx, "testing/text1.txt", crypto.MD5.New(), crypto.SHA256.New()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v\n", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("%d goroutines before, %d after a cancelled hash\n", before, after)
+	}
+}
+
+// stutteringReader returns (0, nil) between every chunk of data, as
+// io.Reader permits though discourages.
+type stutteringReader struct {
+	data    []byte
+	stutter bool
+}
+
+func (r *stutteringReader) Read(p []byte) (int, error) {
+	if r.stutter = !r.stutter; r.stutter {
+		return 0, nil
+	}
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func Test_fromReaderZeroByteReads(t *testing.T) {
+	// Non-periodic data, so a chunk hashed from a refilled buffer changes
+	// the digest, and a pure-Go hash, so the race detector sees its reads.
+	data := make([]byte, 10*bufferSize+123)
+	for i := range data {
+		data[i] = byte(i*7 + i/bufferSize)
+	}
+	hashset, err := FromReader(&stutteringReader{data: data}, fnv.New64a(), fnv.New128a())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected64, expected128 := fnv.New64a(), fnv.New128a()
+	expected64.Write(data)
+	expected128.Write(data)
+	if !slicesEqual(hashset[0], expected64.Sum(nil)) || !slicesEqual(hashset[1], expected128.Sum(nil)) {
+		t.Fatalf("digests %x, expected %x and %x\n", hashset, expected64.Sum(nil), expected128.Sum(nil))
+	}
+}
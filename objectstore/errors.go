@@ -0,0 +1,7 @@
+package objectstore
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrUnknownSize = errcode.New(errcode.Unavailable, "object size not reported by server")
+
+var ErrNoOpen = errcode.New(errcode.InvalidArgument, "tree options have no Open function")
@@ -0,0 +1,141 @@
+// package objectstore exposes objects in cloud storage as io.ReaderAt values
+// backed by HTTP range requests, for hashing with multihash.FromReaderAt
+// without downloading them first.
+//
+// It speaks plain HTTP rather than any provider SDK, so it works with any
+// URL that honours Range headers, including S3 presigned URLs, public or
+// signed GCS URLs, and Azure blob SAS URLs. For private objects without a
+// presigned URL, supply an http.Client whose Transport signs requests.
+package objectstore
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/trytriangles/multihash"
+)
+
+// DefaultRetries is how many times a failed request is retried when an
+// Object's Retries is zero.
+const DefaultRetries = 3
+
+// Object is a remote object of known size, readable at arbitrary offsets.
+// It is safe for concurrent use; each ReadAt is an independent request.
+type Object struct {
+	URL  string
+	Size int64
+	// Client performs requests; http.DefaultClient if nil.
+	Client *http.Client
+	// Retries is how many times a request failing with a network error or
+	// a 5xx or 429 status is retried, with exponential backoff from 100ms.
+	// Negative disables retries.
+	Retries int
+}
+
+// Open issues a HEAD request for objectURL to learn its size.
+func Open(client *http.Client, objectURL string) (*Object, error) {
+	o := &Object{URL: objectURL, Client: client}
+	response, err := o.do(http.MethodHead, "")
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	if response.ContentLength < 0 {
+		return nil, ErrUnknownSize
+	}
+	o.Size = response.ContentLength
+	return o, nil
+}
+
+// ReadAt reads len(p) bytes from offset off with a single range request.
+func (o *Object) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= o.Size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > o.Size {
+		end = o.Size
+	}
+	response, err := o.do(http.MethodGet, fmt.Sprintf("bytes=%d-%d", off, end-1))
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusPartialContent {
+		return 0, multihash.HTTPStatusError{URL: o.URL, StatusCode: response.StatusCode}
+	}
+	n, err = io.ReadFull(response.Body, p[:end-off])
+	if err == nil && end == o.Size && int(end-off) < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Hash computes the object's digests under hashes, keeping parallel range
+// requests in flight.
+func (o *Object) Hash(parallel int, hashes ...hash.Hash) ([][]byte, error) {
+	return multihash.FromReaderAt(o, o.Size, parallel, hashes...)
+}
+
+func (o *Object) do(method, byteRange string) (*http.Response, error) {
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	retries := o.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequest(method, o.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if byteRange != "" {
+			request.Header.Set("Range", byteRange)
+		}
+		response, err := client.Do(request)
+		retryable := err != nil ||
+			response.StatusCode >= 500 ||
+			response.StatusCode == http.StatusTooManyRequests
+		if !retryable {
+			if response.StatusCode >= 400 {
+				response.Body.Close()
+				return nil, multihash.HTTPStatusError{URL: o.URL, StatusCode: response.StatusCode}
+			}
+			return response, nil
+		}
+		if attempt >= retries {
+			if err != nil {
+				return nil, err
+			}
+			response.Body.Close()
+			return nil, multihash.HTTPStatusError{URL: o.URL, StatusCode: response.StatusCode}
+		}
+		if response != nil {
+			response.Body.Close()
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// S3URL returns the virtual-hosted-style URL of an S3 object.
+func S3URL(bucket, region, key string) string {
+	return "https://" + bucket + ".s3." + region + ".amazonaws.com/" + escapeKey(key)
+}
+
+// GCSURL returns the XML API URL of a Google Cloud Storage object.
+func GCSURL(bucket, object string) string {
+	return "https://storage.googleapis.com/" + bucket + "/" + escapeKey(object)
+}
+
+// escapeKey percent-encodes an object key, keeping its slashes.
+func escapeKey(key string) string {
+	return (&url.URL{Path: key}).EscapedPath()
+}
@@ -0,0 +1,50 @@
+package objectstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Object(t *testing.T) {
+	data := bytes.Repeat([]byte("object body "), 300000)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail every third request once, to exercise retries.
+		if atomic.AddInt32(&requests, 1)%3 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "object", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	object, err := Open(server.Client(), server.URL+"/bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Size != int64(len(data)) {
+		t.Fatalf("size was %d, expected %d\n", object.Size, len(data))
+	}
+	hashset, err := object.Hash(3, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := sha256.Sum256(data)
+	if !bytes.Equal(hashset[0], expected[:]) {
+		t.Fatalf("digest was %x, expected %x\n", hashset[0], expected)
+	}
+}
+
+func Test_URLs(t *testing.T) {
+	if u := S3URL("bucket", "eu-west-1", "dir/a file.iso"); u != "https://bucket.s3.eu-west-1.amazonaws.com/dir/a%20file.iso" {
+		t.Fatalf("S3 URL was %s\n", u)
+	}
+	if u := GCSURL("bucket", "dir/file"); u != "https://storage.googleapis.com/bucket/dir/file" {
+		t.Fatalf("GCS URL was %s\n", u)
+	}
+}
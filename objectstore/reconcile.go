@@ -0,0 +1,245 @@
+package objectstore
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Checksum kinds, naming the provider value a Checksum carries.
+const (
+	S3ETag     = "s3-etag"
+	S3CRC32    = "s3-crc32"
+	S3CRC32C   = "s3-crc32c"
+	S3SHA1     = "s3-sha1"
+	S3SHA256   = "s3-sha256"
+	GCSCRC32C  = "gcs-crc32c"
+	GCSMD5     = "gcs-md5"
+	ContentMD5 = "content-md5"
+
+	// S3EncryptedETag is the ETag of an object encrypted with SSE-KMS or
+	// SSE-C, which is not a digest of the object's data.
+	S3EncryptedETag = "s3-etag-encrypted"
+)
+
+// multipartMark separates an S3 multipart digest from its part count.
+const multipartMark = "-"
+
+// Checksum is an integrity value reported by a storage provider.
+type Checksum struct {
+	Kind  string
+	Value string
+}
+
+// s3ChecksumHeaders are the x-amz-checksum-* headers, in the order
+// ParseHeaders reports them.
+var s3ChecksumHeaders = []struct{ kind, name string }{
+	{S3CRC32, "X-Amz-Checksum-Crc32"},
+	{S3CRC32C, "X-Amz-Checksum-Crc32c"},
+	{S3SHA1, "X-Amz-Checksum-Sha1"},
+	{S3SHA256, "X-Amz-Checksum-Sha256"},
+}
+
+// ParseHeaders extracts every checksum a response carries: the S3 ETag and
+// x-amz-checksum-* headers, GCS x-goog-hash values, and Content-MD5 (as
+// returned by Azure and others). The ETag of an object that
+// x-amz-server-side-encryption headers show to be encrypted with SSE-KMS or
+// SSE-C is of kind S3EncryptedETag.
+func ParseHeaders(header http.Header) []Checksum {
+	var checksums []Checksum
+	if etag := strings.Trim(header.Get("ETag"), `"`); etag != "" {
+		kind := S3ETag
+		if strings.HasPrefix(header.Get("X-Amz-Server-Side-Encryption"), "aws:kms") ||
+			header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+			kind = S3EncryptedETag
+		}
+		checksums = append(checksums, Checksum{kind, etag})
+	}
+	for _, s3 := range s3ChecksumHeaders {
+		if value := header.Get(s3.name); value != "" {
+			checksums = append(checksums, Checksum{s3.kind, value})
+		}
+	}
+	for _, line := range header.Values("X-Goog-Hash") {
+		for _, field := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch name {
+			case "crc32c":
+				checksums = append(checksums, Checksum{GCSCRC32C, value})
+			case "md5":
+				checksums = append(checksums, Checksum{GCSMD5, value})
+			}
+		}
+	}
+	if value := header.Get("Content-MD5"); value != "" {
+		checksums = append(checksums, Checksum{ContentMD5, value})
+	}
+	return checksums
+}
+
+// Reconciliation is the outcome of checking one provider checksum.
+type Reconciliation struct {
+	Checksum Checksum
+	// Verifiable is false when the value could not be recomputed locally,
+	// with Reason saying why; Match is then meaningless.
+	Verifiable bool
+	Match      bool
+	Reason     string
+}
+
+// Reconcile reads data once and checks it against each provider checksum.
+// Multipart values — S3 "<digest>-<parts>" ETags and checksums, which hash
+// the concatenated digests of each part — are recomputed from partSize, the
+// part size the object was uploaded with; with partSize zero they are
+// reported unverifiable, as is an S3EncryptedETag. So are they when the data
+// does not divide into as many parts of partSize as the value was made from,
+// since partSize is then wrong rather than the data.
+func Reconcile(data io.Reader, checksums []Checksum, partSize int64) ([]Reconciliation, error) {
+	results := make([]Reconciliation, len(checksums))
+	var hashes []hash.Hash
+	var pending []int
+	expected := make(map[int][]byte)
+	expectedParts := make(map[int]int)
+	for index, checksum := range checksums {
+		results[index].Checksum = checksum
+		h, digest, parts, ok := checker(checksum, partSize)
+		switch {
+		case checksum.Kind == S3EncryptedETag:
+			results[index].Reason = "ETag of an encrypted object is not a digest of its data"
+		case !ok:
+			results[index].Reason = "unrecognised checksum kind or encoding"
+		case parts > 0 && partSize <= 0:
+			results[index].Reason = "multipart value and part size unknown"
+		default:
+			results[index].Verifiable = true
+			hashes = append(hashes, h)
+			pending = append(pending, index)
+			expected[index] = digest
+			if parts > 0 {
+				expectedParts[index] = parts
+			}
+		}
+	}
+	digests, err := multihash.FromReader(data, hashes...)
+	if err != nil {
+		return nil, err
+	}
+	for position, index := range pending {
+		if parts, ok := expectedParts[index]; ok && hashes[position].(*compositeHash).count() != parts {
+			results[index].Verifiable = false
+			results[index].Reason = "part count does not match the part size"
+			continue
+		}
+		results[index].Match = bytes.Equal(digests[position], expected[index])
+	}
+	return results, nil
+}
+
+// checker decodes a checksum and returns a hash that will reproduce it. For
+// a multipart value, parts is the part count it records, and h is a
+// *compositeHash.
+func checker(checksum Checksum, partSize int64) (h hash.Hash, digest []byte, parts int, ok bool) {
+	value := checksum.Value
+	if base, count, found := strings.Cut(value, multipartMark); found &&
+		strings.HasPrefix(checksum.Kind, "s3-") {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return nil, nil, 0, false
+		}
+		value, parts = base, n
+	}
+	var newHash func() hash.Hash
+	decode := base64.StdEncoding.DecodeString
+	switch checksum.Kind {
+	case S3ETag:
+		newHash, decode = md5.New, hex.DecodeString
+	case GCSMD5, ContentMD5:
+		newHash = md5.New
+	case S3CRC32:
+		newHash = func() hash.Hash { return crc32.NewIEEE() }
+	case S3CRC32C, GCSCRC32C:
+		newHash = func() hash.Hash { return multihash.NewCRC32C() }
+	case S3SHA1:
+		newHash = sha1.New
+	case S3SHA256:
+		newHash = sha256.New
+	default:
+		return nil, nil, 0, false
+	}
+	digest, err := decode(value)
+	if err != nil {
+		return nil, nil, 0, false
+	}
+	if parts == 0 {
+		return newHash(), digest, 0, true
+	}
+	return &compositeHash{newHash: newHash, partSize: partSize, current: newHash()}, digest, parts, true
+}
+
+// compositeHash computes an S3-style multipart digest: the hash of the
+// concatenated hashes of each partSize part.
+type compositeHash struct {
+	newHash  func() hash.Hash
+	partSize int64
+	current  hash.Hash
+	filled   int64
+	parts    []byte
+}
+
+func (c *compositeHash) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		take := c.partSize - c.filled
+		if int64(len(p)) < take {
+			take = int64(len(p))
+		}
+		c.current.Write(p[:take])
+		c.filled += take
+		n += int(take)
+		p = p[take:]
+		if c.filled == c.partSize {
+			c.parts = c.current.Sum(c.parts)
+			c.current = c.newHash()
+			c.filled = 0
+		}
+	}
+	return n, nil
+}
+
+func (c *compositeHash) Sum(b []byte) []byte {
+	parts := c.parts
+	if c.filled > 0 {
+		parts = c.current.Sum(append([]byte(nil), parts...))
+	}
+	outer := c.newHash()
+	outer.Write(parts)
+	return outer.Sum(b)
+}
+
+// count returns the number of parts written so far, counting a partial
+// last part.
+func (c *compositeHash) count() int {
+	count := len(c.parts) / c.current.Size()
+	if c.filled > 0 {
+		count++
+	}
+	return count
+}
+
+func (c *compositeHash) Reset() {
+	c.current, c.filled, c.parts = c.newHash(), 0, nil
+}
+
+func (c *compositeHash) Size() int { return c.current.Size() }
+
+func (c *compositeHash) BlockSize() int { return c.current.BlockSize() }
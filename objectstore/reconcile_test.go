@@ -0,0 +1,95 @@
+package objectstore
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"testing"
+)
+
+func Test_Reconcile(t *testing.T) {
+	data := bytes.Repeat([]byte("multipart upload "), 1000000)
+	const partSize = 5 << 20
+
+	whole := md5.Sum(data)
+	var partSums []byte
+	for offset := 0; offset < len(data); offset += partSize {
+		end := offset + partSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := md5.Sum(data[offset:end])
+		partSums = append(partSums, sum[:]...)
+	}
+	composite := md5.Sum(partSums)
+	parts := (len(data) + partSize - 1) / partSize
+	crc := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))
+	sha := sha256.Sum256(data)
+
+	header := http.Header{}
+	header.Set("ETag", fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(composite[:]), parts))
+	header.Set("X-Amz-Checksum-Sha256", base64.StdEncoding.EncodeToString(sha[:]))
+	header.Add("X-Goog-Hash", "crc32c="+base64.StdEncoding.EncodeToString(binary.BigEndian.AppendUint32(nil, crc)))
+	header.Add("X-Goog-Hash", "md5="+base64.StdEncoding.EncodeToString(whole[:]))
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	checksums := ParseHeaders(header)
+	if len(checksums) != 5 {
+		t.Fatalf("parsed %d checksums: %+v\n", len(checksums), checksums)
+	}
+
+	results, err := Reconcile(bytes.NewReader(data), checksums, partSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		expectMatch := result.Checksum.Kind != ContentMD5
+		if !result.Verifiable || result.Match != expectMatch {
+			t.Fatalf("unexpected result %+v\n", result)
+		}
+	}
+
+	results, err = Reconcile(bytes.NewReader(data), checksums[:1], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Verifiable {
+		t.Fatal("multipart ETag reported verifiable without a part size")
+	}
+	results, err = Reconcile(bytes.NewReader(data), checksums[:1], 8<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Verifiable || results[0].Match {
+		t.Fatalf("multipart ETag with the wrong part size gave %+v\n", results[0])
+	}
+
+	header = http.Header{}
+	header.Set("ETag", `"`+hex.EncodeToString(make([]byte, 16))+`"`)
+	header.Set("X-Amz-Server-Side-Encryption", "aws:kms")
+	results, err = Reconcile(bytes.NewReader(data), ParseHeaders(header), partSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Checksum.Kind != S3EncryptedETag || results[0].Verifiable || results[0].Reason == "" {
+		t.Fatalf("SSE-KMS ETag gave %+v\n", results[0])
+	}
+}
+
+func Test_ParseHeadersOrder(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Amz-Checksum-Sha256", "c2hh")
+	header.Set("X-Amz-Checksum-Crc32c", "Y3Jj")
+	header.Set("X-Amz-Checksum-Sha1", "c2hh")
+	for run := 0; run < 10; run++ {
+		checksums := ParseHeaders(header)
+		if len(checksums) != 3 || checksums[0].Kind != S3CRC32C || checksums[1].Kind != S3SHA1 || checksums[2].Kind != S3SHA256 {
+			t.Fatalf("unexpected checksums %+v\n", checksums)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package objectstore
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// ObjectInfo describes one listed object.
+type ObjectInfo struct {
+	Key  string
+	Size int64
+}
+
+// Lister enumerates the objects under a prefix, calling fn for each in the
+// order the store returns them and stopping at the first error fn returns.
+type Lister interface {
+	List(prefix string, fn func(ObjectInfo) error) error
+}
+
+// TreeOptions configure HashTree.
+type TreeOptions struct {
+	// Open returns a reader for a listed object. It is required.
+	Open func(ObjectInfo) io.ReaderAt
+	// Workers bounds how many objects are hashed at once; 1 if zero.
+	Workers int
+	// Parallel is the number of range requests in flight per object; 1 if
+	// zero.
+	Parallel int
+}
+
+// HashTree lists the objects under prefix and hashes each with algorithms,
+// returning a manifest of their keys relative to prefix, in listing order.
+// It is the object store counterpart of hashing a local directory, and
+// produces the same manifest format, so local and remote copies of a tree
+// can be compared entry by entry. A prefix not ending in "/" names a
+// directory all the same: "backups" lists "backups/", not "backups-old/".
+// Keys ending in "/" are folder markers rather than files, and are skipped.
+// Without opts.Open it returns ErrNoOpen.
+func HashTree(lister Lister, prefix string, opts TreeOptions, algorithms ...string) (*manifest.Manifest, error) {
+	if opts.Open == nil {
+		return nil, ErrNoOpen
+	}
+	if _, err := multihash.NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	m := &manifest.Manifest{Algorithms: algorithms}
+	var (
+		lock     sync.Mutex
+		firstErr error
+		wait     sync.WaitGroup
+	)
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	slots := make(chan struct{}, workers)
+	err := lister.List(prefix, func(info ObjectInfo) error {
+		if !strings.HasPrefix(info.Key, prefix) || strings.HasSuffix(info.Key, "/") || info.Key == prefix {
+			return nil
+		}
+		relative := strings.TrimPrefix(info.Key, prefix)
+		lock.Lock()
+		if firstErr != nil {
+			lock.Unlock()
+			return firstErr
+		}
+		index := len(m.Entries)
+		m.Entries = append(m.Entries, manifest.Entry{Path: relative})
+		lock.Unlock()
+
+		slots <- struct{}{}
+		wait.Add(1)
+		go func() {
+			defer func() { <-slots; wait.Done() }()
+			hashes, _ := multihash.NewAll(algorithms...)
+			digests, err := multihash.FromReaderAt(opts.Open(info), info.Size, opts.Parallel, hashes...)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			m.Entries[index].Digests = digests
+		}()
+		return nil
+	})
+	wait.Wait()
+	if err == nil {
+		err = firstErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// S3Lister lists objects with the S3 ListObjectsV2 API, which many other
+// stores (GCS interoperability mode, MinIO, R2) also implement.
+type S3Lister struct {
+	// BucketURL is the bucket's endpoint, e.g. from S3URL(bucket, region, "").
+	BucketURL string
+	// Client performs requests, and should sign them for private buckets;
+	// http.DefaultClient if nil.
+	Client *http.Client
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key  string
+		Size int64
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List pages through the objects under prefix.
+func (l S3Lister) List(prefix string, fn func(ObjectInfo) error) error {
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		response, err := client.Get(strings.TrimSuffix(l.BucketURL, "/") + "/?" + query.Encode())
+		if err != nil {
+			return err
+		}
+		var page listBucketResult
+		if response.StatusCode != http.StatusOK {
+			err = multihash.HTTPStatusError{URL: l.BucketURL, StatusCode: response.StatusCode}
+		} else {
+			err = xml.NewDecoder(response.Body).Decode(&page)
+		}
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, object := range page.Contents {
+			if err = fn(ObjectInfo{Key: object.Key, Size: object.Size}); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
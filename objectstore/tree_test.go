@@ -0,0 +1,114 @@
+package objectstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_HashTree(t *testing.T) {
+	objects := map[string][]byte{
+		"backups/a.tar": bytes.Repeat([]byte("a"), 200000),
+		"backups/b.tar": []byte("b"),
+		"backups/c.tar": bytes.Repeat([]byte("c"), 3000000),
+	}
+	keys := []string{"backups/a.tar", "backups/b.tar", "backups/c.tar"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			// Serve the listing two objects per page.
+			start := 0
+			if token := r.URL.Query().Get("continuation-token"); token != "" {
+				fmt.Sscan(token, &start)
+			}
+			end := start + 2
+			if end > len(keys) {
+				end = len(keys)
+			}
+			fmt.Fprint(w, "<ListBucketResult>")
+			for _, key := range keys[start:end] {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size></Contents>", key, len(objects[key]))
+			}
+			if end < len(keys) {
+				fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", end)
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+			return
+		}
+		data, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	lister := S3Lister{BucketURL: server.URL, Client: server.Client()}
+	m, err := HashTree(lister, "backups/", TreeOptions{
+		Workers:  2,
+		Parallel: 2,
+		Open: func(info ObjectInfo) io.ReaderAt {
+			return &Object{URL: server.URL + "/" + info.Key, Size: info.Size, Client: server.Client()}
+		},
+	}, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v\n", m.Entries)
+	}
+	for _, key := range keys {
+		expected := sha256.Sum256(objects[key])
+		digest, ok := m.Digest(strings.TrimPrefix(key, "backups/"), "sha256")
+		if !ok || !bytes.Equal(digest, expected[:]) {
+			t.Fatalf("digest of %s was %x, expected %x\n", key, digest, expected)
+		}
+	}
+
+	if _, err = HashTree(lister, "backups/", TreeOptions{}, "sha256"); !errors.Is(err, ErrNoOpen) {
+		t.Fatalf("hashing without Open gave %v\n", err)
+	}
+}
+
+// prefixLister lists the objects whose keys start with the prefix, as
+// ListObjectsV2 does, with no regard for "/".
+type prefixLister []ObjectInfo
+
+func (l prefixLister) List(prefix string, fn func(ObjectInfo) error) error {
+	for _, info := range l {
+		if !strings.HasPrefix(info.Key, prefix) {
+			continue
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_HashTree_prefixWithoutSlash(t *testing.T) {
+	lister := prefixLister{
+		{Key: "backups", Size: 0},
+		{Key: "backups/", Size: 0},
+		{Key: "backups/a.tar", Size: 1},
+		{Key: "backups/old/", Size: 0},
+		{Key: "backups-old/x", Size: 1},
+		{Key: "backupsx", Size: 1},
+	}
+	m, err := HashTree(lister, "backups", TreeOptions{
+		Open: func(info ObjectInfo) io.ReaderAt { return bytes.NewReader([]byte("a")) },
+	}, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 1 || m.Entries[0].Path != "a.tar" {
+		t.Fatalf("unexpected entries %+v\n", m.Entries)
+	}
+}
@@ -0,0 +1,80 @@
+package ociimage
+
+import (
+	"strconv"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+var ErrMalformedImage = errcode.New(errcode.Malformed, "malformed image layout")
+var ErrUnsupportedImage = errcode.New(errcode.UnsupportedFormat, "unsupported image")
+var ErrImageNotFound = errcode.New(errcode.NotFound, "image not found")
+var ErrAmbiguousImage = errcode.New(errcode.Ambiguous, "more than one image")
+
+type MalformedImageError struct {
+	Reason string
+}
+
+func (e MalformedImageError) Error() string {
+	return "malformed image layout: " + e.Reason
+}
+
+func (e MalformedImageError) Is(target error) bool {
+	return target == ErrMalformedImage
+}
+
+func (e MalformedImageError) Code() errcode.Code {
+	return errcode.Malformed
+}
+
+type UnsupportedImageError struct {
+	Reason string
+}
+
+func (e UnsupportedImageError) Error() string {
+	return "unsupported image: " + e.Reason
+}
+
+func (e UnsupportedImageError) Is(target error) bool {
+	return target == ErrUnsupportedImage
+}
+
+func (e UnsupportedImageError) Code() errcode.Code {
+	return errcode.UnsupportedFormat
+}
+
+type ImageNotFoundError struct {
+	Tag string
+}
+
+func (e ImageNotFoundError) Error() string {
+	return "no image tagged " + strconv.Quote(e.Tag) + " in layout"
+}
+
+func (e ImageNotFoundError) Is(target error) bool {
+	return target == ErrImageNotFound
+}
+
+func (e ImageNotFoundError) Code() errcode.Code {
+	return errcode.NotFound
+}
+
+type AmbiguousImageError struct {
+	Tag    string
+	Images int
+}
+
+func (e AmbiguousImageError) Error() string {
+	if e.Tag == "" {
+		return "layout holds " + strconv.Itoa(e.Images) + " images; a tag is needed"
+	}
+	return "tag " + strconv.Quote(e.Tag) + " names an index of " + strconv.Itoa(e.Images) + " images"
+}
+
+func (e AmbiguousImageError) Is(target error) bool {
+	return target == ErrAmbiguousImage
+}
+
+func (e AmbiguousImageError) Code() errcode.Code {
+	return errcode.Ambiguous
+}
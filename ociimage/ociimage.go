@@ -0,0 +1,343 @@
+// package ociimage hashes the files of container images kept in the OCI
+// image layout, applying their layers in order as a container runtime would,
+// and compares two images file by file, so that a review can see exactly
+// which files changed between two tags rather than only that a layer did.
+package ociimage
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// RefNameAnnotation is the index annotation holding an image's tag.
+const RefNameAnnotation = "org.opencontainers.image.ref.name"
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type index struct {
+	Manifests []descriptor `json:"manifests"`
+}
+
+type imageManifest struct {
+	Layers []descriptor `json:"layers"`
+}
+
+func isIndex(mediaType string) bool {
+	return mediaType == "application/vnd.oci.image.index.v1+json" ||
+		mediaType == "application/vnd.docker.distribution.manifest.list.v2+json"
+}
+
+// Files returns a manifest of every regular file in the image tagged tag in
+// layout, an OCI image layout directory such as os.DirFS would give, hashed
+// with algorithms, or SHA-256 if none are given. Layers are applied bottom
+// to top, honouring whiteouts and opaque directories, so the manifest lists
+// the files of the image's final filesystem, sorted by path without a
+// leading slash. Hard links carry the digests of their target; symbolic
+// links, directories and devices are not listed.
+//
+// If tag is empty, the layout must hold a single image. A tag naming an
+// image index must resolve to a single image in it too; otherwise Files gives
+// an AmbiguousImageError. Every blob read is checked against the size and
+// digest its descriptor records, and a blob that does not match gives a
+// SizeMismatchError or DigestMismatchError. Layers may be uncompressed or
+// gzip-compressed; zstd layers give an UnsupportedImageError.
+func Files(layout fs.FS, tag string, algorithms ...string) (*manifest.Manifest, error) {
+	if len(algorithms) == 0 {
+		algorithms = []string{"sha256"}
+	}
+	if _, err := multihash.NewAll(algorithms...); err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(layout, "index.json")
+	if err != nil {
+		return nil, err
+	}
+	var top index
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, MalformedImageError{Reason: "index.json: " + err.Error()}
+	}
+	image, err := selectImage(layout, top, tag)
+	if err != nil {
+		return nil, err
+	}
+	var m imageManifest
+	if err := readJSON(layout, image, &m); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][][]byte)
+	for _, layer := range m.Layers {
+		if err := applyLayer(layout, layer, files, algorithms); err != nil {
+			return nil, err
+		}
+	}
+	result := &manifest.Manifest{Algorithms: append([]string(nil), algorithms...)}
+	for filePath, digests := range files {
+		result.Entries = append(result.Entries, manifest.Entry{Path: filePath, Digests: digests})
+	}
+	sort.Slice(result.Entries, func(i, j int) bool {
+		return result.Entries[i].Path < result.Entries[j].Path
+	})
+	return result, nil
+}
+
+func selectImage(layout fs.FS, top index, tag string) (descriptor, error) {
+	candidates := top.Manifests
+	if tag != "" {
+		candidates = nil
+		for _, d := range top.Manifests {
+			if d.Annotations[RefNameAnnotation] == tag {
+				candidates = append(candidates, d)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return descriptor{}, ImageNotFoundError{Tag: tag}
+	}
+	for {
+		if len(candidates) != 1 {
+			return descriptor{}, AmbiguousImageError{Tag: tag, Images: len(candidates)}
+		}
+		if !isIndex(candidates[0].MediaType) {
+			return candidates[0], nil
+		}
+		var nested index
+		if err := readJSON(layout, candidates[0], &nested); err != nil {
+			return descriptor{}, err
+		}
+		candidates = nested.Manifests
+	}
+}
+
+// openBlob opens the blob d describes, returning a reader that checks its
+// size and digest as it reaches the end.
+func openBlob(layout fs.FS, d descriptor) (io.Reader, io.Closer, error) {
+	if !strings.Contains(d.Digest, ":") {
+		return nil, nil, MalformedImageError{Reason: "descriptor digest " + d.Digest + " has no algorithm"}
+	}
+	algorithm, digest, err := multihash.ParseDigest(d.Digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, _ := multihash.New(algorithm)
+	f, err := layout.Open("blobs/" + algorithm + "/" + hex.EncodeToString(digest))
+	if err != nil {
+		return nil, nil, err
+	}
+	checked := multihash.NewSizeCheckingReader(f, d.Size)
+	return multihash.NewVerifyingReader(checked, h, digest), f, nil
+}
+
+func readJSON(layout fs.FS, d descriptor, v interface{}) error {
+	r, closer, err := openBlob(layout, d)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return MalformedImageError{Reason: "blob " + d.Digest + ": " + err.Error()}
+	}
+	return nil
+}
+
+// applyLayer hashes the regular files of layer and lays them over files.
+// Whiteouts only hide what lower layers hold, so they are collected while
+// reading and applied to files before this layer's own contents are added.
+func applyLayer(layout fs.FS, layer descriptor, files map[string][][]byte, algorithms []string) error {
+	blob, closer, err := openBlob(layout, layer)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	buffered := bufio.NewReader(blob)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	var contents io.Reader = buffered
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		contents = gz
+	case bytes.HasPrefix(magic, zstdMagic):
+		return UnsupportedImageError{Reason: "layer " + layer.Digest + " is zstd-compressed"}
+	}
+
+	added := make(map[string][][]byte)
+	// hidden holds paths whose lower contents, including everything below
+	// them, this layer hides; opaque holds directories that stay but lose
+	// their lower contents; replaced holds paths where this layer puts a
+	// directory, hiding only a lower non-directory at the same path.
+	hidden := make(map[string]bool)
+	opaque := make(map[string]bool)
+	replaced := make(map[string]bool)
+	archive := tar.NewReader(contents)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := manifest.MemberPath(header.Name)
+		if name == "" {
+			continue
+		}
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		switch {
+		case base == opaqueWhiteout:
+			opaque[dir] = true
+		case strings.HasPrefix(base, whiteoutPrefix):
+			hidden[path.Join(dir, base[len(whiteoutPrefix):])] = true
+		case header.Typeflag == tar.TypeDir:
+			replaced[name] = true
+		case header.Typeflag == tar.TypeReg:
+			hashes, _ := multihash.NewAll(algorithms...)
+			digests, err := multihash.FromReader(archive, hashes...)
+			if err != nil {
+				return err
+			}
+			added[name] = digests
+			hidden[name] = true
+		case header.Typeflag == tar.TypeLink:
+			target := manifest.MemberPath(header.Linkname)
+			if digests, ok := added[target]; ok {
+				added[name] = digests
+			} else if digests, ok := files[target]; ok {
+				added[name] = digests
+			}
+			hidden[name] = true
+		default:
+			hidden[name] = true
+		}
+	}
+	// Read to the end, so that the gzip checksum and the blob's own digest
+	// are checked even though the tar archive ended earlier.
+	if _, err := io.Copy(io.Discard, contents); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.Discard, buffered); err != nil {
+		return err
+	}
+
+	for filePath := range files {
+		if replaced[filePath] || hiddenBy(filePath, hidden, opaque) {
+			delete(files, filePath)
+		}
+	}
+	for filePath, digests := range added {
+		files[filePath] = digests
+	}
+	return nil
+}
+
+// hiddenBy reports whether filePath is hidden or lies below a hidden path
+// or an opaque directory.
+func hiddenBy(filePath string, hidden, opaque map[string]bool) bool {
+	if hidden[filePath] {
+		return true
+	}
+	for dir := filePath; dir != ""; {
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+		if hidden[dir] || opaque[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes lists the paths that differ between two manifests, each sorted.
+type Changes struct {
+	// Added files are only in the newer manifest.
+	Added []string
+	// Removed files are only in the older manifest.
+	Removed []string
+	// Changed files are in both but disagree on a digest under an algorithm
+	// both record.
+	Changed []string
+}
+
+// Compare reports which files differ between before and after, typically
+// the results of Files for two tags of the same image.
+func Compare(before, after *manifest.Manifest) Changes {
+	var changes Changes
+	earlier := make(map[string]manifest.Entry, len(before.Entries))
+	for _, entry := range before.Entries {
+		earlier[entry.Path] = entry
+	}
+	for _, entry := range after.Entries {
+		previous, ok := earlier[entry.Path]
+		if !ok {
+			changes.Added = append(changes.Added, entry.Path)
+			continue
+		}
+		delete(earlier, entry.Path)
+		for index, algorithm := range after.Algorithms {
+			earlierIndex := before.AlgorithmIndex(algorithm)
+			if earlierIndex < 0 || previous.Digests[earlierIndex] == nil || entry.Digests[index] == nil {
+				continue
+			}
+			if !bytes.Equal(previous.Digests[earlierIndex], entry.Digests[index]) {
+				changes.Changed = append(changes.Changed, entry.Path)
+				break
+			}
+		}
+	}
+	for filePath := range earlier {
+		changes.Removed = append(changes.Removed, filePath)
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+	return changes
+}
+
+// Diff hashes the images tagged beforeTag and afterTag, each in its own
+// layout, and compares their files. The layouts may be the same.
+func Diff(beforeLayout fs.FS, beforeTag string, afterLayout fs.FS, afterTag string, algorithms ...string) (Changes, error) {
+	before, err := Files(beforeLayout, beforeTag, algorithms...)
+	if err != nil {
+		return Changes{}, err
+	}
+	after, err := Files(afterLayout, afterTag, algorithms...)
+	if err != nil {
+		return Changes{}, err
+	}
+	return Compare(before, after), nil
+}
@@ -0,0 +1,140 @@
+package ociimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/trytriangles/multihash"
+)
+
+type member struct {
+	name     string
+	contents string
+	link     string
+}
+
+func layerTar(members ...member) []byte {
+	var archive bytes.Buffer
+	w := tar.NewWriter(&archive)
+	for _, m := range members {
+		header := &tar.Header{Name: m.name, Mode: 0o644, Size: int64(len(m.contents)), Typeflag: tar.TypeReg}
+		if m.link != "" {
+			header = &tar.Header{Name: m.name, Mode: 0o644, Linkname: m.link, Typeflag: tar.TypeLink}
+		}
+		w.WriteHeader(header)
+		w.Write([]byte(m.contents))
+	}
+	w.Close()
+	return archive.Bytes()
+}
+
+func addBlob(layout fstest.MapFS, mediaType string, data []byte) descriptor {
+	sum := sha256.Sum256(data)
+	layout["blobs/sha256/"+hex.EncodeToString(sum[:])] = &fstest.MapFile{Data: data}
+	return descriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+}
+
+func addImage(layout fstest.MapFS, top *index, tag string, layers ...descriptor) {
+	data, _ := json.Marshal(imageManifest{Layers: layers})
+	d := addBlob(layout, "application/vnd.oci.image.manifest.v1+json", data)
+	d.Annotations = map[string]string{RefNameAnnotation: tag}
+	top.Manifests = append(top.Manifests, d)
+}
+
+func testLayout() fstest.MapFS {
+	layout := fstest.MapFS{}
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(layerTar(
+		member{name: "etc/config", contents: "setting=1"},
+		member{name: "./bin/app", contents: "version one"},
+		member{name: "usr/share/doc/readme", contents: "docs"},
+		member{name: "var/cache/state", contents: "cached"},
+	))
+	gz.Close()
+	base := addBlob(layout, "application/vnd.oci.image.layer.v1.tar+gzip", compressed.Bytes())
+	update := addBlob(layout, "application/vnd.oci.image.layer.v1.tar", layerTar(
+		member{name: "bin/app", contents: "version two"},
+		member{name: "bin/app2", link: "bin/app"},
+		member{name: "etc/.wh.config"},
+		member{name: "usr/share/.wh..wh..opq"},
+		member{name: "usr/share/new", contents: "new file"},
+	))
+	var top index
+	addImage(layout, &top, "v1", base)
+	addImage(layout, &top, "v2", base, update)
+	data, _ := json.Marshal(top)
+	layout["index.json"] = &fstest.MapFile{Data: data}
+	return layout
+}
+
+func Test_Files(t *testing.T) {
+	m, err := Files(testLayout(), "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, entry := range m.Entries {
+		paths = append(paths, entry.Path)
+	}
+	expected := []string{"bin/app", "bin/app2", "usr/share/new", "var/cache/state"}
+	if !stringsEqual(paths, expected) {
+		t.Fatalf("files %q, expected %q\n", paths, expected)
+	}
+	sum := sha256.Sum256([]byte("version two"))
+	for _, name := range []string{"bin/app", "bin/app2"} {
+		if digest, _ := m.Digest(name, "sha256"); !bytes.Equal(digest, sum[:]) {
+			t.Fatalf("%s has digest %x, expected %x\n", name, digest, sum)
+		}
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	layout := testLayout()
+	changes, err := Diff(layout, "v1", layout, "v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stringsEqual(changes.Added, []string{"bin/app2", "usr/share/new"}) ||
+		!stringsEqual(changes.Removed, []string{"etc/config", "usr/share/doc/readme"}) ||
+		!stringsEqual(changes.Changed, []string{"bin/app"}) {
+		t.Fatalf("unexpected changes %+v\n", changes)
+	}
+}
+
+func Test_FilesErrors(t *testing.T) {
+	layout := testLayout()
+	if _, err := Files(layout, "v3"); !errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("unknown tag gave %v\n", err)
+	}
+	if _, err := Files(layout, ""); !errors.Is(err, ErrAmbiguousImage) {
+		t.Fatalf("untagged lookup of two images gave %v\n", err)
+	}
+	for _, file := range layout {
+		if at := bytes.Index(file.Data, []byte("new file")); at >= 0 {
+			file.Data[at] = 'N'
+		}
+	}
+	if _, err := Files(layout, "v2"); !errors.Is(err, multihash.ErrDigestMismatch) {
+		t.Fatalf("a corrupt layer gave %v\n", err)
+	}
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package multihash
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// FromReaders hashes the logical concatenation of rs while also computing
+// each segment's own digests, in a single read of each. Results are in the
+// same order as the arguments; that is, if one calls
+//
+//	whole, segments, err := FromReaders(chunks, md5.New, sha256.New)
+//
+// whole[1] will be the SHA256 digest of all chunks together and
+// segments[i][0] the MD5 digest of chunks[i] alone.
+func FromReaders(rs []io.Reader, hashes ...func() hash.Hash) (whole [][]byte, segments [][][]byte, err error) {
+	return hashParts(rs, hashes)
+}
+
+// VerifyParts hashes parts as one logical stream, as with the volumes of a
+// split archive or the chunks of an upload, computing every algorithm both
+// over the whole and over each part separately. Hash state carries over from
+// one part to the next, so each byte is read only once.
+//
+// expectedWhole and expectedParts hold the digests to check, indexed like
+// hashes; expectedParts[i] is for parts[i]. Either may be nil, as may any of
+// their elements, to skip that check. The first mismatch is returned as a
+// PartMismatchError, together with all the digests that were computed.
+// Expecting digests for more parts than given, or more digests than hashes,
+// is a DigestCountError, before anything is read.
+func VerifyParts(
+	parts []io.Reader,
+	expectedWhole [][]byte,
+	expectedParts [][][]byte,
+	hashes ...func() hash.Hash,
+) (whole [][]byte, perPart [][][]byte, err error) {
+	if len(expectedParts) > len(parts) {
+		return nil, nil, DigestCountError{Digests: len(expectedParts), Hashes: len(parts)}
+	}
+	if len(expectedWhole) > len(hashes) {
+		return nil, nil, DigestCountError{Digests: len(expectedWhole), Hashes: len(hashes)}
+	}
+	for _, expected := range expectedParts {
+		if len(expected) > len(hashes) {
+			return nil, nil, DigestCountError{Digests: len(expected), Hashes: len(hashes)}
+		}
+	}
+	whole, perPart, err = hashParts(parts, hashes)
+	if err != nil {
+		return whole, perPart, err
+	}
+	for part, expected := range expectedParts {
+		if err = compareDigests(part, expected, perPart[part]); err != nil {
+			return whole, perPart, err
+		}
+	}
+	return whole, perPart, compareDigests(WholeStream, expectedWhole, whole)
+}
+
+// WholeStream is the Part of a PartMismatchError concerning the digests of
+// the logical stream formed by all parts rather than of any single one.
+const WholeStream = -1
+
+func compareDigests(part int, expected, actual [][]byte) error {
+	for index, digest := range expected {
+		if digest == nil {
+			continue
+		}
+		if !bytes.Equal(digest, actual[index]) {
+			return PartMismatchError{Part: part, Hash: index, Expected: digest, Actual: actual[index]}
+		}
+	}
+	return nil
+}
+
+// hashParts computes, for each of hashes, a digest of all of parts in
+// sequence and one of each part on its own. Per-part hashes are fresh for
+// each part; whole-stream hashes are shared across the FromReader calls,
+// relying on Sum not disturbing their state.
+func hashParts(parts []io.Reader, hashes []func() hash.Hash) (whole [][]byte, perPart [][][]byte, err error) {
+	wholeHashes := make([]hash.Hash, len(hashes))
+	for index, newHash := range hashes {
+		wholeHashes[index] = newHash()
+	}
+	perPart = make([][][]byte, 0, len(parts))
+	for _, part := range parts {
+		partHashes := make([]hash.Hash, len(hashes), 2*len(hashes))
+		for index, newHash := range hashes {
+			partHashes[index] = newHash()
+		}
+		hashset, err := FromReader(part, append(partHashes, wholeHashes...)...)
+		if err != nil {
+			return nil, perPart, err
+		}
+		perPart = append(perPart, hashset[:len(hashes)])
+	}
+	whole = make([][]byte, len(hashes))
+	for index, h := range wholeHashes {
+		whole[index] = h.Sum(nil)
+	}
+	return whole, perPart, nil
+}
@@ -0,0 +1,75 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"testing"
+)
+
+func Test_VerifyParts(t *testing.T) {
+	volumes := [][]byte{
+		bytes.Repeat([]byte("first volume "), 9000),
+		[]byte("second volume"),
+		bytes.Repeat([]byte("third volume "), 7000),
+	}
+	readers := func() []io.Reader {
+		rs := make([]io.Reader, len(volumes))
+		for i, v := range volumes {
+			rs[i] = bytes.NewReader(v)
+		}
+		return rs
+	}
+	wholeSum := sha256.Sum256(bytes.Join(volumes, nil))
+	middleSum := md5.Sum(volumes[1])
+
+	whole, perPart, err := VerifyParts(
+		readers(),
+		[][]byte{nil, wholeSum[:]},
+		[][][]byte{nil, {middleSum[:]}},
+		[]func() hash.Hash{md5.New, sha256.New}...,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perPart) != 3 || !slicesEqual(whole[1], wholeSum[:]) {
+		t.Fatalf("unexpected digests %x, %x\n", whole, perPart)
+	}
+	for i, v := range volumes {
+		sum := sha256.Sum256(v)
+		if !slicesEqual(perPart[i][1], sum[:]) {
+			t.Fatalf("part %d digest was %x, expected %x\n", i, perPart[i][1], sum)
+		}
+	}
+
+	_, _, err = VerifyParts(readers(), nil, [][][]byte{nil, nil, {middleSum[:]}}, md5.New)
+	var mismatch PartMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Part != 2 || !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected part 2 to mismatch, got %v\n", err)
+	}
+
+	for _, expectedParts := range [][][][]byte{{nil, nil, nil, nil}, {{nil, nil}}} {
+		if _, _, err = VerifyParts(readers(), nil, expectedParts, md5.New); !errors.Is(err, ErrDigestCount) {
+			t.Fatalf("expecting %d parts gave %v\n", len(expectedParts), err)
+		}
+	}
+}
+
+func Test_FromReaders(t *testing.T) {
+	segments := []io.Reader{bytes.NewReader([]byte("Sample ")), bytes.NewReader([]byte("text file\n"))}
+	whole, perSegment, err := FromReaders(segments, md5.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x55, 0x30, 0xde, 0x30, 0x71, 0xa1, 0xa9, 0x03, 0x54, 0x78, 0xde, 0xfc, 0xc1, 0xd5, 0x86, 0xe1}
+	if !slicesEqual(whole[0], expected) {
+		t.Fatalf("MD5 of the concatenation was %x, expected %x\n", whole[0], expected)
+	}
+	first := md5.Sum([]byte("Sample "))
+	if !slicesEqual(perSegment[0][0], first[:]) {
+		t.Fatalf("MD5 of the first segment was %x, expected %x\n", perSegment[0][0], first)
+	}
+}
@@ -0,0 +1,23 @@
+package payload
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrUnrecognizedFormat = errcode.New(errcode.UnsupportedFormat, "unrecognized media format")
+var ErrMalformedFile = errcode.New(errcode.Malformed, "malformed media file")
+
+type MalformedFileError struct {
+	Format string
+	Reason string
+}
+
+func (e MalformedFileError) Error() string {
+	return "malformed " + e.Format + " file: " + e.Reason
+}
+
+func (e MalformedFileError) Is(target error) bool {
+	return target == ErrMalformedFile
+}
+
+func (e MalformedFileError) Code() errcode.Code {
+	return errcode.Malformed
+}
@@ -0,0 +1,113 @@
+package payload
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// jpegAnalyzer keeps every marker segment except the application (APP0 to
+// APP15, holding JFIF, EXIF, XMP and ICC data) and comment segments, and
+// the entropy-coded scan data, up to the end-of-image marker. Anything
+// appended after it is skipped.
+var jpegAnalyzer = Analyzer{
+	Name: "jpeg",
+	Match: func(header []byte) bool {
+		return hasPrefix(header, "\xff\xd8\xff")
+	},
+	Copy: copyJPEG,
+}
+
+func copyJPEG(w io.Writer, r *bufio.Reader) error {
+	if _, err := r.Discard(2); err != nil {
+		return err
+	}
+	w.Write([]byte{0xff, 0xd8})
+	marker, err := nextMarker(r)
+	for {
+		if err != nil {
+			return err
+		}
+		switch {
+		case marker == 0xd9:
+			w.Write([]byte{0xff, marker})
+			return nil
+		case marker >= 0xd0 && marker <= 0xd7 || marker == 0x01:
+			// Restart and TEM markers have no length.
+			w.Write([]byte{0xff, marker})
+			marker, err = nextMarker(r)
+			continue
+		}
+		var length [2]byte
+		if err = readFull(r, length[:], "jpeg"); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint16(length[:]))
+		if size < 2 {
+			return MalformedFileError{Format: "jpeg", Reason: "bad segment length"}
+		}
+		if marker >= 0xe0 && marker <= 0xef || marker == 0xfe {
+			if err = copyN(io.Discard, r, size-2, "jpeg"); err != nil {
+				return err
+			}
+			marker, err = nextMarker(r)
+			continue
+		}
+		w.Write([]byte{0xff, marker})
+		w.Write(length[:])
+		if err = copyN(w, r, size-2, "jpeg"); err != nil {
+			return err
+		}
+		if marker == 0xda {
+			marker, err = copyScan(w, r)
+		} else {
+			marker, err = nextMarker(r)
+		}
+	}
+}
+
+// nextMarker reads a marker, skipping fill bytes.
+func nextMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, MalformedFileError{Format: "jpeg", Reason: "truncated"}
+	}
+	if b != 0xff {
+		return 0, MalformedFileError{Format: "jpeg", Reason: "expected a marker"}
+	}
+	for b == 0xff {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, MalformedFileError{Format: "jpeg", Reason: "truncated"}
+		}
+	}
+	return b, nil
+}
+
+// copyScan copies entropy-coded data, in which 0xff is followed by a
+// stuffed 0x00 or a restart marker, until the marker that ends it, which it
+// returns.
+func copyScan(w io.Writer, r *bufio.Reader) (byte, error) {
+	bw := bufio.NewWriter(w)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, MalformedFileError{Format: "jpeg", Reason: "truncated scan"}
+		}
+		if b != 0xff {
+			bw.WriteByte(b)
+			continue
+		}
+		next, err := r.ReadByte()
+		for err == nil && next == 0xff {
+			next, err = r.ReadByte()
+		}
+		if err != nil {
+			return 0, MalformedFileError{Format: "jpeg", Reason: "truncated scan"}
+		}
+		if next == 0x00 || next >= 0xd0 && next <= 0xd7 {
+			bw.Write([]byte{0xff, next})
+			continue
+		}
+		return next, bw.Flush()
+	}
+}
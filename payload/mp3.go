@@ -0,0 +1,65 @@
+package payload
+
+import (
+	"bufio"
+	"io"
+)
+
+// id3v1Size is the size of the ID3v1 tag some files end with.
+const id3v1Size = 128
+
+// mp3Analyzer keeps everything between a leading ID3v2 tag and a trailing
+// ID3v1 tag, which for a typical file is exactly its audio frames. Other
+// tag formats, such as APEv2, are not recognized and stay in the content.
+var mp3Analyzer = Analyzer{
+	Name: "mp3",
+	Match: func(header []byte) bool {
+		return hasPrefix(header, "ID3") || len(header) >= 2 && header[0] == 0xff && header[1]&0xe0 == 0xe0
+	},
+	Copy: copyMP3,
+}
+
+func copyMP3(w io.Writer, r *bufio.Reader) error {
+	for {
+		header, _ := r.Peek(10)
+		if !hasPrefix(header, "ID3") {
+			break
+		}
+		if len(header) < 10 {
+			return MalformedFileError{Format: "mp3", Reason: "truncated ID3v2 tag"}
+		}
+		// The size is syncsafe: 7 bits in each of four bytes.
+		size := int64(header[6]&0x7f)<<21 | int64(header[7]&0x7f)<<14 | int64(header[8]&0x7f)<<7 | int64(header[9]&0x7f)
+		size += 10
+		if header[5]&0x10 != 0 {
+			size += 10
+		}
+		if err := copyN(io.Discard, r, size, "mp3"); err != nil {
+			return err
+		}
+	}
+
+	// Hold back the last id3v1Size bytes until the end is known.
+	buf := make([]byte, 64*1024+id3v1Size)
+	held := 0
+	for {
+		n, err := r.Read(buf[held:])
+		held += n
+		if held > id3v1Size {
+			w.Write(buf[:held-id3v1Size])
+			copy(buf, buf[held-id3v1Size:held])
+			held = id3v1Size
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if held == id3v1Size && hasPrefix(buf, "TAG") {
+		return nil
+	}
+	w.Write(buf[:held])
+	return nil
+}
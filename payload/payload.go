@@ -0,0 +1,101 @@
+// package payload hashes only the content of media files, skipping their
+// metadata, so that copies differing only in EXIF data, text chunks or
+// ID3 tags are recognized as duplicates: for PNG the critical chunks, for
+// JPEG everything but the application and comment segments, and for MP3
+// the audio between its ID3 tags.
+//
+// Each format is an Analyzer, which copies a file's content to a writer;
+// further formats can be registered with Register.
+package payload
+
+import (
+	"bufio"
+	"bytes"
+	"hash"
+	"io"
+	"sync"
+)
+
+// An Analyzer recognizes a format from the start of a file and copies the
+// content of a file in it to w.
+type Analyzer struct {
+	// Name identifies the format, e.g. "png".
+	Name string
+	// Match reports whether a file beginning with header is in the format.
+	// header holds up to 16 bytes.
+	Match func(header []byte) bool
+	// Copy writes the content of the file read from r to w.
+	Copy func(w io.Writer, r *bufio.Reader) error
+}
+
+var (
+	analyzersLock sync.RWMutex
+	analyzers     = []Analyzer{pngAnalyzer, jpegAnalyzer, mp3Analyzer}
+)
+
+// Register adds an analyzer, which takes precedence over those registered
+// before it.
+func Register(a Analyzer) {
+	analyzersLock.Lock()
+	defer analyzersLock.Unlock()
+	analyzers = append([]Analyzer{a}, analyzers...)
+}
+
+// FromReader detects the format of the file read from r and hashes its
+// content with each of hashes, returning the digests in the same order. A
+// file in no registered format gives ErrUnrecognizedFormat.
+func FromReader(r io.Reader, hashes ...hash.Hash) (format string, digests [][]byte, err error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(16)
+	analyzersLock.RLock()
+	var analyzer *Analyzer
+	for index := range analyzers {
+		if analyzers[index].Match(header) {
+			analyzer = &analyzers[index]
+			break
+		}
+	}
+	analyzersLock.RUnlock()
+	if analyzer == nil {
+		return "", nil, ErrUnrecognizedFormat
+	}
+
+	writers := make([]io.Writer, len(hashes))
+	for index, h := range hashes {
+		writers[index] = h
+	}
+	if err = analyzer.Copy(io.MultiWriter(writers...), br); err != nil {
+		return analyzer.Name, nil, err
+	}
+	digests = make([][]byte, len(hashes))
+	for index, h := range hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return analyzer.Name, digests, nil
+}
+
+// readFull reads exactly len(p) bytes, reporting a short file as malformed.
+func readFull(r io.Reader, p []byte, format string) error {
+	if _, err := io.ReadFull(r, p); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return MalformedFileError{Format: format, Reason: "truncated"}
+		}
+		return err
+	}
+	return nil
+}
+
+// copyN copies exactly n bytes, reporting a short file as malformed.
+func copyN(w io.Writer, r io.Reader, n int64, format string) error {
+	if _, err := io.CopyN(w, r, n); err != nil {
+		if err == io.EOF {
+			return MalformedFileError{Format: format, Reason: "truncated"}
+		}
+		return err
+	}
+	return nil
+}
+
+func hasPrefix(header []byte, prefix string) bool {
+	return bytes.HasPrefix(header, []byte(prefix))
+}
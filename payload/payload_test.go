@@ -0,0 +1,101 @@
+package payload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(shade uint8) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for x := 0; x < 32; x++ {
+		for y := 0; y < 32; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 8), uint8(y * 8), shade, 255})
+		}
+	}
+	return img
+}
+
+func digestOf(t *testing.T, data []byte, format string) []byte {
+	t.Helper()
+	detected, digests, err := FromReader(bytes.NewReader(data), sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detected != format {
+		t.Fatalf("detected %s, expected %s\n", detected, format)
+	}
+	return digests[0]
+}
+
+func Test_PNG(t *testing.T) {
+	var plain bytes.Buffer
+	png.Encode(&plain, testImage(0))
+
+	// Insert a tEXt chunk after IHDR, which ends 33 bytes in.
+	text := []byte("tEXtComment\x00edited by hand")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	tagged := append(append(append([]byte(nil), plain.Bytes()[:33]...), chunk...), plain.Bytes()[33:]...)
+
+	if !bytes.Equal(digestOf(t, plain.Bytes(), "png"), digestOf(t, tagged, "png")) {
+		t.Fatal("a text chunk changed the content digest")
+	}
+	var other bytes.Buffer
+	png.Encode(&other, testImage(1))
+	if bytes.Equal(digestOf(t, plain.Bytes(), "png"), digestOf(t, other.Bytes(), "png")) {
+		t.Fatal("different pixels gave the same content digest")
+	}
+	if _, _, err := FromReader(bytes.NewReader(plain.Bytes()[:40])); !errors.Is(err, ErrMalformedFile) {
+		t.Fatalf("expected a truncated file to be malformed, got %v\n", err)
+	}
+}
+
+func Test_JPEG(t *testing.T) {
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, testImage(0), nil)
+
+	exif := []byte("\xff\xe1\x00\x10Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08")
+	comment := []byte("\xff\xfe\x00\x07hello")
+	tagged := append(append(append([]byte(nil), plain.Bytes()[:2]...), exif...), plain.Bytes()[2:]...)
+	tagged = append(tagged[:len(tagged)-2], append(comment, 0xff, 0xd9)...)
+	tagged = append(tagged, "trailing junk"...)
+
+	if !bytes.Equal(digestOf(t, plain.Bytes(), "jpeg"), digestOf(t, tagged, "jpeg")) {
+		t.Fatal("metadata segments changed the content digest")
+	}
+	var other bytes.Buffer
+	jpeg.Encode(&other, testImage(128), nil)
+	if bytes.Equal(digestOf(t, plain.Bytes(), "jpeg"), digestOf(t, other.Bytes(), "jpeg")) {
+		t.Fatal("different pixels gave the same content digest")
+	}
+}
+
+func Test_MP3(t *testing.T) {
+	frames := bytes.Repeat([]byte("\xff\xfb\x90\x64audio frame data"), 5000)
+	id3v2 := append([]byte("ID3\x04\x00\x00\x00\x00\x00\x0a"), "TIT2 title"...)
+	id3v1 := append([]byte("TAG"), bytes.Repeat([]byte{' '}, id3v1Size-3)...)
+	tagged := append(append(append([]byte(nil), id3v2...), frames...), id3v1...)
+
+	if !bytes.Equal(digestOf(t, frames, "mp3"), digestOf(t, tagged, "mp3")) {
+		t.Fatal("ID3 tags changed the content digest")
+	}
+	expected := sha256.Sum256(frames)
+	if !bytes.Equal(digestOf(t, frames, "mp3"), expected[:]) {
+		t.Fatal("untagged audio was altered")
+	}
+}
+
+func Test_Unrecognized(t *testing.T) {
+	if _, _, err := FromReader(bytes.NewReader([]byte("plain text")), sha256.New()); !errors.Is(err, ErrUnrecognizedFormat) {
+		t.Fatalf("expected ErrUnrecognizedFormat, got %v\n", err)
+	}
+}
@@ -0,0 +1,50 @@
+package payload
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// pngAnalyzer keeps the type and data of the critical chunks, those whose
+// type begins with an upper-case letter: IHDR, PLTE, IDAT and IEND.
+// Ancillary chunks, such as text, EXIF, timestamps and color profiles, are
+// skipped, as are the chunks' CRCs. The pixels are still hashed in their
+// compressed form, so re-encoding an image changes its digest.
+var pngAnalyzer = Analyzer{
+	Name: "png",
+	Match: func(header []byte) bool {
+		return hasPrefix(header, pngSignature)
+	},
+	Copy: copyPNG,
+}
+
+func copyPNG(w io.Writer, r *bufio.Reader) error {
+	if _, err := r.Discard(len(pngSignature)); err != nil {
+		return err
+	}
+	var header [8]byte
+	for {
+		if err := readFull(r, header[:], "png"); err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:])
+		if header[4]&0x20 == 0 {
+			w.Write(header[4:])
+			if err := copyN(w, r, length, "png"); err != nil {
+				return err
+			}
+		} else if err := copyN(io.Discard, r, length, "png"); err != nil {
+			return err
+		}
+		if err := copyN(io.Discard, r, 4, "png"); err != nil {
+			return err
+		}
+		if chunkType == "IEND" {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// package phash computes perceptual hashes of images: 64-bit fingerprints
+// that stay close, as counted by Distance, when an image is resized,
+// recompressed or slightly edited, unlike a cryptographic digest. They let
+// deduplication find near-duplicates alongside the exact copies that
+// multihash's digests find.
+//
+// PNG, JPEG and GIF images are decoded; other formats can be added by
+// registering them with the image package.
+package phash
+
+import (
+	"hash"
+	"image"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Hashes holds an image's perceptual hashes.
+type Hashes struct {
+	// Average is the aHash: each bit of an 8x8 grayscale thumbnail set if
+	// the pixel is brighter than the mean. It is fast and tolerant of
+	// scaling, but is thrown by changes in overall brightness or contrast.
+	Average uint64
+	// Difference is the dHash: for each row of a 9x8 thumbnail, a bit for
+	// each pixel brighter than its right neighbour. It follows gradients,
+	// so it tolerates brightness changes.
+	Difference uint64
+	// Perceptual is the pHash: each bit of the lowest 8x8 frequencies of a
+	// 32x32 thumbnail's discrete cosine transform set if above their
+	// median. It is the most robust of the three, and the slowest.
+	Perceptual uint64
+}
+
+// Distance returns the number of bits in which a and b differ. Different
+// images typically differ in about half of their bits; near-duplicates in
+// fewer than ten.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FromReader decodes the image read from r and returns its perceptual
+// hashes, along with the digests of hashes over the file's bytes, all from
+// a single read.
+func FromReader(r io.Reader, hashes ...hash.Hash) (Hashes, [][]byte, error) {
+	writers := make([]io.Writer, len(hashes))
+	for index, h := range hashes {
+		writers[index] = h
+	}
+	tee := io.TeeReader(r, io.MultiWriter(writers...))
+	img, _, err := image.Decode(tee)
+	if err != nil {
+		return Hashes{}, nil, err
+	}
+	// Decoders may stop before the end of the file.
+	if _, err = io.Copy(io.Discard, tee); err != nil {
+		return Hashes{}, nil, err
+	}
+	digests := make([][]byte, len(hashes))
+	for index, h := range hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return Of(img), digests, nil
+}
+
+// Of returns the perceptual hashes of img.
+func Of(img image.Image) Hashes {
+	return Hashes{
+		Average:    Average(img),
+		Difference: Difference(img),
+		Perceptual: Perceptual(img),
+	}
+}
+
+// Average returns the aHash of img; see Hashes.
+func Average(img image.Image) uint64 {
+	pixels := thumbnail(img, 8, 8)
+	mean := 0.0
+	for _, p := range pixels {
+		mean += p
+	}
+	mean /= float64(len(pixels))
+	var h uint64
+	for index, p := range pixels {
+		if p > mean {
+			h |= 1 << uint(63-index)
+		}
+	}
+	return h
+}
+
+// Difference returns the dHash of img; see Hashes.
+func Difference(img image.Image) uint64 {
+	pixels := thumbnail(img, 9, 8)
+	var h uint64
+	bit := 63
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if pixels[y*9+x] > pixels[y*9+x+1] {
+				h |= 1 << uint(bit)
+			}
+			bit--
+		}
+	}
+	return h
+}
+
+// Perceptual returns the pHash of img; see Hashes.
+func Perceptual(img image.Image) uint64 {
+	const size = 32
+	pixels := thumbnail(img, size, size)
+	// Separable 2D DCT-II, keeping only the lowest 8x8 frequencies.
+	var rows [size][8]float64
+	for y := 0; y < size; y++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for x := 0; x < size; x++ {
+				sum += pixels[y*size+x] * math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*size))
+			}
+			rows[y][u] = sum
+		}
+	}
+	coefficients := make([]float64, 64)
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for y := 0; y < size; y++ {
+				sum += rows[y][u] * math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*size))
+			}
+			coefficients[v*8+u] = sum
+		}
+	}
+	// The DC term, the overall brightness, would skew the median. That
+	// leaves 63 terms, so the median is the middle one.
+	sorted := append([]float64(nil), coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	var h uint64
+	for index, c := range coefficients {
+		if c > median {
+			h |= 1 << uint(63-index)
+		}
+	}
+	return h
+}
+
+// thumbnail scales img to width by height grayscale pixels, each the mean
+// luma of the source pixels it covers, in row-major order.
+func thumbnail(img image.Image, width, height int) []float64 {
+	bounds := img.Bounds()
+	pixels := make([]float64, width*height)
+	counts := make([]int, width*height)
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return pixels
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ty := (y - bounds.Min.Y) * height / h
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			tx := (x - bounds.Min.X) * width / w
+			r, g, b, _ := img.At(x, y).RGBA()
+			// ITU-R BT.601 luma.
+			pixels[ty*width+tx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[ty*width+tx]++
+		}
+	}
+	// A source smaller than the thumbnail leaves some cells empty; they
+	// take the pixel that would have been sampled.
+	for index := range pixels {
+		if counts[index] > 0 {
+			pixels[index] /= float64(counts[index])
+			continue
+		}
+		x := bounds.Min.X + (index%width)*w/width
+		y := bounds.Min.Y + (index/width)*h/height
+		r, g, b, _ := img.At(x, y).RGBA()
+		pixels[index] = 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+	}
+	return pixels
+}
@@ -0,0 +1,75 @@
+package phash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func scene(width, height int, seed int64) *image.RGBA {
+	random := rand.New(rand.NewSource(seed))
+	blobs := make([][3]int, 6)
+	for index := range blobs {
+		blobs[index] = [3]int{random.Intn(100), random.Intn(100), 10 + random.Intn(30)}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			shade := 40
+			for _, b := range blobs {
+				dx, dy := x*100/width-b[0], y*100/height-b[1]
+				if dx*dx+dy*dy < b[2]*b[2] {
+					shade += 35
+				}
+			}
+			if shade > 255 {
+				shade = 255
+			}
+			img.Set(x, y, color.RGBA{uint8(shade), uint8(shade / 2), uint8(255 - shade), 255})
+		}
+	}
+	return img
+}
+
+func Test_FromReader(t *testing.T) {
+	var original bytes.Buffer
+	png.Encode(&original, scene(200, 150, 1))
+	hashes, digests, err := FromReader(bytes.NewReader(original.Bytes()), sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := sha256.Sum256(original.Bytes()); !bytes.Equal(digests[0], expected[:]) {
+		t.Fatalf("file digest %x, expected %x\n", digests[0], expected)
+	}
+
+	// A smaller, lossily recompressed copy stays close.
+	var copied bytes.Buffer
+	jpeg.Encode(&copied, scene(100, 75, 1), &jpeg.Options{Quality: 60})
+	copyHashes, _, err := FromReader(bytes.NewReader(copied.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A different image does not.
+	other := Of(scene(200, 150, 2))
+
+	for _, c := range []struct {
+		name            string
+		a, b, different uint64
+	}{
+		{"aHash", hashes.Average, copyHashes.Average, other.Average},
+		{"dHash", hashes.Difference, copyHashes.Difference, other.Difference},
+		{"pHash", hashes.Perceptual, copyHashes.Perceptual, other.Perceptual},
+	} {
+		if d := Distance(c.a, c.b); d > 10 {
+			t.Fatalf("%s: copy is %d bits away\n", c.name, d)
+		}
+		if d := Distance(c.a, c.different); d < 12 {
+			t.Fatalf("%s: different image is only %d bits away\n", c.name, d)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package pkgmeta
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// sumsAlgorithms maps the names of checksum files published beside ISO
+// images to the algorithm of their GNU-style lines. CHECKSUM files, as
+// Fedora publishes them, are BSD-style and name their own algorithms.
+var sumsAlgorithms = map[string]string{
+	"MD5SUMS":    "md5",
+	"SHA1SUMS":   "sha1",
+	"SHA256SUMS": "sha256",
+	"SHA512SUMS": "sha512",
+	"CHECKSUM":   "sha256",
+}
+
+// signatureExtensions mark detached signatures and keys, which an audit
+// leaves to whatever checks signatures rather than reporting as Extra.
+var signatureExtensions = map[string]bool{".asc": true, ".gpg": true, ".sig": true, ".key": true}
+
+// Audit checks an offline copy of a package or ISO mirror under root
+// against the mirror's own metadata. It walks root for:
+//
+//   - Debian Release files, checking every index they list that is present
+//     (a mirror rarely holds every compression of every index), and every
+//     package in the Packages indices among them that matched;
+//   - RPM repodata/repomd.xml files, checking the metadata files they list
+//     and every package in the primary metadata, if it matched;
+//   - SHA256SUMS, SHA512SUMS, SHA1SUMS, MD5SUMS and CHECKSUM files, checking
+//     every file they list.
+//
+// The report's paths are relative to root, with forward slashes. A package
+// listed but absent is Missing, and one listed only without checksums is
+// Unverified, since nothing vouches for it; a file listed nowhere that is neither
+// metadata nor a signature is Extra. Indices that are only present in
+// compressions the standard library cannot read, such as xz, are reported
+// as Failed, as is metadata that does not parse. A file listed more than
+// once, as pool files are by every suite that includes them, is hashed once
+// for each distinct set of checksums.
+//
+// Signatures are not checked; a mirror whose metadata was replaced along
+// with its packages passes. Check Release.gpg, InRelease or repomd.xml.asc
+// against the distribution's keys first.
+func Audit(root string) (multihash.VerifyReport, error) {
+	a := &auditor{
+		root:       root,
+		listed:     make(map[string]bool),
+		checked:    make(map[string]bool),
+		verified:   make(map[string]bool),
+		mismatched: make(map[string]bool),
+		missing:    make(map[string]bool),
+		unverified: make(map[string]bool),
+	}
+	var files []string
+	err := filepath.WalkDir(root, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		relative, err := filepath.Rel(root, filename)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relative))
+		return nil
+	})
+	if err != nil {
+		return multihash.VerifyReport{}, err
+	}
+	for _, relative := range files {
+		dir, base := path.Split(relative)
+		dir = strings.TrimSuffix(dir, "/")
+		switch {
+		case base == "Release":
+			a.listed[relative] = true
+			a.release(relative, dir)
+		case base == "repomd.xml" && path.Base(dir) == "repodata":
+			a.listed[relative] = true
+			a.repomd(relative, path.Dir(dir))
+		case sumsAlgorithms[base] != "" || strings.HasSuffix(base, "-CHECKSUM"):
+			a.listed[relative] = true
+			a.sums(relative, dir)
+		case base == "InRelease" || signatureExtensions[path.Ext(base)]:
+			a.listed[relative] = true
+		}
+	}
+	for _, relative := range files {
+		if !a.listed[relative] {
+			a.report.Extra = append(a.report.Extra, relative)
+		}
+	}
+	for relative := range a.mismatched {
+		delete(a.verified, relative)
+		delete(a.unverified, relative)
+	}
+	for relative := range a.verified {
+		delete(a.unverified, relative)
+	}
+	a.report.Verified = sortedKeys(a.verified)
+	a.report.Mismatched = sortedKeys(a.mismatched)
+	a.report.Missing = sortedKeys(a.missing)
+	a.report.Unverified = sortedKeys(a.unverified)
+	return a.report, nil
+}
+
+type auditor struct {
+	root   string
+	report multihash.VerifyReport
+	// listed holds every path metadata mentions or that is metadata itself;
+	// checked holds the path and checksums of every check made, so each is
+	// made once.
+	listed     map[string]bool
+	checked    map[string]bool
+	verified   map[string]bool
+	mismatched map[string]bool
+	missing    map[string]bool
+	unverified map[string]bool
+}
+
+func (a *auditor) fail(relative string, err error) {
+	if a.report.Failed == nil {
+		a.report.Failed = make(map[string]error)
+	}
+	a.report.Failed[relative] = err
+}
+
+// resolve gives the path relative to the audit root of a path that metadata
+// in dir lists, or false if it would lie outside the root.
+func (a *auditor) resolve(metadata, dir, listed string) (string, bool) {
+	relative := path.Join(dir, listed)
+	if relative == ".." || strings.HasPrefix(relative, "../") || path.IsAbs(listed) {
+		a.fail(metadata, MalformedMetadataError{Format: "mirror", Reason: "listed path " + strconv.Quote(listed) + " is outside the mirror"})
+		return "", false
+	}
+	return relative, true
+}
+
+// check verifies the file at relative against artifact, reporting whether
+// it matched. Files that are absent are Missing unless optional.
+func (a *auditor) check(relative string, artifact Artifact, optional bool) bool {
+	key := relative + "\x00" + strconv.FormatInt(artifact.Size, 10)
+	for _, checksum := range artifact.Checksums {
+		key += "\x00" + checksum.Algorithm + ":" + string(checksum.Digest)
+	}
+	if a.checked[key] {
+		return a.verified[relative] && !a.mismatched[relative]
+	}
+	a.checked[key] = true
+	err := artifact.VerifyFile(filepath.Join(a.root, filepath.FromSlash(relative)))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if !optional {
+			a.listed[relative] = true
+			a.missing[relative] = true
+		}
+		return false
+	case errors.Is(err, multihash.ErrDigestMismatch) || errors.Is(err, multihash.ErrSizeMismatch):
+		a.mismatched[relative] = true
+	case errors.Is(err, multihash.ErrNoChecksum):
+		// Metadata that lists a file without checksums vouches for nothing.
+		a.unverified[relative] = true
+	case err != nil:
+		a.fail(relative, err)
+	default:
+		a.verified[relative] = true
+	}
+	a.listed[relative] = true
+	return err == nil
+}
+
+func (a *auditor) release(relative, dir string) {
+	artifacts, err := readArtifacts(a.root, relative, DebianRelease)
+	if err != nil {
+		a.fail(relative, err)
+		return
+	}
+	// Packages paths are relative to the archive root, the directory
+	// holding dists.
+	archive := dir
+	for archive != "." && archive != "" && path.Base(archive) != "dists" {
+		archive = path.Dir(archive)
+	}
+	if path.Base(archive) == "dists" {
+		archive = path.Dir(archive)
+	} else {
+		archive = dir
+	}
+
+	readable := make(map[string]string)
+	unreadable := make(map[string]string)
+	for _, artifact := range artifacts {
+		indexPath, ok := a.resolve(relative, dir, artifact.Path)
+		if !ok || !a.check(indexPath, artifact, true) {
+			continue
+		}
+		indexDir, base := path.Split(indexPath)
+		switch {
+		case base == "Packages" || base == "Packages.gz":
+			readable[indexDir] = indexPath
+		case strings.HasPrefix(base, "Packages."):
+			unreadable[indexDir] = indexPath
+		}
+	}
+	for indexDir, indexPath := range unreadable {
+		if _, ok := readable[indexDir]; !ok {
+			a.fail(indexPath, manifest.UnsupportedCompressionError{Format: strings.TrimPrefix(path.Ext(indexPath), ".")})
+		}
+	}
+	for _, indexPath := range sortedValues(readable) {
+		packages, err := readArtifacts(a.root, indexPath, DebianPackages)
+		if err != nil {
+			a.fail(indexPath, err)
+			continue
+		}
+		for _, artifact := range packages {
+			if packagePath, ok := a.resolve(indexPath, archive, artifact.Path); ok {
+				a.check(packagePath, artifact, false)
+			}
+		}
+	}
+}
+
+func (a *auditor) repomd(relative, repository string) {
+	var artifacts []Artifact
+	var types []string
+	f, err := os.Open(filepath.Join(a.root, filepath.FromSlash(relative)))
+	if err == nil {
+		artifacts, types, err = RepoMD(f)
+		f.Close()
+	}
+	if err != nil {
+		a.fail(relative, err)
+		return
+	}
+	for index, artifact := range artifacts {
+		dataPath, ok := a.resolve(relative, repository, artifact.Path)
+		if !ok || !a.check(dataPath, artifact, false) || types[index] != "primary" {
+			continue
+		}
+		if ext := path.Ext(dataPath); ext != ".xml" && ext != ".gz" {
+			a.fail(dataPath, manifest.UnsupportedCompressionError{Format: strings.TrimPrefix(ext, ".")})
+			continue
+		}
+		packages, err := readArtifacts(a.root, dataPath, RPMPrimary)
+		if err != nil {
+			a.fail(dataPath, err)
+			continue
+		}
+		for _, artifact := range packages {
+			if packagePath, ok := a.resolve(dataPath, repository, artifact.Path); ok {
+				a.check(packagePath, artifact, false)
+			}
+		}
+	}
+}
+
+func (a *auditor) sums(relative, dir string) {
+	algorithm := sumsAlgorithms[path.Base(relative)]
+	if algorithm == "" {
+		algorithm = sumsAlgorithms["CHECKSUM"]
+	}
+	f, err := os.Open(filepath.Join(a.root, filepath.FromSlash(relative)))
+	if err != nil {
+		a.fail(relative, err)
+		return
+	}
+	defer f.Close()
+	m, err := manifest.Parse(f, algorithm)
+	if err != nil {
+		a.fail(relative, err)
+		return
+	}
+	for _, entry := range m.Entries {
+		artifact := Artifact{Path: entry.Path, Size: -1}
+		for index, digest := range entry.Digests {
+			if digest != nil {
+				artifact.Checksums = append(artifact.Checksums, Checksum{Algorithm: m.Algorithms[index], Digest: digest})
+			}
+		}
+		if filePath, ok := a.resolve(relative, dir, entry.Path); ok {
+			a.check(filePath, artifact, false)
+		}
+	}
+}
+
+// readArtifacts parses the metadata file at relative under root with parse,
+// decompressing it first if need be.
+func readArtifacts(root, relative string, parse func(io.Reader) ([]Artifact, error)) ([]Artifact, error) {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(relative)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := manifest.Decompress(f)
+	if err != nil {
+		return nil, err
+	}
+	return parse(r)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedValues(m map[string]string) []string {
+	var values []string
+	for _, value := range m {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
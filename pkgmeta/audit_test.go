@@ -0,0 +1,118 @@
+package pkgmeta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func writeMirrorFile(t *testing.T, root, name string, data []byte) {
+	filename := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func gzipped(data []byte) []byte {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write(data)
+	w.Close()
+	return compressed.Bytes()
+}
+
+func stanza(filename string, data []byte) string {
+	return "Package: " + filepath.Base(filename) + "\n" +
+		"Filename: " + filename + "\n" +
+		"Size: " + strconv.Itoa(len(data)) + "\n" +
+		"SHA256: " + sha256Hex(data) + "\n\n"
+}
+
+func Test_Audit(t *testing.T) {
+	root := t.TempDir()
+
+	good := []byte("good package")
+	tampered := []byte("original package")
+	packages := gzipped([]byte(stanza("pool/main/g/good.deb", good) +
+		stanza("pool/main/t/tampered.deb", tampered) +
+		stanza("pool/main/m/missing.deb", []byte("never mirrored")) +
+		"Package: bare.deb\nFilename: pool/main/b/bare.deb\nSize: 4\n\n"))
+	release := "Origin: Example\n" +
+		"SHA256:\n" +
+		" " + sha256Hex(packages) + " " + strconv.Itoa(len(packages)) + " main/binary-amd64/Packages.gz\n" +
+		" " + sha256Hex([]byte("absent")) + " 6 main/binary-amd64/Packages.xz\n"
+	writeMirrorFile(t, root, "debian/dists/stable/Release", []byte(release))
+	writeMirrorFile(t, root, "debian/dists/stable/Release.gpg", []byte("signature"))
+	writeMirrorFile(t, root, "debian/dists/stable/main/binary-amd64/Packages.gz", packages)
+	writeMirrorFile(t, root, "debian/pool/main/g/good.deb", good)
+	writeMirrorFile(t, root, "debian/pool/main/b/bare.deb", []byte("bare"))
+	writeMirrorFile(t, root, "debian/pool/main/t/tampered.deb", []byte("modified package"))
+
+	rpm := []byte("rpm package")
+	primary := gzipped([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<metadata xmlns="http://linux.duke.edu/metadata/common" packages="1">
+<package type="rpm"><name>tool</name>
+<checksum type="sha256" pkgid="YES">` + sha256Hex(rpm) + `</checksum>
+<size package="` + strconv.Itoa(len(rpm)) + `" installed="100" archive="200"/>
+<location href="Packages/tool.rpm"/></package>
+</metadata>`))
+	repomd := `<?xml version="1.0" encoding="UTF-8"?>
+<repomd xmlns="http://linux.duke.edu/metadata/repo">
+<data type="primary"><checksum type="sha256">` + sha256Hex(primary) + `</checksum>
+<location href="repodata/primary.xml.gz"/><size>` + strconv.Itoa(len(primary)) + `</size></data>
+</repomd>`
+	writeMirrorFile(t, root, "rpm/repodata/repomd.xml", []byte(repomd))
+	writeMirrorFile(t, root, "rpm/repodata/primary.xml.gz", primary)
+	writeMirrorFile(t, root, "rpm/Packages/tool.rpm", rpm)
+
+	iso := []byte("installer image")
+	writeMirrorFile(t, root, "iso/SHA256SUMS", []byte(sha256Hex(iso)+" *installer.iso\n"))
+	writeMirrorFile(t, root, "iso/installer.iso", iso)
+	writeMirrorFile(t, root, "iso/notes.txt", []byte("unlisted"))
+
+	report, err := Audit(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := []string{
+		"debian/dists/stable/main/binary-amd64/Packages.gz",
+		"debian/pool/main/g/good.deb",
+		"iso/installer.iso",
+		"rpm/Packages/tool.rpm",
+		"rpm/repodata/primary.xml.gz",
+	}
+	if !stringsEqual(report.Verified, verified) {
+		t.Fatalf("verified %q, expected %q\n", report.Verified, verified)
+	}
+	if !stringsEqual(report.Mismatched, []string{"debian/pool/main/t/tampered.deb"}) ||
+		!stringsEqual(report.Missing, []string{"debian/pool/main/m/missing.deb"}) ||
+		!stringsEqual(report.Unverified, []string{"debian/pool/main/b/bare.deb"}) ||
+		!stringsEqual(report.Extra, []string{"iso/notes.txt"}) || len(report.Failed) != 0 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
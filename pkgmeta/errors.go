@@ -0,0 +1,22 @@
+package pkgmeta
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrMalformedMetadata = errcode.New(errcode.Malformed, "malformed package metadata")
+
+type MalformedMetadataError struct {
+	Format string
+	Reason string
+}
+
+func (e MalformedMetadataError) Error() string {
+	return "malformed " + e.Format + " metadata: " + e.Reason
+}
+
+func (e MalformedMetadataError) Is(target error) bool {
+	return target == ErrMalformedMetadata
+}
+
+func (e MalformedMetadataError) Code() errcode.Code {
+	return errcode.Malformed
+}
@@ -0,0 +1,344 @@
+// package pkgmeta reads the checksums that package ecosystems publish for
+// their artifacts, in each ecosystem's own format, and checks downloaded
+// packages against them: PyPI's URL fragments, npm's integrity strings,
+// Maven's checksum sidecars, Debian's Release files and Packages indices and
+// RPM repository metadata. Each ecosystem's algorithm names are mapped to the
+// names multihash.New accepts. Audit uses them to check a whole offline
+// mirror against its own metadata.
+package pkgmeta
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Checksum is a single published digest.
+type Checksum struct {
+	Algorithm string
+	Digest    []byte
+}
+
+// Artifact is a package file and the checksums published for it.
+type Artifact struct {
+	// Path of the file as the metadata names it: a file name for PyPI and
+	// Maven, a path relative to the archive root for Debian, and empty for
+	// npm, whose integrity strings do not name the tarball.
+	Path string
+	// Size is the published size in bytes, or -1 if none is published.
+	Size      int64
+	Checksums []Checksum
+}
+
+// Verify hashes data with every one of a's checksums whose algorithm is
+// available and checks them, along with a's size if known. Checksums under
+// algorithms not linked into the binary are skipped, so that the MD5 sums
+// beside SHA-256 ones in older metadata need not be checked, but if none is
+// available the error is that of the first. A mismatch gives a
+// multihash.SizeMismatchError or multihash.DigestMismatchError.
+func (a Artifact) Verify(data io.Reader) error {
+	var hashes []hash.Hash
+	var expected [][]byte
+	var unavailable error
+	for _, checksum := range a.Checksums {
+		h, err := multihash.New(checksum.Algorithm)
+		if err != nil {
+			if unavailable == nil {
+				unavailable = err
+			}
+			continue
+		}
+		hashes = append(hashes, h)
+		expected = append(expected, checksum.Digest)
+	}
+	if len(hashes) == 0 {
+		if unavailable != nil {
+			return unavailable
+		}
+		return multihash.ErrNoChecksum
+	}
+	_, err := multihash.VerifyReader(data, a.Size, expected, hashes...)
+	return err
+}
+
+// VerifyFile is Verify for the contents of filename.
+func (a Artifact) VerifyFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return a.Verify(f)
+}
+
+// pypiAlgorithms maps the hashlib names PyPI uses to registry names, where
+// they differ. PEP 503 fragments use hashlib's constructor names, and the
+// JSON API's digests add blake2b_256.
+var pypiAlgorithms = map[string]string{
+	"sha3_224":    "sha3-224",
+	"sha3_256":    "sha3-256",
+	"sha3_384":    "sha3-384",
+	"sha3_512":    "sha3-512",
+	"blake2b":     "blake2b-512",
+	"blake2s":     "blake2s-256",
+	"blake2b_256": "blake2b-256",
+}
+
+// PyPIFragment reads the checksum in the fragment of a link from a PyPI
+// simple index, e.g.
+//
+//	https://files.pythonhosted.org/…/requests-2.31.0.tar.gz#sha256=942c5a75…
+//
+// as PEP 503 defines. The artifact's Path is the file name at the end of
+// the link.
+func PyPIFragment(link string) (Artifact, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return Artifact{}, MalformedMetadataError{Format: "PyPI", Reason: err.Error()}
+	}
+	name, value, found := strings.Cut(u.Fragment, "=")
+	if !found {
+		return Artifact{}, MalformedMetadataError{Format: "PyPI", Reason: "link " + strconv.Quote(link) + " has no checksum fragment"}
+	}
+	algorithm := strings.ToLower(name)
+	if mapped, ok := pypiAlgorithms[algorithm]; ok {
+		algorithm = mapped
+	}
+	digest, err := hex.DecodeString(value)
+	if err != nil {
+		return Artifact{}, multihash.MalformedDigestError{Text: value}
+	}
+	return Artifact{
+		Path:      path.Base(u.Path),
+		Size:      -1,
+		Checksums: []Checksum{{Algorithm: algorithm, Digest: digest}},
+	}, nil
+}
+
+// npmAlgorithms are the algorithms npm writes to integrity strings. sha1
+// appears in lockfiles for packages published before npm 5.
+var npmAlgorithms = map[string]bool{"sha1": true, "sha256": true, "sha384": true, "sha512": true}
+
+// NPMIntegrity reads the integrity field of package-lock.json or of a
+// registry's dist metadata: one or more space-separated Subresource
+// Integrity values, e.g. "sha512-…". Unlike multihash.ParseSRI, it accepts
+// the sha1 values of older lockfiles too. Values under other algorithms are
+// ignored, as the specification asks, but at least one must be recognised.
+func NPMIntegrity(integrity string) (Artifact, error) {
+	artifact := Artifact{Size: -1}
+	for _, value := range strings.Fields(integrity) {
+		value, _, _ = strings.Cut(value, "?")
+		algorithm, encoded, found := strings.Cut(value, "-")
+		if !found {
+			return Artifact{}, multihash.MalformedDigestError{Text: value}
+		}
+		if !npmAlgorithms[algorithm] {
+			continue
+		}
+		digest, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return Artifact{}, multihash.MalformedDigestError{Text: value}
+		}
+		artifact.Checksums = append(artifact.Checksums, Checksum{Algorithm: algorithm, Digest: digest})
+	}
+	if len(artifact.Checksums) == 0 {
+		return Artifact{}, MalformedMetadataError{Format: "npm", Reason: "no recognised integrity value in " + strconv.Quote(integrity)}
+	}
+	return artifact, nil
+}
+
+// mavenAlgorithms maps the extensions of Maven checksum files to registry
+// names.
+var mavenAlgorithms = map[string]string{
+	".md5":    "md5",
+	".sha1":   "sha1",
+	".sha256": "sha256",
+	".sha512": "sha512",
+}
+
+// MavenSidecar reads a Maven repository checksum file, such as
+// guava-32.1.2-jre.jar.sha1, given its name and contents. The algorithm is
+// given by the extension, and the artifact's Path is the name without it.
+// The contents are a hex digest, optionally followed by a file name, as
+// some older deployments wrote them.
+func MavenSidecar(name string, contents []byte) (Artifact, error) {
+	extension := path.Ext(name)
+	algorithm, ok := mavenAlgorithms[strings.ToLower(extension)]
+	if !ok {
+		return Artifact{}, MalformedMetadataError{Format: "Maven", Reason: strconv.Quote(name) + " is not a checksum file"}
+	}
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return Artifact{}, MalformedMetadataError{Format: "Maven", Reason: strconv.Quote(name) + " is empty"}
+	}
+	digest, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return Artifact{}, multihash.MalformedDigestError{Text: fields[0]}
+	}
+	return Artifact{
+		Path:      strings.TrimSuffix(path.Base(name), extension),
+		Size:      -1,
+		Checksums: []Checksum{{Algorithm: algorithm, Digest: digest}},
+	}, nil
+}
+
+// debianAlgorithms maps the checksum fields of Debian Packages stanzas to
+// registry names, in the order they are conventionally written.
+var debianAlgorithms = []struct{ field, algorithm string }{
+	{"MD5sum", "md5"},
+	{"SHA1", "sha1"},
+	{"SHA256", "sha256"},
+	{"SHA512", "sha512"},
+}
+
+// DebianPackages reads a Debian Packages index, already decompressed,
+// returning an artifact for every stanza, with the Filename, Size and
+// checksum fields it gives. Paths are relative to the root of the archive,
+// e.g. "pool/main/h/hello/hello_2.10-3_amd64.deb".
+func DebianPackages(r io.Reader) ([]Artifact, error) {
+	var artifacts []Artifact
+	var fields map[string]string
+	line := 0
+	finish := func() error {
+		if fields == nil {
+			return nil
+		}
+		artifact, err := debianArtifact(fields, line)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, artifact)
+		fields = nil
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		switch {
+		case strings.TrimSpace(text) == "":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+		case text[0] == ' ' || text[0] == '\t':
+			// A continuation of a multi-line field such as Description.
+		default:
+			key, value, found := strings.Cut(text, ":")
+			if !found {
+				return nil, MalformedMetadataError{Format: "Debian", Reason: "line " + strconv.Itoa(line) + " is not a field"}
+			}
+			if fields == nil {
+				fields = make(map[string]string)
+			}
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+func debianArtifact(fields map[string]string, line int) (Artifact, error) {
+	artifact := Artifact{Path: fields["Filename"], Size: -1}
+	if artifact.Path == "" {
+		return Artifact{}, MalformedMetadataError{Format: "Debian", Reason: "stanza ending at line " + strconv.Itoa(line) + " has no Filename"}
+	}
+	if size, ok := fields["Size"]; ok {
+		var err error
+		if artifact.Size, err = strconv.ParseInt(size, 10, 64); err != nil || artifact.Size < 0 {
+			return Artifact{}, MalformedMetadataError{Format: "Debian", Reason: "invalid Size " + strconv.Quote(size) + " for " + artifact.Path}
+		}
+	}
+	for _, checksum := range debianAlgorithms {
+		value, ok := fields[checksum.field]
+		if !ok {
+			continue
+		}
+		digest, err := hex.DecodeString(value)
+		if err != nil {
+			return Artifact{}, multihash.MalformedDigestError{Text: value}
+		}
+		artifact.Checksums = append(artifact.Checksums, Checksum{Algorithm: checksum.algorithm, Digest: digest})
+	}
+	return artifact, nil
+}
+
+// releaseAlgorithms maps the checksum sections of Debian Release files to
+// registry names.
+var releaseAlgorithms = map[string]string{
+	"MD5Sum": "md5",
+	"SHA1":   "sha1",
+	"SHA256": "sha256",
+	"SHA512": "sha512",
+}
+
+// DebianRelease reads the checksum sections of a Debian Release file, each a
+// field followed by lines of
+//
+//	<hex digest> <size> <path>
+//
+// returning an artifact for every index listed, with the checksums of every
+// section that lists it. Paths are relative to the directory holding the
+// Release file, e.g. "main/binary-amd64/Packages.gz". The file must be
+// unsigned; an InRelease file's signature has to be stripped, and checked,
+// first.
+func DebianRelease(r io.Reader) ([]Artifact, error) {
+	var artifacts []Artifact
+	byPath := make(map[string]int)
+	algorithm := ""
+	line := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" || (text[0] != ' ' && text[0] != '\t') {
+			key, value, _ := strings.Cut(text, ":")
+			algorithm = ""
+			if strings.TrimSpace(value) == "" {
+				algorithm = releaseAlgorithms[key]
+			}
+			continue
+		}
+		if algorithm == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, MalformedMetadataError{Format: "Debian", Reason: "line " + strconv.Itoa(line) + " is not a checksum line"}
+		}
+		digest, err := hex.DecodeString(fields[0])
+		if err != nil {
+			return nil, multihash.MalformedDigestError{Text: fields[0]}
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size < 0 {
+			return nil, MalformedMetadataError{Format: "Debian", Reason: "invalid size " + strconv.Quote(fields[1]) + " on line " + strconv.Itoa(line)}
+		}
+		index, ok := byPath[fields[2]]
+		if !ok {
+			index = len(artifacts)
+			byPath[fields[2]] = index
+			artifacts = append(artifacts, Artifact{Path: fields[2], Size: size})
+		}
+		artifacts[index].Checksums = append(artifacts[index].Checksums, Checksum{Algorithm: algorithm, Digest: digest})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
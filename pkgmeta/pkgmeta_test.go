@@ -0,0 +1,102 @@
+package pkgmeta
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+var (
+	contents  = "package contents"
+	sha1Sum   = sha1.Sum([]byte(contents))
+	sha256Sum = sha256.Sum256([]byte(contents))
+	sha512Sum = sha512.Sum512([]byte(contents))
+)
+
+func Test_PyPIFragment(t *testing.T) {
+	artifact, err := PyPIFragment("https://files.example.org/packages/ab/cd/example-1.0.tar.gz#sha256=" + hex.EncodeToString(sha256Sum[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Path != "example-1.0.tar.gz" || artifact.Checksums[0].Algorithm != "sha256" {
+		t.Fatalf("unexpected artifact %+v\n", artifact)
+	}
+	if err := artifact.Verify(strings.NewReader(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if err := artifact.Verify(strings.NewReader("tampered")); !errors.Is(err, multihash.ErrDigestMismatch) {
+		t.Fatalf("tampered package gave %v\n", err)
+	}
+	if _, err := PyPIFragment("https://files.example.org/example-1.0.tar.gz"); !errors.Is(err, ErrMalformedMetadata) {
+		t.Fatalf("link without fragment gave %v\n", err)
+	}
+}
+
+func Test_NPMIntegrity(t *testing.T) {
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(sha512Sum[:]) +
+		" md6-ignored sha1-" + base64.StdEncoding.EncodeToString(sha1Sum[:])
+	artifact, err := NPMIntegrity(integrity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifact.Checksums) != 2 || artifact.Checksums[1].Algorithm != "sha1" {
+		t.Fatalf("unexpected artifact %+v\n", artifact)
+	}
+	if err := artifact.Verify(strings.NewReader(contents)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_MavenSidecar(t *testing.T) {
+	artifact, err := MavenSidecar("repo/lib-1.0.jar.sha1", []byte(hex.EncodeToString(sha1Sum[:])+"  lib-1.0.jar\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if artifact.Path != "lib-1.0.jar" || artifact.Checksums[0].Algorithm != "sha1" {
+		t.Fatalf("unexpected artifact %+v\n", artifact)
+	}
+	if err := artifact.Verify(strings.NewReader(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MavenSidecar("lib-1.0.jar.asc", nil); !errors.Is(err, ErrMalformedMetadata) {
+		t.Fatalf("signature file gave %v\n", err)
+	}
+}
+
+func Test_DebianPackages(t *testing.T) {
+	index := "Package: example\n" +
+		"Version: 1.0-1\n" +
+		"Filename: pool/main/e/example/example_1.0-1_all.deb\n" +
+		"Size: " + strconv.Itoa(len(contents)) + "\n" +
+		"SHA256: " + hex.EncodeToString(sha256Sum[:]) + "\n" +
+		"Description: an example\n" +
+		" spanning two lines\n" +
+		"\n" +
+		"Package: other\n" +
+		"Filename: pool/main/o/other/other_2.0_all.deb\n" +
+		"Size: 5\n"
+	artifacts, err := DebianPackages(strings.NewReader(index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 2 || artifacts[0].Path != "pool/main/e/example/example_1.0-1_all.deb" || artifacts[1].Size != 5 {
+		t.Fatalf("unexpected artifacts %+v\n", artifacts)
+	}
+	if err := artifacts[0].Verify(strings.NewReader(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if err := artifacts[0].Verify(strings.NewReader(contents + "!")); !errors.Is(err, multihash.ErrSizeMismatch) {
+		t.Fatalf("overlong package gave %v\n", err)
+	}
+	if err := artifacts[1].Verify(strings.NewReader("12345")); !errors.Is(err, multihash.ErrNoChecksum) {
+		t.Fatalf("stanza without checksums gave %v\n", err)
+	}
+}
@@ -0,0 +1,121 @@
+package pkgmeta
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+)
+
+// rpmAlgorithms maps the checksum types of RPM repository metadata to
+// registry names, where they differ. Old createrepo versions wrote "sha"
+// for SHA-1.
+var rpmAlgorithms = map[string]string{
+	"sha": "sha1",
+}
+
+type rpmChecksum struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type rpmLocation struct {
+	Href string `xml:"href,attr"`
+}
+
+type repomdData struct {
+	Type     string      `xml:"type,attr"`
+	Checksum rpmChecksum `xml:"checksum"`
+	Location rpmLocation `xml:"location"`
+	Size     string      `xml:"size"`
+}
+
+type rpmPackage struct {
+	Checksum rpmChecksum `xml:"checksum"`
+	Location rpmLocation `xml:"location"`
+	Size     struct {
+		Package string `xml:"package,attr"`
+	} `xml:"size"`
+}
+
+// RepoMD reads an RPM repository's repodata/repomd.xml, returning an
+// artifact for each metadata file it lists, keyed in types by the same
+// index, e.g. "primary". Paths are relative to the repository root, the
+// directory holding repodata.
+func RepoMD(r io.Reader) (artifacts []Artifact, types []string, err error) {
+	var document struct {
+		Data []repomdData `xml:"data"`
+	}
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, nil, MalformedMetadataError{Format: "RPM", Reason: err.Error()}
+	}
+	for _, data := range document.Data {
+		artifact, err := rpmArtifact(data.Location, data.Checksum, data.Size)
+		if err != nil {
+			return nil, nil, err
+		}
+		artifacts = append(artifacts, artifact)
+		types = append(types, data.Type)
+	}
+	return artifacts, types, nil
+}
+
+// RPMPrimary reads the primary metadata of an RPM repository, already
+// decompressed, returning an artifact for every package. Packages are
+// decoded one at a time, so repositories of any size can be read. Paths are
+// relative to the repository root.
+func RPMPrimary(r io.Reader) ([]Artifact, error) {
+	var artifacts []Artifact
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return artifacts, nil
+		}
+		if err != nil {
+			return nil, MalformedMetadataError{Format: "RPM", Reason: err.Error()}
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "package" {
+			continue
+		}
+		var p rpmPackage
+		if err := decoder.DecodeElement(&p, &start); err != nil {
+			return nil, MalformedMetadataError{Format: "RPM", Reason: err.Error()}
+		}
+		artifact, err := rpmArtifact(p.Location, p.Checksum, p.Size.Package)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+}
+
+func rpmArtifact(location rpmLocation, checksum rpmChecksum, size string) (Artifact, error) {
+	if location.Href == "" {
+		return Artifact{}, MalformedMetadataError{Format: "RPM", Reason: "entry has no location"}
+	}
+	artifact := Artifact{Path: location.Href, Size: -1}
+	if size = strings.TrimSpace(size); size != "" {
+		var err error
+		if artifact.Size, err = strconv.ParseInt(size, 10, 64); err != nil || artifact.Size < 0 {
+			return Artifact{}, MalformedMetadataError{Format: "RPM", Reason: "invalid size " + strconv.Quote(size) + " for " + location.Href}
+		}
+	}
+	if checksum.Type != "" {
+		algorithm := strings.ToLower(checksum.Type)
+		if mapped, ok := rpmAlgorithms[algorithm]; ok {
+			algorithm = mapped
+		}
+		value := strings.TrimSpace(checksum.Value)
+		digest, err := hex.DecodeString(value)
+		if err != nil {
+			return Artifact{}, multihash.MalformedDigestError{Text: value}
+		}
+		artifact.Checksums = []Checksum{{Algorithm: algorithm, Digest: digest}}
+	}
+	return artifact, nil
+}
@@ -0,0 +1,162 @@
+package multihash
+
+import (
+	"hash"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool is a set of long-lived hashing goroutines for services that hash
+// many small buffers, where starting goroutines and allocating hashes for
+// every call would cost more than the hashing itself. Each worker has its
+// own bounded queue, a buffered channel used as a ring buffer, and keeps one
+// instance of every algorithm it has been asked for, resetting it between
+// jobs. A Pool is safe for concurrent use.
+type Pool struct {
+	queues    []chan *poolJob
+	next      uint32
+	wg        sync.WaitGroup
+	shedDelay int64 // nanoseconds; see SetShedDelay
+	now       func() time.Time
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type poolJob struct {
+	data       []byte
+	algorithms []string
+	optional   int       // how many of algorithms, at the end, may be shed
+	queued     time.Time // set only for jobs with optional algorithms
+	digests    [][]byte
+	err        error
+	done       chan struct{}
+}
+
+var poolJobs = sync.Pool{
+	New: func() any {
+		return &poolJob{done: make(chan struct{}, 1)}
+	},
+}
+
+// NewPool starts workers goroutines, runtime.GOMAXPROCS(0) if workers is not
+// positive, each with a queue of queueLength jobs. If pin is set, each
+// worker locks itself to an OS thread for its lifetime, which keeps hashes'
+// state hot in one core's cache at the cost of a thread per worker.
+func NewPool(workers, queueLength int, pin bool) *Pool {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if queueLength < 1 {
+		queueLength = 1
+	}
+	p := &Pool{queues: make([]chan *poolJob, workers), now: time.Now}
+	for index := range p.queues {
+		p.queues[index] = make(chan *poolJob, queueLength)
+		p.wg.Add(1)
+		go p.work(p.queues[index], pin)
+	}
+	return p
+}
+
+func (p *Pool) work(queue chan *poolJob, pin bool) {
+	defer p.wg.Done()
+	if pin {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+	}
+	hashes := map[string]hash.Hash{}
+	for job := range queue {
+		job.digests, job.err = make([][]byte, len(job.algorithms)), nil
+		algorithms := job.algorithms
+		if job.optional > 0 && p.shouldShed(job.queued) {
+			algorithms = algorithms[:len(algorithms)-job.optional]
+		}
+		for index, algorithm := range algorithms {
+			h, ok := hashes[algorithm]
+			if !ok {
+				if h, job.err = New(algorithm); job.err != nil {
+					break
+				}
+				hashes[algorithm] = h
+			}
+			h.Reset()
+			h.Write(job.data)
+			job.digests[index] = h.Sum(nil)
+		}
+		job.done <- struct{}{}
+	}
+}
+
+// Sum hashes data with each of algorithms on one of the pool's workers and
+// returns the digests in the same order. It blocks while that worker's
+// queue is full. After Close, it returns ErrPoolClosed.
+func (p *Pool) Sum(data []byte, algorithms ...string) ([][]byte, error) {
+	return p.sum(data, algorithms, 0)
+}
+
+// SumOptional is Sum for the required algorithms followed by the optional
+// ones, whose digests are left nil if the pool is shedding load when a
+// worker picks the job up; see SetShedDelay. The digests are in the order
+// of required, then optional, so a nil digest records which optional
+// algorithms were skipped. The required digests are always computed.
+func (p *Pool) SumOptional(data []byte, required, optional []string) ([][]byte, error) {
+	algorithms := make([]string, 0, len(required)+len(optional))
+	algorithms = append(append(algorithms, required...), optional...)
+	return p.sum(data, algorithms, len(optional))
+}
+
+// SetShedDelay makes workers skip the optional algorithms of SumOptional
+// jobs that waited longer than delay in their queue, which happens when
+// callers submit work faster than the pool hashes it. Shedding stops once
+// the queues drain enough for jobs to be picked up within delay again. A
+// delay of zero or less, the default, never sheds.
+func (p *Pool) SetShedDelay(delay time.Duration) {
+	atomic.StoreInt64(&p.shedDelay, int64(delay))
+}
+
+func (p *Pool) shouldShed(queued time.Time) bool {
+	delay := time.Duration(atomic.LoadInt64(&p.shedDelay))
+	return delay > 0 && p.now().Sub(queued) > delay
+}
+
+func (p *Pool) sum(data []byte, algorithms []string, optional int) ([][]byte, error) {
+	job := poolJobs.Get().(*poolJob)
+	job.data, job.algorithms, job.optional = data, algorithms, optional
+	if optional > 0 {
+		job.queued = p.now()
+	}
+
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		poolJobs.Put(job)
+		return nil, ErrPoolClosed
+	}
+	p.queues[atomic.AddUint32(&p.next, 1)%uint32(len(p.queues))] <- job
+	p.mu.RUnlock()
+
+	<-job.done
+	digests, err := job.digests, job.err
+	job.data, job.algorithms, job.optional, job.digests, job.err = nil, nil, 0, nil, nil
+	poolJobs.Put(job)
+	if err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// Close stops the workers once their queued jobs are done.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,128 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Pool(t *testing.T) {
+	pool := NewPool(3, 4, true)
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte("message " + strconv.Itoa(i))
+			digests, err := pool.Sum(data, "md5", "sha256")
+			if err != nil {
+				errs <- err
+				return
+			}
+			md5Digest, sha256Digest := md5.Sum(data), sha256.Sum256(data)
+			if !digestsEqual(digests, [][]byte{md5Digest[:], sha256Digest[:]}) {
+				errs <- errors.New("wrong digests for message " + strconv.Itoa(i))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if _, err := pool.Sum(nil, "no-such-algorithm"); !errors.Is(err, ErrHashFunctionNotAvailable) {
+		t.Fatalf("expected an unknown algorithm error, got %v\n", err)
+	}
+	pool.Close()
+	if _, err := pool.Sum(nil, "md5"); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v\n", err)
+	}
+}
+
+// gatedHash blocks every Write until its gate is closed, signalling on
+// started as the first one begins.
+type gatedHash struct {
+	hash.Hash
+	started chan struct{}
+	gate    chan struct{}
+	once    *sync.Once
+}
+
+func (g gatedHash) Write(p []byte) (int, error) {
+	g.once.Do(func() { close(g.started) })
+	<-g.gate
+	return g.Hash.Write(p)
+}
+
+// fakeClock is a clock for a Pool that only moves when told to, signalling
+// on read each time it is read.
+type fakeClock struct {
+	mu      sync.Mutex
+	current time.Time
+	read    chan struct{}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case c.read <- struct{}{}:
+	default:
+	}
+	return c.current
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = c.current.Add(d)
+}
+
+func Test_PoolShedding(t *testing.T) {
+	gated := gatedHash{Hash: sha256.New(), started: make(chan struct{}), gate: make(chan struct{}), once: new(sync.Once)}
+	registerForTest(t, "test-gated", func() hash.Hash { return gated })
+	clock := &fakeClock{current: time.Unix(0, 0), read: make(chan struct{}, 1)}
+	pool := NewPool(1, 2, false)
+	defer pool.Close()
+	pool.now = clock.now
+	pool.SetShedDelay(time.Second)
+
+	// Hold the only worker on a job, queue an optional one behind it, and
+	// let more than the shed delay pass before the worker reaches it.
+	blocked := make(chan error)
+	go func() {
+		_, err := pool.Sum([]byte("blocking"), "test-gated")
+		blocked <- err
+	}()
+	<-gated.started
+	shed := make(chan [][]byte)
+	go func() {
+		digests, _ := pool.SumOptional([]byte("data"), []string{"sha256"}, []string{"md5"})
+		shed <- digests
+	}()
+	// The first read of the clock is the optional job being queued.
+	<-clock.read
+	clock.advance(2 * time.Second)
+	close(gated.gate)
+	if err := <-blocked; err != nil {
+		t.Fatal(err)
+	}
+	digests := <-shed
+	sha256Digest := sha256.Sum256([]byte("data"))
+	if !slicesEqual(digests[0], sha256Digest[:]) || digests[1] != nil {
+		t.Fatalf("expected md5 to be shed behind a blocked job, got %x\n", digests)
+	}
+
+	digests, err := pool.SumOptional([]byte("data"), []string{"sha256"}, []string{"md5"})
+	md5Digest := md5.Sum([]byte("data"))
+	if err != nil || !slicesEqual(digests[1], md5Digest[:]) {
+		t.Fatalf("expected md5 once the queue drained, got %x, %v\n", digests, err)
+	}
+}
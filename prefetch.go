@@ -0,0 +1,136 @@
+package multihash
+
+import (
+	"hash"
+	"io"
+	"sync"
+)
+
+// rangeChunkSize is the size of each ReadAt issued by a PrefetchReader.
+// Remote object stores charge per request in latency, if not in money, so
+// ranges are larger than the buffer used for local reads.
+const rangeChunkSize = 16 * bufferSize // 1 MiB
+
+var rangeChunkPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, rangeChunkSize)
+		return &b
+	},
+}
+
+// FromReaderAt hashes the first size bytes of r like FromReader, but issues
+// up to parallel ReadAt calls for consecutive ranges concurrently, ahead of
+// the hashes consuming them. Chunks are still fed to the hashes strictly in
+// order. This hides the per-request latency of sources such as HTTP range
+// requests against object stores, where sequential reads leave the
+// connection idle most of the time; for local files a parallel of 1 or 2 is
+// enough.
+func FromReaderAt(r io.ReaderAt, size int64, parallel int, hashes ...hash.Hash) (hashset [][]byte, err error) {
+	prefetcher := NewPrefetchReader(r, size, parallel)
+	defer prefetcher.Close()
+	return FromReader(prefetcher, hashes...)
+}
+
+// PrefetchReader presents the first size bytes of an io.ReaderAt as an
+// io.Reader, keeping a number of range reads in flight ahead of the
+// consumer. Close it when done to release the buffers of reads still in
+// flight.
+type PrefetchReader struct {
+	source   io.ReaderAt
+	size     int64
+	next     int64 // offset of the next range to request
+	pending  []chan rangeResult
+	current  rangeResult
+	consumed int
+	err      error
+}
+
+type rangeResult struct {
+	buffer *[]byte
+	n      int
+	err    error
+}
+
+// NewPrefetchReader starts up to parallel concurrent reads from r.
+func NewPrefetchReader(r io.ReaderAt, size int64, parallel int) *PrefetchReader {
+	if parallel < 1 {
+		parallel = 1
+	}
+	p := &PrefetchReader{source: r, size: size}
+	for i := 0; i < parallel && p.next < size; i++ {
+		p.request()
+	}
+	return p
+}
+
+// request starts a read of the next range.
+func (p *PrefetchReader) request() {
+	offset := p.next
+	length := int64(rangeChunkSize)
+	if remaining := p.size - offset; remaining < length {
+		length = remaining
+	}
+	p.next += length
+	result := make(chan rangeResult, 1)
+	p.pending = append(p.pending, result)
+	go func() {
+		buffer := rangeChunkPool.Get().(*[]byte)
+		n, err := p.source.ReadAt((*buffer)[:length], offset)
+		if int64(n) == length {
+			// ReadAt may report io.EOF alongside a read ending exactly at
+			// the end of the source.
+			err = nil
+		} else if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		result <- rangeResult{buffer: buffer, n: n, err: err}
+	}()
+}
+
+func (p *PrefetchReader) Read(b []byte) (n int, err error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+	if p.current.buffer == nil || p.consumed == p.current.n {
+		p.release()
+		if len(p.pending) == 0 {
+			p.err = io.EOF
+			return 0, p.err
+		}
+		p.current = <-p.pending[0]
+		p.pending = p.pending[1:]
+		p.consumed = 0
+		if p.current.err != nil {
+			p.err = p.current.err
+			return 0, p.err
+		}
+		if p.next < p.size {
+			p.request()
+		}
+	}
+	n = copy(b, (*p.current.buffer)[p.consumed:p.current.n])
+	p.consumed += n
+	return n, nil
+}
+
+func (p *PrefetchReader) release() {
+	if p.current.buffer != nil {
+		rangeChunkPool.Put(p.current.buffer)
+		p.current.buffer = nil
+	}
+}
+
+// Close waits for reads in flight and returns their buffers to the pool.
+func (p *PrefetchReader) Close() error {
+	p.release()
+	for _, pending := range p.pending {
+		if result := <-pending; result.buffer != nil {
+			rangeChunkPool.Put(result.buffer)
+		}
+	}
+	p.pending = nil
+	if p.err == nil {
+		p.err = io.ErrClosedPipe
+	}
+	return nil
+}
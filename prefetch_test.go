@@ -0,0 +1,52 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowReaderAt delays every ReadAt, recording how many overlap.
+type slowReaderAt struct {
+	data     []byte
+	inFlight int32
+	peak     int32
+}
+
+func (s *slowReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	current := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&s.peak)
+		if current <= peak || atomic.CompareAndSwapInt32(&s.peak, peak, current) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return bytes.NewReader(s.data).ReadAt(p, offset)
+}
+
+func Test_FromReaderAt(t *testing.T) {
+	data := bytes.Repeat([]byte("ranged "), 1500000)
+	source := &slowReaderAt{data: data}
+	hashset, err := FromReaderAt(source, int64(len(data)), 4, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := sha256.Sum256(data)
+	if !slicesEqual(hashset[0], expected[:]) {
+		t.Fatalf("digest was %x, expected %x\n", hashset[0], expected)
+	}
+	if source.peak < 2 {
+		t.Fatalf("range reads did not overlap; peak concurrency %d\n", source.peak)
+	}
+
+	_, err = FromReaderAt(bytes.NewReader(data[:1000]), int64(len(data)), 2, sha256.New())
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a short source to fail, got %v\n", err)
+	}
+}
@@ -0,0 +1,41 @@
+package multihash
+
+import "io"
+
+// ProgressReader passes reads through from an underlying reader, calling a
+// hook with the number of bytes read so far after every Read that returns
+// data, to drive a progress bar during a long hash. For a file, pass its
+// size as the total:
+//
+//	info, err := f.Stat()
+//	…
+//	hashset, err := FromReaderContext(ctx, NewProgressReader(f, info.Size(), onProgress), hashes...)
+//
+// The hook runs on the goroutine reading, between Reads, so it should be
+// quick; one that updates a UI can cancel the hash through the context.
+type ProgressReader struct {
+	r          io.Reader
+	total      int64
+	read       int64
+	onProgress func(bytesRead, totalBytes int64)
+}
+
+// NewProgressReader returns a ProgressReader reading from r and reporting
+// to onProgress. total is passed through to onProgress as given; -1 is a
+// conventional value for a length that is not known. A nil onProgress
+// reports nothing.
+func NewProgressReader(r io.Reader, total int64, onProgress func(bytesRead, totalBytes int64)) *ProgressReader {
+	if onProgress == nil {
+		onProgress = func(int64, int64) {}
+	}
+	return &ProgressReader{r: r, total: total, onProgress: onProgress}
+}
+
+func (p *ProgressReader) Read(b []byte) (n int, err error) {
+	n, err = p.r.Read(b)
+	if n > 0 {
+		p.read += int64(n)
+		p.onProgress(p.read, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package multihash
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func Test_ProgressReader(t *testing.T) {
+	data := bytes.Repeat([]byte("progress"), 50000)
+	var reports []int64
+	progress := NewProgressReader(bytes.NewReader(data), int64(len(data)), func(bytesRead, totalBytes int64) {
+		if totalBytes != int64(len(data)) {
+			t.Fatalf("total reported as %d\n", totalBytes)
+		}
+		reports = append(reports, bytesRead)
+	})
+	if _, err := FromReaderContext(context.Background(), progress, sha256.New()); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) < 2 || reports[len(reports)-1] != int64(len(data)) {
+		t.Fatalf("unexpected progress reports %v\n", reports)
+	}
+
+	// Cancelling from the hook stops the hash before the end of input.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var last int64
+	progress = NewProgressReader(bytes.NewReader(data), int64(len(data)), func(bytesRead, totalBytes int64) {
+		last = bytesRead
+		cancel()
+	})
+	if _, err := FromReaderContext(ctx, progress, sha256.New()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v\n", err)
+	}
+	if last == int64(len(data)) {
+		t.Fatal("the whole input was read despite cancellation")
+	}
+
+	if _, err := FromReader(NewProgressReader(bytes.NewReader(data), -1, nil), sha256.New()); err != nil {
+		t.Fatalf("reading without a hook gave %v\n", err)
+	}
+}
@@ -0,0 +1,83 @@
+// package quickxor implements Microsoft's QuickXorHash, the content hash
+// OneDrive and SharePoint report for files. Importing it registers the
+// algorithm with multihash as "quickxor".
+//
+// Each input byte is XORed into a 160-bit circular register at a bit offset
+// advancing by 11 for every byte, and the total length is XORed into the
+// last 64 bits. The service reports the digest base64 encoded.
+package quickxor
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"github.com/trytriangles/multihash"
+)
+
+// Size is the size of the hash in bytes.
+const Size = 20
+
+// BlockSize is the block size of the hash in bytes. The algorithm has no
+// inherent block size, so this is the usual 64; the bit offsets themselves
+// only repeat every 160 bytes, widthInBits.
+const BlockSize = 64
+
+const (
+	widthInBits = Size * 8
+	shift       = 11
+)
+
+func init() {
+	multihash.Register("quickxor", New)
+}
+
+type digest struct {
+	// Every widthInBits bytes the bit offset returns to where it started,
+	// so bytes are folded into one of widthInBits lanes by position and only
+	// placed into the register when the sum is taken.
+	lanes  [widthInBits]byte
+	length uint64
+}
+
+// New returns a new hash.Hash computing QuickXorHash.
+func New() hash.Hash {
+	return &digest{}
+}
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Reset() {
+	*d = digest{}
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	lane := int(d.length % widthInBits)
+	for _, b := range p {
+		d.lanes[lane] ^= b
+		lane++
+		if lane == widthInBits {
+			lane = 0
+		}
+	}
+	d.length += uint64(len(p))
+	return len(p), nil
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	var register [Size]byte
+	for lane, value := range d.lanes {
+		bit := lane * shift % widthInBits
+		register[bit/8] ^= value << (bit % 8)
+		if bit%8 != 0 {
+			register[(bit/8+1)%Size] ^= value >> (8 - bit%8)
+		}
+	}
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], d.length)
+	for i, value := range length {
+		register[Size-len(length)+i] ^= value
+	}
+	return append(b, register[:]...)
+}
@@ -0,0 +1,111 @@
+package quickxor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/trytriangles/multihash"
+)
+
+// reference is a direct port of the cell-based reference implementation
+// Microsoft publishes, against which the lane-folding version is checked.
+func reference(chunks [][]byte) []byte {
+	const bitsInLastCell = widthInBits % 64
+	data := make([]uint64, (widthInBits-1)/64+1)
+	shiftSoFar, lengthSoFar := 0, 0
+	for _, array := range chunks {
+		cbSize := len(array)
+		vectorArrayIndex := shiftSoFar / 64
+		vectorOffset := shiftSoFar % 64
+		iterations := cbSize
+		if iterations > widthInBits {
+			iterations = widthInBits
+		}
+		for i := 0; i < iterations; i++ {
+			isLastCell := vectorArrayIndex == len(data)-1
+			bitsInVectorCell := 64
+			if isLastCell {
+				bitsInVectorCell = bitsInLastCell
+			}
+			if vectorOffset <= bitsInVectorCell-8 {
+				for j := i; j < cbSize; j += widthInBits {
+					data[vectorArrayIndex] ^= uint64(array[j]) << vectorOffset
+				}
+			} else {
+				index1 := vectorArrayIndex
+				index2 := vectorArrayIndex + 1
+				if isLastCell {
+					index2 = 0
+				}
+				low := bitsInVectorCell - vectorOffset
+				var xored byte
+				for j := i; j < cbSize; j += widthInBits {
+					xored ^= array[j]
+				}
+				data[index1] ^= uint64(xored) << vectorOffset
+				data[index2] ^= uint64(xored) >> low
+			}
+			vectorOffset += shift
+			for vectorOffset >= bitsInVectorCell {
+				if isLastCell {
+					vectorArrayIndex = 0
+				} else {
+					vectorArrayIndex++
+				}
+				vectorOffset -= bitsInVectorCell
+			}
+		}
+		shiftSoFar = (shiftSoFar + shift*(cbSize%widthInBits)) % widthInBits
+		lengthSoFar += cbSize
+	}
+	result := make([]byte, 24)
+	for i, cell := range data {
+		binary.LittleEndian.PutUint64(result[i*8:], cell)
+	}
+	result = result[:Size]
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(lengthSoFar))
+	for i := range length {
+		result[Size-8+i] ^= length[i]
+	}
+	return result
+}
+
+func Test_knownValues(t *testing.T) {
+	for input, expected := range map[string]string{
+		"":  "AAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+		"a": "YQAAAAAAAAAAAAAAAQAAAAAAAAA=",
+	} {
+		h := New()
+		h.Write([]byte(input))
+		if sum := base64.StdEncoding.EncodeToString(h.Sum(nil)); sum != expected {
+			t.Fatalf("QuickXorHash of %q was %s, expected %s\n", input, sum, expected)
+		}
+	}
+}
+
+func Test_matchesReference(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		var chunks [][]byte
+		h := New()
+		for c := random.Intn(6); c >= 0; c-- {
+			chunk := make([]byte, random.Intn(1000))
+			random.Read(chunk)
+			chunks = append(chunks, chunk)
+			h.Write(chunk)
+		}
+		if sum, expected := h.Sum(nil), reference(chunks); !bytes.Equal(sum, expected) {
+			t.Fatalf("trial %d: sum was %x, reference %x\n", trial, sum, expected)
+		}
+	}
+}
+
+func Test_registered(t *testing.T) {
+	if _, err := multihash.New("quickxor"); err != nil {
+		t.Fatal(err)
+	}
+}
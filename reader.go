@@ -0,0 +1,42 @@
+package multihash
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// VerifyingReader passes reads through from an underlying reader while
+// hashing them, and at the end of input checks the digest against an
+// expected value. On a mismatch, the final Read returns a
+// DigestMismatchError instead of io.EOF, so a consumer that reads to the end
+// cannot mistake corrupt data for good data.
+type VerifyingReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected []byte
+	err      error
+}
+
+// NewVerifyingReader returns a VerifyingReader checking that r's content has
+// digest expected under h. h should be freshly constructed or Reset.
+func NewVerifyingReader(r io.Reader, h hash.Hash, expected []byte) *VerifyingReader {
+	return &VerifyingReader{r: r, hash: h, expected: expected}
+}
+
+func (v *VerifyingReader) Read(p []byte) (n int, err error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	n, err = v.r.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF {
+		if actual := v.hash.Sum(nil); !bytes.Equal(actual, v.expected) {
+			err = DigestMismatchError{Expected: v.expected, Actual: actual}
+		}
+	}
+	if err != nil {
+		v.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,22 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"io"
+	"testing"
+)
+
+func Test_VerifyingReader(t *testing.T) {
+	data := []byte("Sample text file\n")
+	sum := md5.Sum(data)
+	read, err := io.ReadAll(NewVerifyingReader(bytes.NewReader(data), md5.New(), sum[:]))
+	if err != nil || !bytes.Equal(read, data) {
+		t.Fatalf("read %q, %v\n", read, err)
+	}
+	_, err = io.ReadAll(NewVerifyingReader(bytes.NewReader(data[1:]), md5.New(), sum[:]))
+	if !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected a digest mismatch, got %v\n", err)
+	}
+}
@@ -0,0 +1,154 @@
+package multihash
+
+import (
+	"crypto"
+	"hash"
+	"hash/crc32"
+	"sort"
+	"sync"
+)
+
+// cryptoHashes maps algorithm names to the crypto.Hash implementing them.
+// These are available by name as soon as their implementation is linked into
+// the binary, typically by blank-importing e.g. crypto/sha256.
+var cryptoHashes = map[string]crypto.Hash{
+	"md5":         crypto.MD5,
+	"sha1":        crypto.SHA1,
+	"sha224":      crypto.SHA224,
+	"sha256":      crypto.SHA256,
+	"sha384":      crypto.SHA384,
+	"sha512":      crypto.SHA512,
+	"sha512-224":  crypto.SHA512_224,
+	"sha512-256":  crypto.SHA512_256,
+	"sha3-224":    crypto.SHA3_224,
+	"sha3-256":    crypto.SHA3_256,
+	"sha3-384":    crypto.SHA3_384,
+	"sha3-512":    crypto.SHA3_512,
+	"blake2s-256": crypto.BLAKE2s_256,
+	"blake2b-256": crypto.BLAKE2b_256,
+	"blake2b-384": crypto.BLAKE2b_384,
+	"blake2b-512": crypto.BLAKE2b_512,
+	"ripemd160":   crypto.RIPEMD160,
+}
+
+// hashAliases maps other names New accepts to registry names. FIPS 180-4
+// names the truncated SHA-512 variants "SHA-512/224" and "SHA-512/256",
+// which would otherwise read as truncations of SHA-512 and give different
+// digests.
+var hashAliases = map[string]string{
+	"sha512/224": "sha512-224",
+	"sha512/256": "sha512-256",
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]func() hash.Hash{
+		"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+		"crc32c": func() hash.Hash { return NewCRC32C() },
+	}
+)
+
+// Register makes a hash function available under name to New and to the
+// functions in this package and its subpackages that take algorithm names.
+// Packages implementing non-standard algorithms call it from an init
+// function, so that blank-importing them is enough to make them available,
+// in the same way as with crypto.RegisterHash. Registering a name twice
+// replaces the earlier registration.
+func Register(name string, newHash func() hash.Hash) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[name] = newHash
+}
+
+// New returns a new hash.Hash computing the algorithm registered as name.
+// Names are lower case, e.g. "sha256" or "crc32c". If name is a standard
+// crypto.Hash whose implementation is not linked into the binary, the error
+// is an UnavailableHashFunctionError; if name is not known at all, it is an
+// UnknownAlgorithmError. Both match ErrHashFunctionNotAvailable.
+//
+// A name of the form "sha256/64" asks for a digest truncated to its leading
+// 64 bits, as returned by Truncate, and one of the form "sha256+text" for
+// the digest of text with normalized line endings, as returned by
+// NormalizeLineEndings; see there. "sha256+normalized" also strips a
+// leading byte order mark, as NormalizeText does. Registered names are
+// looked up first, so "sha512/256" is SHA-512/256, with its own initial
+// values, rather than SHA-512 truncated, the same as "sha512-256".
+func New(name string) (hash.Hash, error) {
+	if canonical, ok := hashAliases[name]; ok {
+		name = canonical
+	}
+	registryLock.RLock()
+	newHash, ok := registry[name]
+	registryLock.RUnlock()
+	if ok {
+		return newHash(), nil
+	}
+	if h, ok := cryptoHashes[name]; ok {
+		if !h.Available() {
+			return nil, UnavailableHashFunctionError{Hash: h}
+		}
+		return h.New(), nil
+	}
+	if base, normalized, ok := parseTextName(name); ok {
+		h, err := New(base)
+		if err != nil {
+			return nil, err
+		}
+		if normalized {
+			return NormalizeText(h), nil
+		}
+		return NormalizeLineEndings(h), nil
+	}
+	if base, bits, ok := parseTruncatedName(name); ok {
+		h, err := New(base)
+		if err != nil {
+			return nil, err
+		}
+		return Truncate(h, bits)
+	}
+	return nil, UnknownAlgorithmError{Name: name}
+}
+
+// NewAll calls New for each of names, returning the hashes in the same order.
+func NewAll(names ...string) ([]hash.Hash, error) {
+	hashes := make([]hash.Hash, len(names))
+	for index, name := range names {
+		h, err := New(name)
+		if err != nil {
+			return nil, err
+		}
+		hashes[index] = h
+	}
+	return hashes, nil
+}
+
+// Algorithms returns the sorted names of every algorithm New can currently
+// construct.
+func Algorithms() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	names := make([]string, 0, len(registry)+len(cryptoHashes))
+	for name := range registry {
+		names = append(names, name)
+	}
+	for name, h := range cryptoHashes {
+		if _, ok := registry[name]; !ok && h.Available() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hashName maps a crypto.Hash to its registry name, e.g. crypto.SHA256 to
+// "sha256".
+func hashName(h crypto.Hash) string {
+	for name, candidate := range cryptoHashes {
+		if candidate == h {
+			return name
+		}
+	}
+	return h.String()
+}
@@ -0,0 +1,57 @@
+package multihash
+
+import (
+	"crypto"
+	"errors"
+	"hash"
+	"hash/fnv"
+	"testing"
+
+	_ "crypto/sha256"
+)
+
+func Test_registry(t *testing.T) {
+	h, err := New("sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Size() != crypto.SHA256.Size() {
+		t.Fatalf("sha256 had size %d\n", h.Size())
+	}
+
+	_, err = New("no-such-algorithm")
+	var unknown UnknownAlgorithmError
+	if !errors.Is(err, ErrHashFunctionNotAvailable) || !errors.As(err, &unknown) {
+		t.Fatalf("expected an unknown algorithm error, got %v\n", err)
+	}
+
+	registerForTest(t, "fnv64a", func() hash.Hash { return fnv.New64a() })
+	found := false
+	for _, name := range Algorithms() {
+		found = found || name == "fnv64a"
+	}
+	if !found {
+		t.Fatal("registered algorithm missing from Algorithms()")
+	}
+	if _, err = NewAll("crc32c", "fnv64a"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// registerForTest registers newHash under name for the rest of the test,
+// restoring whatever was registered under it before once the test ends.
+func registerForTest(t *testing.T, name string, newHash func() hash.Hash) {
+	registryLock.RLock()
+	previous, registered := registry[name]
+	registryLock.RUnlock()
+	Register(name, newHash)
+	t.Cleanup(func() {
+		registryLock.Lock()
+		defer registryLock.Unlock()
+		if registered {
+			registry[name] = previous
+		} else {
+			delete(registry, name)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package release
+
+import (
+	"strconv"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+var ErrBadSignature = errcode.New(errcode.Mismatch, "signature does not verify")
+var ErrDuplicateArtifact = errcode.New(errcode.Conflict, "artifacts share a base name")
+
+type DuplicateArtifactError struct {
+	Name  string
+	Paths []string
+}
+
+func (e DuplicateArtifactError) Error() string {
+	message := "artifacts share the base name " + strconv.Quote(e.Name)
+	for index, artifact := range e.Paths {
+		if index == 0 {
+			message += ": "
+		} else {
+			message += ", "
+		}
+		message += strconv.Quote(artifact)
+	}
+	return message
+}
+
+func (e DuplicateArtifactError) Is(target error) bool {
+	return target == ErrDuplicateArtifact
+}
+
+func (e DuplicateArtifactError) Code() errcode.Code {
+	return errcode.Conflict
+}
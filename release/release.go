@@ -0,0 +1,153 @@
+// package release produces the checksum files that accompany a software
+// release: SHA256SUMS and optionally SHA512SUMS, Subresource Integrity
+// values for each artifact, and detached signatures over the checksum files.
+package release
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+
+	_ "crypto/sha512"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/atomicfile"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// Options configure a bundle.
+type Options struct {
+	// SHA512 adds SHA512SUMS alongside SHA256SUMS, and SHA-512 values to
+	// the SRI strings.
+	SHA512 bool
+	// Signer, if set, signs each checksum file, writing the signature to
+	// the same name with ".sig" appended. Ed25519 keys sign the file's
+	// contents directly; other keys sign its SHA-256 digest.
+	Signer crypto.Signer
+	// Durability controls syncing as each file is atomically written; see
+	// atomicfile. The zero value syncs every file before publishing it.
+	Durability atomicfile.Durability
+}
+
+// Bundle describes what Create wrote.
+type Bundle struct {
+	// Manifest holds the digests of every artifact, listed by base name.
+	Manifest *manifest.Manifest
+	// SRI maps each artifact's base name to its Subresource Integrity
+	// string, with one value per algorithm separated by spaces.
+	SRI map[string]string
+	// Files lists the paths of every file written.
+	Files []string
+
+	durability atomicfile.Durability
+}
+
+// Create hashes artifacts in a single read each and writes the release
+// checksum files into dir: SHA256SUMS, SHA512SUMS if requested, integrity.json
+// with the SRI strings, and a .sig for each checksum file if a signer is
+// given. Artifacts are listed by base name, sorted, as is conventional for
+// checksum files published next to the artifacts themselves, so two
+// artifacts with the same base name are a DuplicateArtifactError, returned
+// before anything is written.
+func Create(dir string, artifacts []string, opts Options) (*Bundle, error) {
+	algorithms := []string{"sha256"}
+	if opts.SHA512 {
+		algorithms = append(algorithms, "sha512")
+	}
+	sorted := append([]string(nil), artifacts...)
+	sort.Slice(sorted, func(i, j int) bool { return filepath.Base(sorted[i]) < filepath.Base(sorted[j]) })
+	for index := 1; index < len(sorted); index++ {
+		if name := filepath.Base(sorted[index]); name == filepath.Base(sorted[index-1]) {
+			return nil, DuplicateArtifactError{Name: name, Paths: []string{sorted[index-1], sorted[index]}}
+		}
+	}
+
+	bundle := &Bundle{Manifest: &manifest.Manifest{Algorithms: algorithms}, SRI: make(map[string]string), durability: opts.Durability}
+	for _, artifact := range sorted {
+		hashes, err := multihash.NewAll(algorithms...)
+		if err != nil {
+			return nil, err
+		}
+		digests, err := multihash.FromFile(artifact, hashes...)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(artifact)
+		bundle.Manifest.Entries = append(bundle.Manifest.Entries, manifest.Entry{Path: name, Digests: digests})
+		var sri bytes.Buffer
+		for index, algorithm := range algorithms {
+			if index > 0 {
+				sri.WriteByte(' ')
+			}
+			sri.WriteString(multihash.FormatSRI(algorithm, digests[index]))
+		}
+		bundle.SRI[name] = sri.String()
+	}
+
+	for _, algorithm := range algorithms {
+		var sums bytes.Buffer
+		if err := bundle.Manifest.WriteGNU(&sums, algorithm); err != nil {
+			return nil, err
+		}
+		name := filepath.Join(dir, sumsName(algorithm))
+		if err := bundle.write(name, sums.Bytes()); err != nil {
+			return nil, err
+		}
+		if opts.Signer != nil {
+			signature, err := Sign(opts.Signer, sums.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			if err = bundle.write(name+".sig", signature); err != nil {
+				return nil, err
+			}
+		}
+	}
+	integrity, err := json.MarshalIndent(bundle.SRI, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = bundle.write(filepath.Join(dir, "integrity.json"), append(integrity, '\n')); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+func (b *Bundle) write(name string, data []byte) error {
+	if err := atomicfile.WriteFile(name, data, 0o644, b.durability); err != nil {
+		return err
+	}
+	b.Files = append(b.Files, name)
+	return nil
+}
+
+func sumsName(algorithm string) string {
+	switch algorithm {
+	case "sha512":
+		return "SHA512SUMS"
+	}
+	return "SHA256SUMS"
+}
+
+// Sign produces a detached signature over data as Create does.
+func Sign(signer crypto.Signer, data []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(data)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// VerifyEd25519 checks a signature made by Sign with an Ed25519 key, for
+// passing to multihash.ChecksumSource.VerifySignature via a closure.
+func VerifyEd25519(publicKey ed25519.PublicKey, data, signature []byte) error {
+	if !ed25519.Verify(publicKey, data, signature) {
+		return ErrBadSignature
+	}
+	return nil
+}
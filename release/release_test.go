@@ -0,0 +1,68 @@
+package release
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+func Test_Create(t *testing.T) {
+	dir := t.TempDir()
+	artifacts := []string{filepath.Join(dir, "tool-linux.tar.gz"), filepath.Join(dir, "tool-darwin.tar.gz")}
+	for _, artifact := range artifacts {
+		if err := os.WriteFile(artifact, []byte("build of "+artifact), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := Create(dir, artifacts, Options{SHA512: true, Signer: private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Files) != 5 {
+		t.Fatalf("unexpected files written: %v\n", bundle.Files)
+	}
+
+	sums, err := os.ReadFile(filepath.Join(dir, "SHA256SUMS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.SplitN(string(sums), "\n", 2)[0], "  tool-darwin.tar.gz") {
+		t.Fatalf("SHA256SUMS is not sorted by name:\n%s", sums)
+	}
+	signature, err := os.ReadFile(filepath.Join(dir, "SHA256SUMS.sig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyEd25519(public, sums, signature); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyEd25519(public, append(sums, '\n'), signature); err != ErrBadSignature {
+		t.Fatalf("expected a tampered file to fail verification, got %v\n", err)
+	}
+
+	parsed, err := manifest.ParseGNU(strings.NewReader(string(sums)), "sha256")
+	if err != nil || len(parsed.Entries) != 2 {
+		t.Fatalf("SHA256SUMS did not parse: %v\n", err)
+	}
+	if sri := bundle.SRI["tool-linux.tar.gz"]; !strings.HasPrefix(sri, "sha256-") || !strings.Contains(sri, " sha512-") {
+		t.Fatalf("SRI string was %q\n", sri)
+	}
+
+	other := filepath.Join(dir, "other")
+	os.Mkdir(other, 0o755)
+	if err = os.WriteFile(filepath.Join(other, "tool-linux.tar.gz"), []byte("another build"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Create(other, append(artifacts, filepath.Join(other, "tool-linux.tar.gz")), Options{}); !errors.Is(err, ErrDuplicateArtifact) {
+		t.Fatalf("artifacts sharing a base name gave %v\n", err)
+	}
+}
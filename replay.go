@@ -0,0 +1,120 @@
+package multihash
+
+import (
+	"bufio"
+	"encoding/json"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ReadEvent is one entry of a replay log: a single Read call as the pipeline
+// made it and as the underlying reader answered it. The data itself is not
+// logged, only its CRC-32C, so logs are small and safe to attach to a bug
+// report; replaying needs the original input alongside.
+type ReadEvent struct {
+	Sequence  int    `json:"seq"`
+	Requested int    `json:"requested"`
+	Returned  int    `json:"returned"`
+	Err       string `json:"err,omitempty"`
+	CRC32C    uint32 `json:"crc32c"`
+}
+
+// RecordingReader passes reads through from an underlying reader unchanged,
+// writing a ReadEvent for each to a replay log as a JSON line. Wrapping the
+// input of FromReader in one captures the buffer sizes, chunk sequence and
+// read errors behind a digest that cannot otherwise be reproduced, for
+// Replay to run through the pipeline again later.
+type RecordingReader struct {
+	r        io.Reader
+	log      *json.Encoder
+	sequence int
+	err      error
+}
+
+// NewRecordingReader returns a RecordingReader reading from r and logging to
+// log.
+func NewRecordingReader(r io.Reader, log io.Writer) *RecordingReader {
+	return &RecordingReader{r: r, log: json.NewEncoder(log)}
+}
+
+func (rec *RecordingReader) Read(p []byte) (n int, err error) {
+	n, err = rec.r.Read(p)
+	event := ReadEvent{
+		Sequence:  rec.sequence,
+		Requested: len(p),
+		Returned:  n,
+		CRC32C:    crc32.Checksum(p[:n], castagnoliTable),
+	}
+	if err != nil {
+		event.Err = err.Error()
+	}
+	rec.sequence++
+	// Recording must not change what the reader returns, so a failure to
+	// write the log is kept for Err rather than returned here.
+	if logErr := rec.log.Encode(event); logErr != nil && rec.err == nil {
+		rec.err = logErr
+	}
+	return n, err
+}
+
+// Err returns the first error met writing the replay log, if any.
+func (rec *RecordingReader) Err() error {
+	return rec.err
+}
+
+// Replay runs data through FromReader with hashes, reproducing the read
+// sizes and errors recorded in log by a RecordingReader: each Read returns
+// exactly as many bytes as it did when recorded, and the recorded errors
+// reappear at the same points, with io.EOF and io.ErrUnexpectedEOF restored
+// as themselves so that the pipeline treats them as it did originally.
+//
+// If data does not match the recording, or the pipeline now reads with a
+// buffer too small for a recorded chunk, the error is a
+// ReplayDivergedError, matching ErrReplayDiverged. Other recorded errors
+// reappear as a ReplayedReadError with the original text, and a log that
+// does not decode gives a MalformedReplayLogError.
+func Replay(log io.Reader, data io.Reader, hashes ...hash.Hash) ([][]byte, error) {
+	return FromReader(&replayReader{events: json.NewDecoder(bufio.NewReader(log)), data: data}, hashes...)
+}
+
+type replayReader struct {
+	events *json.Decoder
+	data   io.Reader
+	// decoded counts the events read, to locate a malformed one.
+	decoded int
+}
+
+func (r *replayReader) Read(p []byte) (int, error) {
+	var event ReadEvent
+	if err := r.events.Decode(&event); err != nil {
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, MalformedReplayLogError{Sequence: r.decoded, Reason: err.Error()}
+	}
+	r.decoded++
+	if event.Returned > len(p) {
+		return 0, ReplayDivergedError{Sequence: event.Sequence, Reason: "buffer smaller than recorded chunk"}
+	}
+	n, err := io.ReadFull(r.data, p[:event.Returned])
+	if err != nil {
+		return n, ReplayDivergedError{Sequence: event.Sequence, Reason: "input shorter than recorded"}
+	}
+	if crc32.Checksum(p[:n], castagnoliTable) != event.CRC32C {
+		return n, ReplayDivergedError{Sequence: event.Sequence, Reason: "input differs from recorded chunk"}
+	}
+	return n, replayedError(event.Sequence, event.Err)
+}
+
+func replayedError(sequence int, text string) error {
+	switch text {
+	case "":
+		return nil
+	case io.EOF.Error():
+		return io.EOF
+	case io.ErrUnexpectedEOF.Error():
+		return io.ErrUnexpectedEOF
+	}
+	return ReplayedReadError{Sequence: sequence, Text: text}
+}
@@ -0,0 +1,59 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/trytriangles/multihash/errcode"
+)
+
+func Test_Replay(t *testing.T) {
+	data := strings.Repeat("Sample text file\n", 10000)
+	var log bytes.Buffer
+	recorder := NewRecordingReader(iotest.HalfReader(strings.NewReader(data)), &log)
+	recorded, err := FromReader(recorder, md5.New(), sha256.New())
+	if err != nil || recorder.Err() != nil {
+		t.Fatal(err, recorder.Err())
+	}
+	if strings.Count(log.String(), "\n") < 2 {
+		t.Fatalf("log recorded too few reads: %s\n", log.String())
+	}
+
+	replayed, err := Replay(bytes.NewReader(log.Bytes()), strings.NewReader(data), md5.New(), sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := range recorded {
+		if !slicesEqual(recorded[index], replayed[index]) {
+			t.Fatalf("replayed digest %d was %x, recorded %x\n", index, replayed[index], recorded[index])
+		}
+	}
+
+	altered := "X" + data[1:]
+	if _, err = Replay(bytes.NewReader(log.Bytes()), strings.NewReader(altered), md5.New()); !errors.Is(err, ErrReplayDiverged) {
+		t.Fatalf("replaying altered input gave %v\n", err)
+	}
+}
+
+func Test_ReplayError(t *testing.T) {
+	var log bytes.Buffer
+	recorder := NewRecordingReader(iotest.TimeoutReader(strings.NewReader("Sample text file\n")), &log)
+	if _, err := FromReader(recorder, md5.New()); err != iotest.ErrTimeout {
+		t.Fatalf("recording gave %v\n", err)
+	}
+
+	_, err := Replay(bytes.NewReader(log.Bytes()), strings.NewReader("Sample text file\n"), md5.New())
+	if !errors.Is(err, ErrReplayedRead) || err.Error() != iotest.ErrTimeout.Error() || errcode.Of(err) != errcode.ReadFailed {
+		t.Fatalf("replay gave %v, expected the recorded timeout\n", err)
+	}
+
+	_, err = Replay(strings.NewReader("{\"seq\":0,"), strings.NewReader("Sample text file\n"), md5.New())
+	if !errors.Is(err, ErrMalformedReplayLog) {
+		t.Fatalf("replaying a corrupt log gave %v\n", err)
+	}
+}
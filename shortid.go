@@ -0,0 +1,123 @@
+package multihash
+
+import (
+	"bytes"
+	"encoding/base32"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// IDEncoding selects the alphabet of short content IDs.
+type IDEncoding int
+
+const (
+	// Base32 uses the lower-case RFC 4648 alphabet without padding, which
+	// survives case-insensitive handling such as DNS names.
+	Base32 IDEncoding = iota
+	// Base58 uses the Bitcoin alphabet, omitting 0, O, I and l, for IDs
+	// that are short and unambiguous to read aloud or copy by hand.
+	Base58
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var lowerBase32 = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ShortID encodes digest in encoding and keeps the first length characters,
+// for use in URLs and file names. Each character carries 5 bits in Base32
+// and about 5.86 in Base58, so a length of 12 gives roughly 60 to 70 bits:
+// plenty to tell apart millions of objects, but not enough to rely on
+// without checking for collisions, which is what IDIndex is for. If the
+// encoded digest is shorter than length it is returned whole, and a length
+// of zero or less gives "".
+func ShortID(digest []byte, length int, encoding IDEncoding) string {
+	if length <= 0 {
+		return ""
+	}
+	var encoded string
+	switch encoding {
+	case Base58:
+		encoded = encodeBase58(digest)
+	default:
+		encoded = lowerBase32.EncodeToString(digest)
+	}
+	if length < len(encoded) {
+		encoded = encoded[:length]
+	}
+	return encoded
+}
+
+func encodeBase58(digest []byte) string {
+	n := new(big.Int).SetBytes(digest)
+	radix := big.NewInt(58)
+	remainder := new(big.Int)
+	var reversed []byte
+	for n.Sign() > 0 {
+		n.QuoRem(n, radix, remainder)
+		reversed = append(reversed, base58Alphabet[remainder.Int64()])
+	}
+	for _, b := range digest {
+		if b != 0 {
+			break
+		}
+		reversed = append(reversed, base58Alphabet[0])
+	}
+	var b strings.Builder
+	for i := len(reversed) - 1; i >= 0; i-- {
+		b.WriteByte(reversed[i])
+	}
+	return b.String()
+}
+
+// IDIndex hands out collision-checked short IDs, remembering which digest
+// each ID was given to. It is safe for concurrent use.
+type IDIndex struct {
+	Length   int
+	Encoding IDEncoding
+
+	lock sync.Mutex
+	ids  map[string][]byte
+}
+
+// Assign returns the ID for digest: the first Length characters of its
+// encoding if no other digest already holds them, otherwise the shortest
+// longer prefix that is free, as git does when abbreviating object names.
+// The same digest always gets back the ID first assigned to it. An error is
+// returned only if even the full encoding is taken, which for a
+// cryptographic digest means an actual collision. A Length below 1, as in
+// the zero IDIndex, counts as 1.
+func (x *IDIndex) Assign(digest []byte) (string, error) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.ids == nil {
+		x.ids = make(map[string][]byte)
+	}
+	full := ShortID(digest, len(digest)*2, x.Encoding)
+	start := x.Length
+	if start < 1 {
+		start = 1
+	}
+	for length := start; ; length++ {
+		id := ShortID(digest, length, x.Encoding)
+		holder, taken := x.ids[id]
+		if !taken {
+			x.ids[id] = digest
+			return id, nil
+		}
+		if bytes.Equal(holder, digest) {
+			return id, nil
+		}
+		if id == full {
+			return "", IDCollisionError{ID: id}
+		}
+	}
+}
+
+// Lookup returns the digest an ID was assigned to.
+func (x *IDIndex) Lookup(id string) (digest []byte, ok bool) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	digest, ok = x.ids[id]
+	return
+}
@@ -0,0 +1,61 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func Test_ShortID(t *testing.T) {
+	// Reference values from the widely used Bitcoin base58 test vectors.
+	if encoded := encodeBase58([]byte{0x00, 0x00, 0x28, 0x7f, 0xb4, 0xcd}); encoded != "11233QC4" {
+		t.Fatalf("base58 encoding was %s\n", encoded)
+	}
+	if encoded := encodeBase58([]byte("Hello World!")); encoded != "2NEpo7TZRRrLZSi2U" {
+		t.Fatalf("base58 encoding was %s\n", encoded)
+	}
+	digest := sha256.Sum256([]byte("Sample text file\n"))
+	if id := ShortID(digest[:], 10, Base32); len(id) != 10 || id != lowerBase32.EncodeToString(digest[:])[:10] {
+		t.Fatalf("base32 ID was %s\n", id)
+	}
+	if id := ShortID(digest[:], -1, Base58); id != "" {
+		t.Fatalf("negative length gave ID %q\n", id)
+	}
+}
+
+func Test_IDIndex(t *testing.T) {
+	index := &IDIndex{Length: 2, Encoding: Base32}
+	// Two digests agreeing in their first byte share the first base32
+	// character, and here the second as well.
+	a := []byte{0x12, 0x30, 0x00}
+	b := []byte{0x12, 0x31, 0x00}
+	idA, err := index.Assign(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := index.Assign(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idA == idB || len(idB) <= len(idA) {
+		t.Fatalf("colliding prefixes were not lengthened: %s, %s\n", idA, idB)
+	}
+	if again, _ := index.Assign(a); again != idA {
+		t.Fatalf("digest was reassigned from %s to %s\n", idA, again)
+	}
+	if digest, ok := index.Lookup(idB); !ok || !slicesEqual(digest, b) {
+		t.Fatalf("lookup of %s gave %x\n", idB, digest)
+	}
+}
+
+func Test_IDIndexZeroLength(t *testing.T) {
+	var index IDIndex
+	for _, digest := range [][]byte{{0x12, 0x30}, {0x92, 0x30}} {
+		id, err := index.Assign(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != 1 {
+			t.Fatalf("the zero IDIndex gave %x the ID %q, expected one character\n", digest, id)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// package sidecar writes and checks per-file checksum files: for a file
+// release.iso, a release.iso.sha256 beside it holding the line
+//
+//	<hex digest>  release.iso
+//
+// the convention many download servers follow, and which `sha256sum -c
+// release.iso.sha256` checks from the file's directory.
+package sidecar
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/atomicfile"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// DefaultAlgorithm is used when Options.Algorithms is empty.
+const DefaultAlgorithm = "sha256"
+
+// Options configures which sidecars are written and how they are named. The
+// zero value writes a single .sha256 sidecar.
+type Options struct {
+	// Algorithms lists the registered multihash algorithms to write a
+	// sidecar for, all computed in a single pass over the file.
+	Algorithms []string
+	// Extensions overrides the extension used for an algorithm, without the
+	// leading dot, e.g. {"sha256": "sha256sum"}. Algorithms not listed use
+	// their own name, with "/" and "+" replaced by "-", so that "sha512/256"
+	// has the sidecar extension "sha512-256" rather than a directory.
+	Extensions map[string]string
+	// Durability controls syncing as each sidecar is atomically written;
+	// see atomicfile.
+	Durability atomicfile.Durability
+}
+
+func (o Options) algorithms() []string {
+	if len(o.Algorithms) == 0 {
+		return []string{DefaultAlgorithm}
+	}
+	return o.Algorithms
+}
+
+// Path returns the name of filename's sidecar for algorithm.
+func (o Options) Path(filename, algorithm string) string {
+	extension, ok := o.Extensions[algorithm]
+	if !ok {
+		extension = extensionReplacer.Replace(algorithm)
+	}
+	return filename + "." + extension
+}
+
+var extensionReplacer = strings.NewReplacer("/", "-", "+", "-")
+
+// Write hashes filename with every configured algorithm and writes a sidecar
+// for each, returning their paths in the order of the algorithms. Sidecars
+// are written atomically, so a crash never leaves a truncated one behind.
+func Write(filename string, opts Options) ([]string, error) {
+	algorithms := opts.algorithms()
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	digests, err := multihash.FromFile(filename, hashes...)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(algorithms))
+	for index, algorithm := range algorithms {
+		paths[index] = opts.Path(filename, algorithm)
+		if err = writeSidecar(paths[index], filepath.Base(filename), algorithm, digests[index], opts.Durability); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+func writeSidecar(sidecar, name, algorithm string, digest []byte, durability atomicfile.Durability) error {
+	m := &manifest.Manifest{
+		Algorithms: []string{algorithm},
+		Entries:    []manifest.Entry{{Path: name, Digests: [][]byte{digest}}},
+	}
+	var contents bytes.Buffer
+	if err := m.WriteGNU(&contents, algorithm); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(sidecar, contents.Bytes(), 0o644, durability)
+}
@@ -0,0 +1,61 @@
+package sidecar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "crypto/md5"
+)
+
+func Test_Write(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "release.iso")
+	if err := os.WriteFile(filename, []byte("Sample text file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Algorithms: []string{"sha256", "md5"}, Extensions: map[string]string{"md5": "md5sum"}}
+	paths, err := Write(filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != filename+".sha256" || paths[1] != filename+".md5sum" {
+		t.Fatalf("sidecars written at %q\n", paths)
+	}
+	contents, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("Sample text file\n"))
+	if expected := hex.EncodeToString(sum[:]) + "  release.iso\n"; string(contents) != expected {
+		t.Fatalf("sidecar contained %q, expected %q\n", contents, expected)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Fatalf("temporary files left behind: %v\n", entries)
+	}
+}
+
+func Test_WriteSlashedAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "release.iso")
+	if err := os.WriteFile(filename, []byte("Sample text file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Algorithms: []string{"sha512/256"}}
+	paths, err := Write(filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != filename+".sha512-256" {
+		t.Fatalf("sidecars written at %q\n", paths)
+	}
+	report, err := Verify(dir, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Verified) != 1 || !report.OK() {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
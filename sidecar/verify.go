@@ -0,0 +1,181 @@
+package sidecar
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trytriangles/multihash"
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// Report lists the outcome of checking a tree against its sidecars, by
+// slash-separated path relative to the root.
+type Report struct {
+	// Verified files matched every sidecar they have.
+	Verified []string
+	// Mismatched files differ from a sidecar although they were not
+	// modified after it was written: the signature of silent corruption.
+	Mismatched []string
+	// Stale files differ from a sidecar and were modified after it was
+	// written, which usually means an intentional change the sidecar was
+	// not updated for.
+	Stale []string
+	// Regenerated files were mismatched or stale, and had their sidecars
+	// rewritten at the caller's request.
+	Regenerated []string
+	// Unprotected files lack a sidecar for at least one configured
+	// algorithm. Those they have are still checked.
+	Unprotected []string
+	// Orphaned sidecars have no file beside them to describe.
+	Orphaned []string
+	// Failed files, or their sidecars, could not be read or parsed.
+	Failed map[string]error
+}
+
+// OK reports whether every file matched its sidecars and nothing was
+// unprotected, orphaned or unreadable. Regenerated files do not count
+// against it, having been accepted by the caller.
+func (r Report) OK() bool {
+	return len(r.Mismatched) == 0 && len(r.Stale) == 0 && len(r.Unprotected) == 0 &&
+		len(r.Orphaned) == 0 && len(r.Failed) == 0
+}
+
+// Verify checks every file under root against its sidecars, as named by
+// opts. Any file whose name ends in a configured sidecar extension is taken
+// to be a sidecar rather than data.
+//
+// If regenerate is not nil, it is called with the path of each mismatched or
+// stale file, and with stale true for a file that was modified after its
+// sidecars were written; returning true rewrites that file's sidecars from
+// its current content, healing them after an intentional change, and
+// reports it as Regenerated instead. A mismatched file that was not
+// modified is likely corrupt, so callers accepting edits should only
+// regenerate stale ones.
+func Verify(root string, opts Options, regenerate func(filePath string, stale bool) bool) (report Report, err error) {
+	algorithms := opts.algorithms()
+	if _, err = multihash.NewAll(algorithms...); err != nil {
+		return report, err
+	}
+	failed := func(filePath string, err error) {
+		if report.Failed == nil {
+			report.Failed = make(map[string]error)
+		}
+		report.Failed[filePath] = err
+	}
+	err = filepath.WalkDir(root, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		relative, err := filepath.Rel(root, filename)
+		if err != nil {
+			return err
+		}
+		relative = filepath.ToSlash(relative)
+		if base, ok := opts.sidecarOf(filename, algorithms); ok {
+			if _, statErr := os.Stat(base); errors.Is(statErr, fs.ErrNotExist) {
+				report.Orphaned = append(report.Orphaned, relative)
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			failed(relative, err)
+			return nil
+		}
+		var (
+			present  []string
+			expected [][]byte
+			stale    bool
+		)
+		for _, algorithm := range algorithms {
+			sidecar := opts.Path(filename, algorithm)
+			sidecarInfo, statErr := os.Stat(sidecar)
+			if errors.Is(statErr, fs.ErrNotExist) {
+				continue
+			}
+			digest, readErr := readSidecar(sidecar, filepath.Base(filename), algorithm)
+			if readErr == nil {
+				readErr = statErr
+			}
+			if readErr != nil {
+				failed(relative, readErr)
+				return nil
+			}
+			present = append(present, algorithm)
+			expected = append(expected, digest)
+			stale = stale || info.ModTime().After(sidecarInfo.ModTime())
+		}
+		if len(present) < len(algorithms) {
+			report.Unprotected = append(report.Unprotected, relative)
+		}
+		if len(present) == 0 {
+			return nil
+		}
+
+		hashes, _ := multihash.NewAll(present...)
+		actual, err := multihash.FromFile(filename, hashes...)
+		if err != nil {
+			failed(relative, err)
+			return nil
+		}
+		matched := true
+		for index := range present {
+			matched = matched && bytes.Equal(expected[index], actual[index])
+		}
+		switch {
+		case matched:
+			report.Verified = append(report.Verified, relative)
+		case regenerate != nil && regenerate(relative, stale):
+			if _, err = Write(filename, opts); err != nil {
+				failed(relative, err)
+				return nil
+			}
+			report.Regenerated = append(report.Regenerated, relative)
+		case stale:
+			report.Stale = append(report.Stale, relative)
+		default:
+			report.Mismatched = append(report.Mismatched, relative)
+		}
+		return nil
+	})
+	return report, err
+}
+
+// sidecarOf reports whether filename is a sidecar under opts, and if so, the
+// name of the file it describes.
+func (o Options) sidecarOf(filename string, algorithms []string) (base string, ok bool) {
+	for _, algorithm := range algorithms {
+		suffix := o.Path("", algorithm)
+		if strings.HasSuffix(filename, suffix) && len(filename) > len(suffix) {
+			return strings.TrimSuffix(filename, suffix), true
+		}
+	}
+	return "", false
+}
+
+// readSidecar returns the digest a sidecar records for name under
+// algorithm. A sidecar listing a single file under some other name is
+// accepted, since renaming a file and its sidecar together is common.
+func readSidecar(sidecar, name, algorithm string) ([]byte, error) {
+	f, err := os.Open(sidecar)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := manifest.ParseGNU(f, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if digest, ok := m.Digest(name, algorithm); ok {
+		return digest, nil
+	}
+	if len(m.Entries) == 1 {
+		return m.Entries[0].Digests[0], nil
+	}
+	return nil, manifest.MissingDigestError{Path: name, Algorithm: algorithm}
+}
@@ -0,0 +1,81 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Verify(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return filename
+	}
+	for _, name := range []string{"good.txt", "sub/corrupt.txt", "edited.txt", "healed.txt"} {
+		if _, err := Write(write(name, "original"), Options{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("unprotected.txt", "no sidecar")
+	write("gone.txt.sha256", "")
+
+	// Corruption leaves the modification time alone; an edit moves it on.
+	corrupt := write("sub/corrupt.txt", "corrupted")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(corrupt, past, past); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	for _, name := range []string{"edited.txt", "healed.txt"} {
+		if err := os.Chtimes(write(name, "edited"), future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	offered := make(map[string]bool)
+	report, err := Verify(dir, Options{}, func(filePath string, stale bool) bool {
+		offered[filePath] = stale
+		return stale && filePath == "healed.txt"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offered) != 3 || offered["sub/corrupt.txt"] || !offered["edited.txt"] || !offered["healed.txt"] {
+		t.Fatalf("regenerate was offered %v\n", offered)
+	}
+	for _, tc := range []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"verified", report.Verified, "good.txt"},
+		{"mismatched", report.Mismatched, "sub/corrupt.txt"},
+		{"stale", report.Stale, "edited.txt"},
+		{"regenerated", report.Regenerated, "healed.txt"},
+		{"unprotected", report.Unprotected, "unprotected.txt"},
+		{"orphaned", report.Orphaned, "gone.txt.sha256"},
+	} {
+		if len(tc.got) != 1 || tc.got[0] != tc.want {
+			t.Fatalf("%s files were %q, expected %s\n", tc.name, tc.got, tc.want)
+		}
+	}
+	if report.OK() || len(report.Failed) != 0 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+
+	report, err = Verify(dir, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Verified) != 2 {
+		t.Fatalf("after healing, verified files were %q\n", report.Verified)
+	}
+}
@@ -0,0 +1,86 @@
+package multihash
+
+import (
+	"bytes"
+	"hash"
+	"io"
+)
+
+// SizeCheckingReader passes reads through from an underlying reader,
+// failing with a SizeMismatchError as soon as more than the expected number
+// of bytes arrive, and in place of io.EOF if fewer did. A truncated or
+// overlong stream is thereby reported as such, before any digest is
+// compared, and reading an overlong one stops at the first excess byte
+// rather than running on to the end of a possibly unbounded response; the
+// error's Actual size is then one more than expected.
+type SizeCheckingReader struct {
+	r        io.Reader
+	expected int64
+	read     int64
+	err      error
+}
+
+// NewSizeCheckingReader returns a SizeCheckingReader checking that r yields
+// exactly size bytes. No stream has a negative size, so with one every read
+// fails with an InvalidSizeError, and r is never read.
+func NewSizeCheckingReader(r io.Reader, size int64) *SizeCheckingReader {
+	s := &SizeCheckingReader{r: r, expected: size}
+	if size < 0 {
+		s.err = InvalidSizeError{Size: size}
+	}
+	return s
+}
+
+func (s *SizeCheckingReader) Read(p []byte) (n int, err error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	// Ask for one byte beyond the expected size at most, which is enough to
+	// detect an overlong stream without reading any further into it. The
+	// comparison leaves out the extra byte, so that it cannot overflow.
+	if remaining := s.expected - s.read; remaining < int64(len(p)) {
+		p = p[:remaining+1]
+	}
+	n, err = s.r.Read(p)
+	s.read += int64(n)
+	switch {
+	case s.read > s.expected:
+		n -= int(s.read - s.expected)
+		err = SizeMismatchError{Expected: s.expected, Actual: s.read}
+	case err == io.EOF && s.read < s.expected:
+		err = SizeMismatchError{Expected: s.expected, Actual: s.read}
+	}
+	if err != nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// VerifyReader hashes data like FromReader and checks it against an
+// expected size and digests, in the same order as hashes; a nil expected
+// digest is not checked. A negative size is not checked either.
+//
+// The size is checked first, as data is read: a stream of the wrong length
+// gives a SizeMismatchError, matching ErrSizeMismatch rather than
+// ErrDigestMismatch, as soon as that is known. Otherwise the first digest
+// that does not match gives a DigestMismatchError. The digests are returned
+// in either case, except when the size was wrong. Giving more expected
+// digests than hashes is a DigestCountError, before data is read.
+func VerifyReader(data io.Reader, size int64, expected [][]byte, hashes ...hash.Hash) (hashset [][]byte, err error) {
+	if len(expected) > len(hashes) {
+		return nil, DigestCountError{Digests: len(expected), Hashes: len(hashes)}
+	}
+	if size >= 0 {
+		data = NewSizeCheckingReader(data, size)
+	}
+	hashset, err = FromReader(data, hashes...)
+	if err != nil {
+		return nil, err
+	}
+	for index, digest := range expected {
+		if digest != nil && !bytes.Equal(digest, hashset[index]) {
+			return hashset, DigestMismatchError{Expected: digest, Actual: hashset[index]}
+		}
+	}
+	return hashset, nil
+}
@@ -0,0 +1,56 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_SizeCheckingReader(t *testing.T) {
+	const data = "Sample text file\n"
+	if err := iotest.TestReader(NewSizeCheckingReader(strings.NewReader(data), int64(len(data))), []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int64{int64(len(data)) - 1, int64(len(data)) + 1} {
+		_, err := io.ReadAll(NewSizeCheckingReader(strings.NewReader(data), size))
+		if !errors.Is(err, ErrSizeMismatch) {
+			t.Fatalf("expecting %d bytes gave %v\n", size, err)
+		}
+	}
+	if _, err := io.ReadAll(NewSizeCheckingReader(strings.NewReader(data), math.MaxInt64)); !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expecting the largest size gave %v\n", err)
+	}
+	for _, size := range []int64{-1, -2} {
+		if n, err := NewSizeCheckingReader(strings.NewReader(data), size).Read(make([]byte, 8)); n != 0 || !errors.Is(err, ErrInvalidSize) {
+			t.Fatalf("expecting %d bytes gave %d, %v\n", size, n, err)
+		}
+	}
+
+	// An endless stream must be abandoned as soon as it overruns.
+	endless := NewSizeCheckingReader(iotest.OneByteReader(strings.NewReader(strings.Repeat("x", 1<<20))), 10)
+	read, err := io.ReadAll(endless)
+	if !errors.Is(err, ErrSizeMismatch) || len(read) != 10 || endless.read != 11 {
+		t.Fatalf("overlong stream gave %d bytes after reading %d, %v\n", len(read), endless.read, err)
+	}
+}
+
+func Test_VerifyReader(t *testing.T) {
+	const data = "Sample text file\n"
+	sum := md5.Sum([]byte(data))
+	if _, err := VerifyReader(strings.NewReader(data), int64(len(data)), [][]byte{sum[:]}, md5.New()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyReader(strings.NewReader(data[1:]), int64(len(data)), [][]byte{sum[:]}, md5.New()); !errors.Is(err, ErrSizeMismatch) || errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("truncated data gave %v\n", err)
+	}
+	if _, err := VerifyReader(strings.NewReader("Sample text filf\n"), -1, [][]byte{sum[:]}, md5.New()); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("altered data gave %v\n", err)
+	}
+	if _, err := VerifyReader(strings.NewReader(data), -1, [][]byte{sum[:], sum[:]}, md5.New()); !errors.Is(err, ErrDigestCount) {
+		t.Fatalf("two digests for one hash gave %v\n", err)
+	}
+}
@@ -0,0 +1,99 @@
+package multihash
+
+import (
+	"bufio"
+	"hash"
+	"io"
+
+	"github.com/trytriangles/multihash/atomicfile"
+)
+
+// Chunk is one piece of a stream written out by Split.
+type Chunk struct {
+	Path    string
+	Size    int64
+	Digests [][]byte
+}
+
+// Split reads data until EOF, writing it into consecutive files of
+// chunkSize bytes (the last may be shorter) named by name(0), name(1), and
+// so on, as a streaming backup receiver would. Each chunk's digests under
+// hashes are computed as it is written, and the digests of the whole stream
+// are accumulated alongside, so the input is read exactly once however long
+// it is. Digests are in the same order as hashes.
+//
+// Chunk files are written atomically and synced before the next one is
+// started, so a chunk file that exists is always complete. On error, the
+// chunks completed so far are returned with it. A chunkSize that is not
+// positive is a ChunkSizeError, before anything is read.
+func Split(
+	data io.Reader,
+	chunkSize int64,
+	name func(index int) string,
+	hashes ...func() hash.Hash,
+) (chunks []Chunk, whole [][]byte, err error) {
+	if chunkSize <= 0 {
+		return nil, nil, ChunkSizeError{Size: chunkSize}
+	}
+	source := bufio.NewReaderSize(data, bufferSize)
+	wholeHashes := make([]hash.Hash, len(hashes))
+	for index, newHash := range hashes {
+		wholeHashes[index] = newHash()
+	}
+	for index := 0; ; index++ {
+		// Only start a chunk file once there is data to put in it.
+		if _, err = source.Peek(1); err == io.EOF {
+			break
+		} else if err != nil {
+			return chunks, nil, err
+		}
+		chunk, err := writeChunk(source, chunkSize, name(index), hashes, wholeHashes)
+		if err != nil {
+			return chunks, nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	whole = make([][]byte, len(hashes))
+	for index, h := range wholeHashes {
+		whole[index] = h.Sum(nil)
+	}
+	return chunks, whole, nil
+}
+
+func writeChunk(
+	source io.Reader,
+	chunkSize int64,
+	path string,
+	hashes []func() hash.Hash,
+	wholeHashes []hash.Hash,
+) (chunk Chunk, err error) {
+	f, err := atomicfile.Create(path, atomicfile.SyncFile)
+	if err != nil {
+		return chunk, err
+	}
+	defer f.Abort()
+	chunkHashes := make([]hash.Hash, len(hashes), len(hashes)+len(wholeHashes))
+	for index, newHash := range hashes {
+		chunkHashes[index] = newHash()
+	}
+	counter := &countingWriter{w: f}
+	hashset, err := FromReader(io.TeeReader(io.LimitReader(source, chunkSize), counter), append(chunkHashes, wholeHashes...)...)
+	if err != nil {
+		return chunk, err
+	}
+	if err = f.Close(); err != nil {
+		return chunk, err
+	}
+	return Chunk{Path: path, Size: counter.n, Digests: hashset[:len(hashes)]}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,57 @@
+package multihash
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Split(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 25000)
+	name := func(index int) string { return filepath.Join(dir, fmt.Sprintf("part.%03d", index)) }
+	chunks, whole, err := Split(bytes.NewReader(data), 100000, name, sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 || chunks[2].Size != 50000 {
+		t.Fatalf("unexpected chunks %+v\n", chunks)
+	}
+	wholeSum := sha256.Sum256(data)
+	if !slicesEqual(whole[0], wholeSum[:]) {
+		t.Fatalf("whole-stream digest was %x, expected %x\n", whole[0], wholeSum)
+	}
+	for i, chunk := range chunks {
+		written, err := os.ReadFile(chunk.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, data[i*100000:i*100000+int(chunk.Size)]) {
+			t.Fatalf("chunk %d has the wrong contents\n", i)
+		}
+		sum := sha256.Sum256(written)
+		if !slicesEqual(chunk.Digests[0], sum[:]) {
+			t.Fatalf("chunk %d digest was %x, expected %x\n", i, chunk.Digests[0], sum)
+		}
+	}
+	if _, err = os.Stat(name(3)); !os.IsNotExist(err) {
+		t.Fatal("an empty trailing chunk was created")
+	}
+}
+
+func Test_Split_invalidChunkSize(t *testing.T) {
+	dir := t.TempDir()
+	name := func(index int) string { return filepath.Join(dir, fmt.Sprintf("part.%03d", index)) }
+	for _, size := range []int64{0, -1} {
+		if _, _, err := Split(bytes.NewReader([]byte("data")), size, name, sha256.New); !errors.Is(err, ErrInvalidChunkSize) {
+			t.Fatalf("chunk size %d gave %v\n", size, err)
+		}
+	}
+	if _, err := os.Stat(name(0)); !os.IsNotExist(err) {
+		t.Fatal("a chunk was created for an invalid chunk size")
+	}
+}
@@ -0,0 +1,6 @@
+package sqlblob
+
+import "github.com/trytriangles/multihash/errcode"
+
+var ErrNotCheckpointable = errcode.New(errcode.UnsupportedAlgorithm, "hash state cannot be checkpointed")
+var ErrCheckpointMismatch = errcode.New(errcode.InvalidArgument, "checkpoint does not match the algorithms")
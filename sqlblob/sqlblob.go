@@ -0,0 +1,252 @@
+// package sqlblob hashes database large objects as they are streamed out,
+// as for checking a blob export against a checksum the database computed
+// itself. Reads that fail with a dropped connection are retried from the
+// last checkpoint: hash states are saved as the stream progresses, so a
+// reconnect resumes at that offset instead of restarting a multi-gigabyte
+// read, and the checkpoints can be persisted to resume a later run.
+package sqlblob
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/trytriangles/multihash"
+)
+
+// DefaultChunkSize is the size of each ranged read when Options.ChunkSize is
+// zero.
+const DefaultChunkSize = 1 << 20
+
+// DefaultRetries is the number of reconnects attempted when Options.Retries
+// is zero.
+const DefaultRetries = 3
+
+// Checkpoint is the progress of a hash: the offset reached and each hash's
+// state there, from encoding.BinaryMarshaler, in algorithm order.
+type Checkpoint struct {
+	Offset int64
+	States [][]byte
+}
+
+// Options configures chunking and retries. The zero value reads 1 MiB
+// chunks, retries driver.ErrBadConn three times and checkpoints after every
+// chunk.
+type Options struct {
+	ChunkSize int
+	// Retries bounds the reconnects after failed reads; -1 disables them.
+	Retries int
+	// Retryable reports whether a read error may be retried. If nil, only
+	// driver.ErrBadConn is.
+	Retryable func(err error) bool
+	// Resume, if set, continues a hash from a checkpoint saved by an
+	// earlier run.
+	Resume *Checkpoint
+	// OnCheckpoint, if set, is called with each checkpoint taken, so that it
+	// can be persisted.
+	OnCheckpoint func(Checkpoint)
+}
+
+func (o Options) chunkSize() int {
+	if o.ChunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return o.ChunkSize
+}
+
+func (o Options) retries() int {
+	switch {
+	case o.Retries < 0:
+		return 0
+	case o.Retries == 0:
+		return DefaultRetries
+	}
+	return o.Retries
+}
+
+func (o Options) retryable(err error) bool {
+	if o.Retryable != nil {
+		return o.Retryable(err)
+	}
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// OpenFunc opens a blob for reading from offset. It is called again, at the
+// last checkpoint's offset, after a retryable error.
+type OpenFunc func(ctx context.Context, offset int64) (io.ReadCloser, error)
+
+// Hash hashes the blob opened by open with algorithms, which must be
+// registered multihash algorithms whose hashes implement
+// encoding.BinaryMarshaler, as those of the standard library do. A
+// checkpoint is taken after every opts.ChunkSize bytes.
+func Hash(ctx context.Context, open OpenFunc, opts Options, algorithms ...string) (digests [][]byte, err error) {
+	hashes, err := multihash.NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := Checkpoint{}
+	if opts.Resume != nil {
+		checkpoint = *opts.Resume
+		if err = restore(hashes, checkpoint); err != nil {
+			return nil, err
+		}
+	} else if checkpoint, err = save(hashes, 0); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, opts.chunkSize())
+	retries := opts.retries()
+	for {
+		done, err := hashFrom(ctx, open, checkpoint.Offset, hashes, buf, func(offset int64) error {
+			saved, err := save(hashes, offset)
+			if err != nil {
+				return err
+			}
+			checkpoint = saved
+			if opts.OnCheckpoint != nil {
+				opts.OnCheckpoint(checkpoint)
+			}
+			return nil
+		})
+		if done {
+			break
+		}
+		if retries == 0 || !opts.retryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		retries--
+		if err = restore(hashes, checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	digests = make([][]byte, len(hashes))
+	for index, h := range hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return digests, nil
+}
+
+// hashFrom reads the blob from offset to its end, calling checkpoint after
+// each full buffer. done is true when the end was reached.
+func hashFrom(
+	ctx context.Context,
+	open OpenFunc,
+	offset int64,
+	hashes []hash.Hash,
+	buf []byte,
+	checkpoint func(offset int64) error,
+) (done bool, err error) {
+	r, err := open(ctx, offset)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+	for {
+		n, err := io.ReadFull(r, buf)
+		for _, h := range hashes {
+			h.Write(buf[:n])
+		}
+		offset += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if err = checkpoint(offset); err != nil {
+			return false, err
+		}
+	}
+}
+
+func save(hashes []hash.Hash, offset int64) (Checkpoint, error) {
+	checkpoint := Checkpoint{Offset: offset, States: make([][]byte, len(hashes))}
+	for index, h := range hashes {
+		marshaler, ok := h.(encoding.BinaryMarshaler)
+		if !ok {
+			return checkpoint, ErrNotCheckpointable
+		}
+		state, err := marshaler.MarshalBinary()
+		if err != nil {
+			return checkpoint, err
+		}
+		checkpoint.States[index] = state
+	}
+	return checkpoint, nil
+}
+
+func restore(hashes []hash.Hash, checkpoint Checkpoint) error {
+	if len(checkpoint.States) != len(hashes) {
+		return ErrCheckpointMismatch
+	}
+	for index, h := range hashes {
+		unmarshaler, ok := h.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return ErrNotCheckpointable
+		}
+		if err := unmarshaler.UnmarshalBinary(checkpoint.States[index]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChunkQuery returns an OpenFunc reading a blob through repeated ranged
+// queries, which works with any driver and keeps no transaction or cursor
+// open across a reconnect. query must select one column holding the bytes
+// of the blob from a 1-based offset and length given as its first two
+// arguments, followed by args, for example for PostgreSQL
+//
+//	SELECT substring(data FROM $1 FOR $2) FROM files WHERE id = $3
+//
+// or for MySQL and SQLite
+//
+//	SELECT substr(data, ?, ?) FROM files WHERE id = ?
+func ChunkQuery(db *sql.DB, chunkSize int, query string, args ...interface{}) OpenFunc {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		return &chunkReader{ctx: ctx, db: db, query: query, args: args, offset: offset, size: chunkSize}, nil
+	}
+}
+
+type chunkReader struct {
+	ctx    context.Context
+	db     *sql.DB
+	query  string
+	args   []interface{}
+	offset int64
+	size   int
+	chunk  []byte
+	eof    bool
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.chunk) == 0 {
+		if c.eof {
+			return 0, io.EOF
+		}
+		args := append([]interface{}{c.offset + 1, c.size}, c.args...)
+		if err := c.db.QueryRowContext(c.ctx, c.query, args...).Scan(&c.chunk); err != nil {
+			return 0, err
+		}
+		c.offset += int64(len(c.chunk))
+		c.eof = len(c.chunk) < c.size
+		if len(c.chunk) == 0 {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	return n, nil
+}
+
+func (c *chunkReader) Close() error {
+	return nil
+}
@@ -0,0 +1,150 @@
+package sqlblob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// flakyReader fails with driver.ErrBadConn once it has returned failAfter
+// bytes.
+type flakyReader struct {
+	data      []byte
+	failAfter int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.failAfter <= 0 {
+		return 0, driver.ErrBadConn
+	}
+	if len(p) > f.failAfter {
+		p = p[:f.failAfter]
+	}
+	n := copy(p, f.data)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	f.data = f.data[n:]
+	f.failAfter -= n
+	return n, nil
+}
+
+func (f *flakyReader) Close() error { return nil }
+
+func Test_Hash(t *testing.T) {
+	blob := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+	expected := sha256.Sum256(blob)
+
+	var opened []int64
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		opened = append(opened, offset)
+		return &flakyReader{data: blob[offset:], failAfter: 5000}, nil
+	}
+	var checkpoints []Checkpoint
+	opts := Options{ChunkSize: 1024, Retries: 10, OnCheckpoint: func(c Checkpoint) { checkpoints = append(checkpoints, c) }}
+	digests, err := Hash(context.Background(), open, opts, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(digests[0], expected[:]) {
+		t.Fatalf("expected %x, got %x\n", expected, digests[0])
+	}
+	if len(opened) < 2 || opened[1] != 4096 {
+		t.Fatalf("unexpected reopen offsets %v\n", opened)
+	}
+
+	// Resuming from a saved checkpoint gives the same digest.
+	resume := checkpoints[3]
+	opts = Options{ChunkSize: 1024, Resume: &resume}
+	digests, err = Hash(context.Background(), func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(blob[offset:])), nil
+	}, opts, "sha256")
+	if err != nil || !bytes.Equal(digests[0], expected[:]) {
+		t.Fatalf("resumed hash gave %x, %v\n", digests, err)
+	}
+
+	_, err = Hash(context.Background(), open, Options{ChunkSize: 1024, Retries: -1}, "sha256")
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected the read error without retries, got %v\n", err)
+	}
+}
+
+// blobDriver serves "substr(blob, offset, length)" for a single blob.
+type blobDriver struct {
+	blob []byte
+}
+
+func (d blobDriver) Open(string) (driver.Conn, error) { return blobConn(d), nil }
+
+// blobConnector lets a test open a blobDriver without registering it, which
+// could only be done once per process.
+type blobConnector blobDriver
+
+func (c blobConnector) Connect(context.Context) (driver.Conn, error) { return blobConn(c), nil }
+func (c blobConnector) Driver() driver.Driver                        { return blobDriver(c) }
+
+type blobConn blobDriver
+
+func (c blobConn) Prepare(string) (driver.Stmt, error) { return blobStmt(c), nil }
+func (c blobConn) Close() error                        { return nil }
+func (c blobConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type blobStmt blobConn
+
+func (s blobStmt) Close() error  { return nil }
+func (s blobStmt) NumInput() int { return 2 }
+func (s blobStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s blobStmt) Query(args []driver.Value) (driver.Rows, error) {
+	offset, length := args[0].(int64)-1, args[1].(int64)
+	end := offset + length
+	if end > int64(len(s.blob)) {
+		end = int64(len(s.blob))
+	}
+	if offset > end {
+		offset = end
+	}
+	return &blobRows{chunk: s.blob[offset:end]}, nil
+}
+
+type blobRows struct {
+	chunk []byte
+	done  bool
+}
+
+func (r *blobRows) Columns() []string { return []string{"chunk"} }
+func (r *blobRows) Close() error      { return nil }
+func (r *blobRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.chunk
+	return nil
+}
+
+func Test_ChunkQuery(t *testing.T) {
+	blob := bytes.Repeat([]byte("large object "), 700)
+	db := sql.OpenDB(blobConnector{blob: blob})
+	defer db.Close()
+
+	// A length that is an exact multiple of the chunk size ends with an
+	// empty chunk.
+	for _, chunkSize := range []int{1000, 700, 13} {
+		digests, err := Hash(context.Background(), ChunkQuery(db, chunkSize, "SELECT substr(data, ?, ?)"), Options{}, "sha256")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := sha256.Sum256(blob)
+		if !bytes.Equal(digests[0], expected[:]) {
+			t.Fatalf("chunk size %d: expected %x, got %x\n", chunkSize, expected, digests[0])
+		}
+	}
+}
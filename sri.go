@@ -0,0 +1,39 @@
+package multihash
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// sriAlgorithms are the algorithms Subresource Integrity defines.
+var sriAlgorithms = []string{"sha256", "sha384", "sha512"}
+
+// FormatSRI renders digest as a Subresource Integrity value, e.g.
+// "sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC",
+// as used in HTML integrity attributes and npm lockfiles. algorithm should
+// be one of "sha256", "sha384" and "sha512"; browsers ignore others.
+func FormatSRI(algorithm string, digest []byte) string {
+	return algorithm + "-" + base64.StdEncoding.EncodeToString(digest)
+}
+
+// ParseSRI decodes a single Subresource Integrity value. Options following a
+// '?' are ignored, as the specification requires.
+func ParseSRI(value string) (algorithm string, digest []byte, err error) {
+	value, _, _ = strings.Cut(strings.TrimSpace(value), "?")
+	algorithm, encoded, found := strings.Cut(value, "-")
+	if !found {
+		return "", nil, MalformedDigestError{Text: value}
+	}
+	known := false
+	for _, name := range sriAlgorithms {
+		known = known || name == algorithm
+	}
+	if !known {
+		return "", nil, UnknownAlgorithmError{Name: algorithm}
+	}
+	digest, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, MalformedDigestError{Text: value}
+	}
+	return algorithm, digest, nil
+}
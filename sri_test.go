@@ -0,0 +1,23 @@
+package multihash
+
+import (
+	"crypto/sha512"
+	"errors"
+	"testing"
+)
+
+func Test_SRI(t *testing.T) {
+	// From the examples in the Subresource Integrity specification.
+	sum := sha512.Sum384([]byte("alert('Hello, world.');"))
+	value := FormatSRI("sha384", sum[:])
+	if value != "sha384-H8BRh8j48O9oYatfu5AZzq6A9RINhZO5H16dQZngK7T62em8MUt1FLm52t+eX6xO" {
+		t.Fatalf("SRI value was %s\n", value)
+	}
+	algorithm, digest, err := ParseSRI(value + "?foo")
+	if err != nil || algorithm != "sha384" || !slicesEqual(digest, sum[:]) {
+		t.Fatalf("parsed %s, %x, %v\n", algorithm, digest, err)
+	}
+	if _, _, err = ParseSRI("md5-abc"); !errors.Is(err, ErrHashFunctionNotAvailable) {
+		t.Fatalf("expected md5 to be rejected, got %v\n", err)
+	}
+}
@@ -0,0 +1,123 @@
+package multihash
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// StallStats describe where the time went while a StallReader was read.
+type StallStats struct {
+	// Reads counts the calls to Read, and Bytes the bytes they returned.
+	Reads int64
+	Bytes int64
+	// Blocked is the total time spent inside the underlying Read, waiting
+	// on the producer, and LongestBlock the longest single such wait.
+	Blocked      time.Duration
+	LongestBlock time.Duration
+	// Consuming is the total time between Reads, from each one returning to
+	// the next being called, which is the consumer's own time: for
+	// FromReader, that spent hashing.
+	Consuming time.Duration
+}
+
+// StallReader passes reads through from an underlying reader, such as the
+// read end of an io.Pipe or a network stream, timing how long each Read
+// blocks and how long the consumer takes between Reads. A high Blocked
+// against Consuming means the producer is the bottleneck; the reverse means
+// the hashes are. With an idle timeout, a Read that blocks for longer gives
+// an IdleTimeoutError, telling a hung producer apart from a slow one.
+//
+// The underlying Read cannot be interrupted, so on a timeout it is left to
+// finish in the background, its result discarded, and every later Read
+// returns the same error. Close the producer's end, e.g. with
+// io.PipeWriter.CloseWithError, to release it.
+type StallReader struct {
+	r       io.Reader
+	timeout time.Duration
+	now     func() time.Time
+
+	mutex      sync.Mutex
+	stats      StallStats
+	lastReturn time.Time
+	buffer     []byte
+	err        error
+}
+
+// NewStallReader returns a StallReader reading from r. A timeout of zero or
+// less disables the idle timeout, and Reads are then made directly on r.
+func NewStallReader(r io.Reader, timeout time.Duration) *StallReader {
+	return &StallReader{r: r, timeout: timeout, now: time.Now}
+}
+
+// Stats returns the statistics so far. It may be called while another
+// goroutine is reading, e.g. to export them as metrics.
+func (s *StallReader) Stats() StallStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.stats
+}
+
+func (s *StallReader) Read(p []byte) (n int, err error) {
+	s.mutex.Lock()
+	if s.err != nil {
+		s.mutex.Unlock()
+		return 0, s.err
+	}
+	start := s.now()
+	if !s.lastReturn.IsZero() {
+		s.stats.Consuming += start.Sub(s.lastReturn)
+	}
+	s.mutex.Unlock()
+
+	if s.timeout > 0 {
+		n, err = s.readWithTimeout(p)
+	} else {
+		n, err = s.r.Read(p)
+	}
+
+	end := s.now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	blocked := end.Sub(start)
+	s.stats.Reads++
+	s.stats.Bytes += int64(n)
+	s.stats.Blocked += blocked
+	if blocked > s.stats.LongestBlock {
+		s.stats.LongestBlock = blocked
+	}
+	s.lastReturn = end
+	if _, timedOut := err.(IdleTimeoutError); timedOut {
+		s.err = err
+	}
+	return n, err
+}
+
+type stallResult struct {
+	n   int
+	err error
+}
+
+// readWithTimeout reads into a buffer of the StallReader's own, so that a
+// Read abandoned on a timeout cannot write into p after Read has returned.
+func (s *StallReader) readWithTimeout(p []byte) (int, error) {
+	if cap(s.buffer) < len(p) {
+		s.buffer = make([]byte, len(p))
+	}
+	buffer := s.buffer[:len(p)]
+	done := make(chan stallResult, 1)
+	go func() {
+		n, err := s.r.Read(buffer)
+		done <- stallResult{n, err}
+	}()
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return copy(p, buffer[:result.n]), result.err
+	case <-timer.C:
+		// The abandoned Read still owns the buffer.
+		s.buffer = nil
+		return 0, IdleTimeoutError{Timeout: s.timeout}
+	}
+}
@@ -0,0 +1,45 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_StallReader(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+			w.Write([]byte("chunk"))
+		}
+		w.Close()
+	}()
+	stalls := NewStallReader(r, time.Second)
+	hashset, err := FromReader(stalls, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := sha256.Sum256([]byte("chunkchunkchunk"))
+	if !slicesEqual(hashset[0], expected[:]) {
+		t.Fatalf("digest %x, expected %x\n", hashset[0], expected)
+	}
+	stats := stalls.Stats()
+	if stats.Bytes != 15 || stats.Reads < 4 || stats.Blocked < 30*time.Millisecond || stats.LongestBlock < 10*time.Millisecond {
+		t.Fatalf("unexpected stats %+v\n", stats)
+	}
+}
+
+func Test_StallReaderTimeout(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	stalls := NewStallReader(r, 20*time.Millisecond)
+	if _, err := FromReader(stalls, sha256.New()); !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("hung producer gave %v\n", err)
+	}
+	if _, err := stalls.Read(make([]byte, 1)); !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("read after timeout gave %v\n", err)
+	}
+}
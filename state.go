@@ -0,0 +1,178 @@
+package multihash
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"strconv"
+)
+
+// stateMagic begins every marshaled Resumable, and names the format version.
+const stateMagic = "MHS1"
+
+// Resumable hashes a stream with several algorithms in a way that can be
+// handed between processes: its state marshals to a small self-describing
+// message, so an upload proxy can hash the first part of a stream, pass the
+// state to another instance along with the upload's session, and have that
+// instance hash the rest and produce the digests of the whole.
+//
+// The wire format is, in order:
+//
+//	"MHS1"                             magic and version
+//	uvarint                            bytes hashed so far
+//	uvarint                            number of algorithms
+//	per algorithm:
+//	  uvarint, bytes                   registered name
+//	  uvarint, bytes                   encoding.BinaryMarshaler state
+//	4 bytes                            big-endian CRC-32C of all the above
+//
+// The states are those of the standard library's hashes, which embed their
+// own algorithm identifiers; algorithms whose hashes do not implement
+// encoding.BinaryMarshaler cannot be resumed.
+type Resumable struct {
+	algorithms []string
+	hashes     []hash.Hash
+	offset     int64
+}
+
+// NewResumable returns a Resumable hashing with algorithms, which must be
+// registered and marshalable.
+func NewResumable(algorithms ...string) (*Resumable, error) {
+	hashes, err := NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	for index, h := range hashes {
+		if _, ok := h.(encoding.BinaryMarshaler); !ok {
+			return nil, UnresumableHashError{Algorithm: algorithms[index]}
+		}
+	}
+	return &Resumable{algorithms: algorithms, hashes: hashes}, nil
+}
+
+// Write hashes p with every algorithm.
+func (r *Resumable) Write(p []byte) (int, error) {
+	for _, h := range r.hashes {
+		h.Write(p)
+	}
+	r.offset += int64(len(p))
+	return len(p), nil
+}
+
+// Offset returns the number of bytes hashed so far; a process taking over
+// from a marshaled state continues the stream from this offset.
+func (r *Resumable) Offset() int64 {
+	return r.offset
+}
+
+// Algorithms returns the algorithms being computed.
+func (r *Resumable) Algorithms() []string {
+	return r.algorithms
+}
+
+// Digests returns the digests of everything written so far, in algorithm
+// order, without disturbing the state.
+func (r *Resumable) Digests() [][]byte {
+	digests := make([][]byte, len(r.hashes))
+	for index, h := range r.hashes {
+		digests[index] = h.Sum(nil)
+	}
+	return digests
+}
+
+// MarshalBinary encodes the state in the format described on Resumable.
+func (r *Resumable) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBufferString(stateMagic)
+	var scratch [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		buf.Write(scratch[:binary.PutUvarint(scratch[:], v)])
+	}
+	putUvarint(uint64(r.offset))
+	putUvarint(uint64(len(r.hashes)))
+	for index, h := range r.hashes {
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		putUvarint(uint64(len(r.algorithms[index])))
+		buf.WriteString(r.algorithms[index])
+		putUvarint(uint64(len(state)))
+		buf.Write(state)
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.Checksum(buf.Bytes(), castagnoliTable))
+	buf.Write(sum[:])
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces r's state with one encoded by MarshalBinary,
+// including its algorithms. A corrupted or truncated state is reported as a
+// MalformedStateError.
+func (r *Resumable) UnmarshalBinary(data []byte) error {
+	if len(data) < len(stateMagic)+4 || string(data[:len(stateMagic)]) != stateMagic {
+		return MalformedStateError{Reason: "not a multihash state"}
+	}
+	body, sum := data[:len(data)-4], data[len(data)-4:]
+	if crc32.Checksum(body, castagnoliTable) != binary.BigEndian.Uint32(sum) {
+		return MalformedStateError{Reason: "checksum mismatch"}
+	}
+	body = body[len(stateMagic):]
+	uvarint := func() (uint64, bool) {
+		v, n := binary.Uvarint(body)
+		if n <= 0 {
+			return 0, false
+		}
+		body = body[n:]
+		return v, true
+	}
+	field := func() ([]byte, bool) {
+		length, ok := uvarint()
+		if !ok || length > uint64(len(body)) {
+			return nil, false
+		}
+		v := body[:length]
+		body = body[length:]
+		return v, true
+	}
+
+	offset, ok := uvarint()
+	if !ok || offset > 1<<63-1 {
+		return MalformedStateError{Reason: "bad offset"}
+	}
+	count, ok := uvarint()
+	if !ok || count > uint64(len(body)) {
+		return MalformedStateError{Reason: "bad algorithm count"}
+	}
+	algorithms := make([]string, 0, count)
+	hashes := make([]hash.Hash, 0, count)
+	for i := uint64(0); i < count; i++ {
+		name, ok := field()
+		if !ok {
+			return MalformedStateError{Reason: "truncated algorithm " + strconv.FormatUint(i, 10)}
+		}
+		state, ok := field()
+		if !ok {
+			return MalformedStateError{Reason: "truncated state for " + strconv.Quote(string(name))}
+		}
+		h, err := New(string(name))
+		if err != nil {
+			return err
+		}
+		unmarshaler, ok := h.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return UnresumableHashError{Algorithm: string(name)}
+		}
+		if err = unmarshaler.UnmarshalBinary(state); err != nil {
+			return MalformedStateError{Reason: string(name) + ": " + err.Error()}
+		}
+		algorithms = append(algorithms, string(name))
+		hashes = append(hashes, h)
+	}
+	if len(body) != 0 {
+		return MalformedStateError{Reason: "trailing data"}
+	}
+	r.algorithms, r.hashes, r.offset = algorithms, hashes, int64(offset)
+	return nil
+}
@@ -0,0 +1,47 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func Test_Resumable(t *testing.T) {
+	data := []byte("the first half of an upload, then the second half")
+	first, err := NewResumable("md5", "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Write(data[:20])
+	state, err := first.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var second Resumable
+	if err = second.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	if second.Offset() != 20 || len(second.Algorithms()) != 2 {
+		t.Fatalf("unexpected resumed state at %d with %v\n", second.Offset(), second.Algorithms())
+	}
+	second.Write(data[20:])
+	digests := second.Digests()
+	md5Digest := md5.Sum(data)
+	sha256Digest := sha256.Sum256(data)
+	if !digestsEqual(digests, [][]byte{md5Digest[:], sha256Digest[:]}) {
+		t.Fatalf("unexpected digests %x\n", digests)
+	}
+
+	state[len(state)/2] ^= 1
+	if err = second.UnmarshalBinary(state); !errors.Is(err, ErrMalformedState) {
+		t.Fatalf("expected a malformed state error, got %v\n", err)
+	}
+	if err = second.UnmarshalBinary(state[:len(state)-1]); !errors.Is(err, ErrMalformedState) {
+		t.Fatalf("expected a malformed state error for a truncated state, got %v\n", err)
+	}
+	if _, err = NewResumable("sha256/128"); !errors.Is(err, ErrUnresumableHash) {
+		t.Fatalf("expected an unresumable hash error, got %v\n", err)
+	}
+}
@@ -0,0 +1,160 @@
+package multihash
+
+import (
+	"bytes"
+	"hash"
+	"strings"
+)
+
+// textSuffix marks an algorithm name as hashing text with normalized line
+// endings; see NormalizeLineEndings.
+const textSuffix = "+text"
+
+// normalizedSuffix marks an algorithm name as hashing normalized text; see
+// NormalizeText.
+const normalizedSuffix = "+normalized"
+
+// utf8BOM is the UTF-8 encoding of U+FEFF, the byte order mark some Windows
+// editors put at the start of a file.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// NormalizeLineEndings wraps h so that CRLF and lone CR line endings are
+// hashed as LF, letting a text file checked out on Windows and on Unix give
+// the same digest. Everything else, including a byte order mark, is hashed
+// unchanged; the wrapper must only be used on files known to be text.
+//
+// The result is a different digest from h's over the same bytes, so it
+// travels under its own name: requesting "sha256+text" from New yields the
+// same as NormalizeLineEndings(sha256.New()), and manifests carry that name.
+func NormalizeLineEndings(h hash.Hash) hash.Hash {
+	return &lineEndings{Hash: h}
+}
+
+// TextName labels algorithm hashed with normalized line endings, e.g.
+// "sha256+text".
+func TextName(algorithm string) string {
+	return algorithm + textSuffix
+}
+
+// NormalizeText wraps h like NormalizeLineEndings, and also drops a UTF-8
+// byte order mark at the start of the stream, so that a source file saved
+// by a Windows editor compares equal to its Unix checkout. Requesting it
+// alongside the raw algorithm, e.g. "sha256" and "sha256+normalized" from
+// NewAll, computes both digests in the same pass.
+//
+// A stream shorter than a byte order mark that begins like one is held back
+// until more is written. Sum hashes it as ordinary text without changing
+// what later writes give; since it resets h to do so, h must be new, as
+// from New.
+func NormalizeText(h hash.Hash) hash.Hash {
+	return &bomStripper{Hash: NormalizeLineEndings(h)}
+}
+
+// NormalizedTextName labels algorithm hashed as normalized text, e.g.
+// "sha256+normalized".
+func NormalizedTextName(algorithm string) string {
+	return algorithm + normalizedSuffix
+}
+
+// parseTextName reports which of the text variants name asks for, if any.
+func parseTextName(name string) (base string, normalized bool, ok bool) {
+	if strings.HasSuffix(name, normalizedSuffix) {
+		return strings.TrimSuffix(name, normalizedSuffix), true, true
+	}
+	if strings.HasSuffix(name, textSuffix) {
+		return strings.TrimSuffix(name, textSuffix), false, true
+	}
+	return "", false, false
+}
+
+type lineEndings struct {
+	hash.Hash
+	// afterCR records that the last byte written was a CR, already hashed as
+	// LF, so that an LF starting the next write completes a CRLF rather than
+	// beginning a new line.
+	afterCR bool
+}
+
+func (l *lineEndings) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		// A pending CR still awaits the byte after it.
+		return 0, nil
+	}
+	data := p
+	if l.afterCR && data[0] == '\n' {
+		data = data[1:]
+	}
+	l.afterCR = false
+	if len(data) == 0 {
+		return len(p), nil
+	}
+	if bytes.IndexByte(data, '\r') < 0 {
+		l.Hash.Write(data)
+		return len(p), nil
+	}
+	normalized := make([]byte, 0, len(data))
+	for index := 0; index < len(data); index++ {
+		if data[index] != '\r' {
+			normalized = append(normalized, data[index])
+			continue
+		}
+		normalized = append(normalized, '\n')
+		if index+1 < len(data) && data[index+1] == '\n' {
+			index++
+		} else if index+1 == len(data) {
+			l.afterCR = true
+		}
+	}
+	l.Hash.Write(normalized)
+	return len(p), nil
+}
+
+func (l *lineEndings) Reset() {
+	l.Hash.Reset()
+	l.afterCR = false
+}
+
+type bomStripper struct {
+	hash.Hash
+	// pending holds the leading bytes while they could still be a byte
+	// order mark; started is set once they have been decided.
+	pending []byte
+	started bool
+}
+
+func (b *bomStripper) Write(p []byte) (int, error) {
+	if b.started {
+		return b.Hash.Write(p)
+	}
+	b.pending = append(b.pending, p...)
+	if len(b.pending) < len(utf8BOM) && bytes.HasPrefix(utf8BOM, b.pending) {
+		return len(p), nil
+	}
+	b.started = true
+	data := b.pending
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+	}
+	b.pending = nil
+	b.Hash.Write(data)
+	return len(p), nil
+}
+
+func (b *bomStripper) Sum(in []byte) []byte {
+	if b.started || len(b.pending) == 0 {
+		return b.Hash.Sum(in)
+	}
+	// Nothing has reached the wrapped hash yet, so it can be given the
+	// pending bytes for the sum and reset afterwards, leaving later writes
+	// free to complete a byte order mark.
+	b.Hash.Write(b.pending)
+	sum := b.Hash.Sum(in)
+	b.Hash.Reset()
+	return sum
+}
+
+func (b *bomStripper) Reset() {
+	b.Hash.Reset()
+	b.pending = nil
+	b.started = false
+}
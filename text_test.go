@@ -0,0 +1,68 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func Test_NormalizeLineEndings(t *testing.T) {
+	expected := sha256.Sum256([]byte("one\ntwo\nthree\n\nfour"))
+	for _, writes := range [][]string{
+		{"one\ntwo\nthree\n\nfour"},
+		{"one\r\ntwo\r\nthree\r\n\r\nfour"},
+		{"one\rtwo\rthree\r\rfour"},
+		{"one\r", "\ntwo\r", "\nthree\r", "\r", "\nfour"},
+		{"one\r", "", "\ntwo\nthree\n\nfour"},
+	} {
+		h, err := New(TextName("sha256"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, write := range writes {
+			h.Write([]byte(write))
+		}
+		if sum := h.Sum(nil); !slicesEqual(sum, expected[:]) {
+			t.Fatalf("writes %q gave %x, expected %x\n", writes, sum, expected)
+		}
+	}
+}
+
+func Test_NormalizeText(t *testing.T) {
+	expected := sha256.Sum256([]byte("package main\n"))
+	for _, writes := range [][]string{
+		{"package main\n"},
+		{"\xef\xbb\xbfpackage main\r\n"},
+		{"\xef", "\xbb", "\xbfpackage main\r", "\n"},
+	} {
+		h, err := New(NormalizedTextName("sha256"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, write := range writes {
+			h.Write([]byte(write))
+		}
+		if sum := h.Sum(nil); !slicesEqual(sum, expected[:]) {
+			t.Fatalf("writes %q gave %x, expected %x\n", writes, sum, expected)
+		}
+	}
+
+	// A byte order mark anywhere but the start, or a partial one, is kept.
+	for _, text := range []string{"a\xef\xbb\xbf", "\xef\xbb"} {
+		h := NormalizeText(sha256.New())
+		h.Write([]byte(text))
+		if sum, raw := h.Sum(nil), sha256.Sum256([]byte(text)); !slicesEqual(sum, raw[:]) {
+			t.Fatalf("%q was altered\n", text)
+		}
+	}
+
+	// Sum leaves a held-back prefix to be completed by later writes.
+	h := NormalizeText(sha256.New())
+	h.Write([]byte("\xef"))
+	if sum, raw := h.Sum(nil), sha256.Sum256([]byte("\xef")); !slicesEqual(sum, raw[:]) {
+		t.Fatalf("a partial byte order mark summed to %x, expected %x\n", sum, raw)
+	}
+	h.Write([]byte("\xbb\xbfpackage main\n"))
+	if sum := h.Sum(nil); !slicesEqual(sum, expected[:]) {
+		t.Fatalf("writes after Sum gave %x, expected %x\n", sum, expected)
+	}
+}
@@ -0,0 +1,180 @@
+package multihash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// trailerMagic ends every digest trailer.
+const trailerMagic = "MHTR"
+
+// TrailerWriter passes writes through to an underlying writer while hashing
+// them, and on Close appends a trailer carrying the digests, so that a pipe
+// or an object upload carries its own integrity information without a side
+// channel. The trailer is, in order:
+//
+//	the digests, in algorithm order, each at its hash's size
+//	8 bytes   big-endian length of the data
+//	4 bytes   big-endian CRC-32C of the algorithm names joined by newlines
+//	"MHTR"
+//
+// A TrailerReader configured with the same algorithms strips and checks it.
+type TrailerWriter struct {
+	w          io.Writer
+	algorithms []string
+	hashes     []hash.Hash
+	n          int64
+	closed     bool
+}
+
+// NewTrailerWriter returns a TrailerWriter writing to w and hashing with
+// algorithms.
+func NewTrailerWriter(w io.Writer, algorithms ...string) (*TrailerWriter, error) {
+	hashes, err := NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	return &TrailerWriter{w: w, algorithms: algorithms, hashes: hashes}, nil
+}
+
+func (t *TrailerWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	for _, h := range t.hashes {
+		h.Write(p[:n])
+	}
+	t.n += int64(n)
+	return n, err
+}
+
+// Close writes the trailer. It does not close the underlying writer.
+func (t *TrailerWriter) Close() error {
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	var trailer bytes.Buffer
+	for _, h := range t.hashes {
+		trailer.Write(h.Sum(nil))
+	}
+	writeTrailerFooter(&trailer, t.n, t.algorithms)
+	_, err := t.w.Write(trailer.Bytes())
+	return err
+}
+
+func writeTrailerFooter(trailer *bytes.Buffer, n int64, algorithms []string) {
+	var footer [12]byte
+	binary.BigEndian.PutUint64(footer[:8], uint64(n))
+	binary.BigEndian.PutUint32(footer[8:], algorithmsChecksum(algorithms))
+	trailer.Write(footer[:])
+	trailer.WriteString(trailerMagic)
+}
+
+func algorithmsChecksum(algorithms []string) uint32 {
+	return crc32.Checksum([]byte(strings.Join(algorithms, "\n")), castagnoliTable)
+}
+
+// TrailerReader reads a stream written by a TrailerWriter, returning the
+// data without its trailer. Because the end of the data is only known at
+// the end of the stream, the last trailer's worth of bytes is held back
+// until then; io.EOF is returned only once the trailer has been checked.
+// A stream without a valid trailer is reported as a MalformedTrailerError,
+// one of the wrong length as a SizeMismatchError, and one whose data does
+// not match its digests as a DigestMismatchError.
+type TrailerReader struct {
+	r          io.Reader
+	algorithms []string
+	hashes     []hash.Hash
+	// buf holds bytes read but not yet returned; the last trailerSize of
+	// them may be the trailer.
+	buf         []byte
+	chunk       []byte
+	trailerSize int
+	n           int64
+	err         error
+}
+
+// NewTrailerReader returns a TrailerReader reading from r and checking the
+// trailer written under algorithms.
+func NewTrailerReader(r io.Reader, algorithms ...string) (*TrailerReader, error) {
+	hashes, err := NewAll(algorithms...)
+	if err != nil {
+		return nil, err
+	}
+	size := 8 + 4 + len(trailerMagic)
+	for _, h := range hashes {
+		size += h.Size()
+	}
+	return &TrailerReader{r: r, algorithms: algorithms, hashes: hashes, trailerSize: size}, nil
+}
+
+func (t *TrailerReader) Read(p []byte) (int, error) {
+	for len(t.buf) <= t.trailerSize && t.err == nil {
+		if t.chunk == nil {
+			t.chunk = make([]byte, bufferSize)
+		}
+		n, err := t.r.Read(t.chunk)
+		t.buf = append(t.buf, t.chunk[:n]...)
+		if err == io.EOF {
+			t.err = t.check()
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	available := len(t.buf) - t.trailerSize
+	if t.err != nil && available <= 0 {
+		return 0, t.err
+	}
+	if available > len(p) {
+		available = len(p)
+	}
+	if available <= 0 {
+		return 0, t.err
+	}
+	n := copy(p, t.buf[:available])
+	// Once the trailer has been checked, the rest has been hashed already.
+	if t.err == nil {
+		for _, h := range t.hashes {
+			h.Write(p[:n])
+		}
+		t.n += int64(n)
+	}
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// check runs at the end of the stream, when buf ends with the trailer,
+// hashing the data before it, which Read then returns without hashing
+// again.
+func (t *TrailerReader) check() error {
+	if len(t.buf) < t.trailerSize {
+		return MalformedTrailerError{Reason: "stream is shorter than its trailer"}
+	}
+	data, trailer := t.buf[:len(t.buf)-t.trailerSize], t.buf[len(t.buf)-t.trailerSize:]
+	footer := trailer[len(trailer)-16:]
+	if string(footer[12:]) != trailerMagic {
+		return MalformedTrailerError{Reason: "no trailer"}
+	}
+	if binary.BigEndian.Uint32(footer[8:12]) != algorithmsChecksum(t.algorithms) {
+		return MalformedTrailerError{Reason: "trailer written with other algorithms"}
+	}
+	for _, h := range t.hashes {
+		h.Write(data)
+	}
+	total := t.n + int64(len(data))
+	if expected := int64(binary.BigEndian.Uint64(footer[:8])); expected != total {
+		return SizeMismatchError{Expected: expected, Actual: total}
+	}
+	digests := trailer
+	for _, h := range t.hashes {
+		expected := digests[:h.Size()]
+		digests = digests[h.Size():]
+		if actual := h.Sum(nil); !bytes.Equal(actual, expected) {
+			return DigestMismatchError{Expected: expected, Actual: actual}
+		}
+	}
+	return io.EOF
+}
@@ -0,0 +1,53 @@
+package multihash
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_Trailer(t *testing.T) {
+	data := bytes.Repeat([]byte("streamed payload "), 10000)
+	var stream bytes.Buffer
+	w, err := NewTrailerWriter(&stream, "md5", "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data[:1000])
+	w.Write(data[1000:])
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stream.Len() != len(data)+16+32+16 {
+		t.Fatalf("unexpected stream length %d\n", stream.Len())
+	}
+
+	read := func(stream []byte, algorithms ...string) ([]byte, error) {
+		r, err := NewTrailerReader(iotest.OneByteReader(bytes.NewReader(stream)), algorithms...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return io.ReadAll(r)
+	}
+	stripped, err := read(stream.Bytes(), "md5", "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, data) {
+		t.Fatal("data was not returned intact without its trailer")
+	}
+
+	corrupt := append([]byte(nil), stream.Bytes()...)
+	corrupt[500] ^= 1
+	if _, err = read(corrupt, "md5", "sha256"); !errors.Is(err, ErrDigestMismatch) {
+		t.Fatalf("expected a digest mismatch, got %v\n", err)
+	}
+	if _, err = read(stream.Bytes(), "sha256", "md5"); !errors.Is(err, ErrMalformedTrailer) {
+		t.Fatalf("expected a trailer for other algorithms to be rejected, got %v\n", err)
+	}
+	if _, err = read(data[:10], "md5"); !errors.Is(err, ErrMalformedTrailer) {
+		t.Fatalf("expected a stream without a trailer to be rejected, got %v\n", err)
+	}
+}
@@ -0,0 +1,60 @@
+package multihash
+
+import (
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// Truncate wraps h so that its digest is cut to the leading bits bits, for
+// short identifiers where the full digest is unwieldy. bits must be a
+// positive multiple of 8 no larger than h's own size.
+//
+// Truncated digests are a different algorithm from the full ones, with
+// correspondingly weaker collision resistance, so they should always travel
+// under their own name: requesting "sha256/64" from New yields the same as
+// Truncate(sha256.New(), 64), and manifests carry that name, making a
+// truncated digest impossible to mistake for a full one.
+func Truncate(h hash.Hash, bits int) (hash.Hash, error) {
+	if bits <= 0 || bits%8 != 0 || bits/8 > h.Size() {
+		return nil, TruncationError{Bits: bits, Size: h.Size() * 8}
+	}
+	return truncated{Hash: h, size: bits / 8}, nil
+}
+
+// TruncatedName labels algorithm truncated to bits, e.g. "sha256/64", as
+// New reads it. "sha512/224" and "sha512/256" are not available as labels:
+// New reads those as the FIPS 180-4 algorithms of the same names, which
+// have their own initial values, so for SHA-512 at those sizes it returns a
+// TruncatedNameError rather than a name for a different digest.
+func TruncatedName(algorithm string, bits int) (string, error) {
+	name := algorithm + "/" + strconv.Itoa(bits)
+	if _, ok := hashAliases[name]; ok {
+		return "", TruncatedNameError{Name: name}
+	}
+	return name, nil
+}
+
+func parseTruncatedName(name string) (base string, bits int, ok bool) {
+	base, suffix, found := strings.Cut(name, "/")
+	if !found {
+		return "", 0, false
+	}
+	bits, err := strconv.Atoi(suffix)
+	if err != nil {
+		return "", 0, false
+	}
+	return base, bits, true
+}
+
+type truncated struct {
+	hash.Hash
+	size int
+}
+
+func (t truncated) Size() int { return t.size }
+
+func (t truncated) Sum(b []byte) []byte {
+	full := t.Hash.Sum(nil)
+	return append(b, full[:t.size]...)
+}
@@ -0,0 +1,60 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func Test_Truncate(t *testing.T) {
+	name, err := TruncatedName("sha256", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := New(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("Sample text file\n"))
+	full := sha256.Sum256([]byte("Sample text file\n"))
+	if sum := h.Sum(nil); h.Size() != 8 || !slicesEqual(sum, full[:8]) {
+		t.Fatalf("truncated digest was %x, expected %x\n", sum, full[:8])
+	}
+	for _, bits := range []int{0, 12, 512} {
+		if _, err = Truncate(sha256.New(), bits); !errors.Is(err, ErrInvalidTruncation) {
+			t.Fatalf("truncating to %d bits gave %v\n", bits, err)
+		}
+	}
+}
+
+func Test_TruncatedSHA512Names(t *testing.T) {
+	// The "abc" examples of FIPS 180-4, which differ from the leading bits
+	// of SHA-512("abc").
+	for name, expected := range map[string]string{
+		"sha512/224": "4634270f707b6a54daae7530460842e20e37ed265ceee9a43e8924aa",
+		"sha512/256": "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23",
+	} {
+		h, err := New(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Write([]byte("abc"))
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != expected {
+			t.Fatalf("%s of \"abc\" was %s, expected %s\n", name, sum, expected)
+		}
+	}
+	for _, bits := range []int{224, 256} {
+		if name, err := TruncatedName("sha512", bits); !errors.Is(err, ErrInvalidTruncation) {
+			t.Fatalf("truncating sha512 to %d bits was named %q, %v\n", bits, name, err)
+		}
+	}
+	h, err := New("sha512/128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Size() != 16 {
+		t.Fatalf("sha512/128 has a %d-byte digest, expected a truncation to 16\n", h.Size())
+	}
+}
@@ -0,0 +1,132 @@
+package multihash
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// VerifyReport lists the outcome of checking files against a manifest, by
+// the paths recorded in the manifest.
+type VerifyReport struct {
+	// Verified files matched every digest the manifest holds for them.
+	Verified []string
+	// Mismatched files were read but differ from at least one digest.
+	Mismatched []string
+	// Missing files are in the manifest but not on disk.
+	Missing []string
+	// Unverified files have no digest in the manifest, so nothing vouches
+	// for their content; they were not read.
+	Unverified []string
+	// Extra files were found but are not in the manifest.
+	Extra []string
+	// Failed files could not be read, for reasons other than not existing.
+	Failed map[string]error
+}
+
+// OK reports whether every checked file was present and matched, and no
+// unlisted files were found.
+func (r VerifyReport) OK() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0 && len(r.Unverified) == 0 &&
+		len(r.Extra) == 0 && len(r.Failed) == 0
+}
+
+// VerifySample re-hashes a random percent of the entries in m, resolving
+// their paths relative to root, and reports how they compare. The sample is
+// drawn from seed, so a run can be reproduced exactly, and varying the seed
+// between runs (by date, say) eventually covers the whole manifest. At least
+// one entry is checked whenever percent is above zero; a percent that is
+// not a number from 0 to 100 is a PercentError. An entry whose path could
+// lie outside root is Failed with a manifest.InvalidPathError, unread.
+//
+// This trades certainty for cost on archives too large to re-verify in
+// full: a corruption affecting a fraction f of files goes unnoticed by a run
+// with probability about (1-f) to the power of the sample size.
+func VerifySample(root string, m *manifest.Manifest, percent float64, seed int64) (VerifyReport, error) {
+	if !(percent >= 0 && percent <= 100) {
+		return VerifyReport{}, PercentError{Percent: percent}
+	}
+	count := int(math.Ceil(float64(len(m.Entries)) * percent / 100))
+	if count > len(m.Entries) {
+		count = len(m.Entries)
+	}
+	sample := rand.New(rand.NewSource(seed)).Perm(len(m.Entries))[:count]
+	sort.Ints(sample)
+	entries := make([]manifest.Entry, count)
+	for i, index := range sample {
+		entries[i] = m.Entries[index]
+	}
+	report, _, err := verifyEntries(root, m.Algorithms, entries, 1)
+	return report, err
+}
+
+// verifyEntries hashes each entry's file under root with algorithms, on
+// workers goroutines, and sorts it into a report by the entry's path as
+// recorded. Paths are made canonical first, as by manifest.CanonicalPath,
+// so that none can reach outside root; an entry without a canonical path is
+// Failed with its InvalidPathError and not read. An entry with no digests is
+// Unverified, also unread, as Migrate reports it. The canonical paths of
+// all entries but the Failed ones are returned, in order.
+func verifyEntries(root string, algorithms []string, entries []manifest.Entry, workers int) (report VerifyReport, paths []string, err error) {
+	if _, err = NewAll(algorithms...); err != nil {
+		return report, nil, err
+	}
+	pathErrs := make([]error, len(entries))
+	var hashed []string
+	for index, entry := range entries {
+		canonical, err := manifest.CanonicalPath(entry.Path)
+		if err != nil {
+			pathErrs[index] = err
+			continue
+		}
+		paths = append(paths, canonical)
+		if hasDigest(entry.Digests) {
+			hashed = append(hashed, canonical)
+		}
+	}
+	results := hashFiles(root, hashed, algorithms, workers)
+	for index, entry := range entries {
+		result := fileResult{err: pathErrs[index]}
+		if result.err == nil {
+			if !hasDigest(entry.Digests) {
+				report.Unverified = append(report.Unverified, entry.Path)
+				continue
+			}
+			result, results = results[0], results[1:]
+		}
+		switch {
+		case errors.Is(result.err, fs.ErrNotExist):
+			report.Missing = append(report.Missing, entry.Path)
+		case result.err != nil:
+			if report.Failed == nil {
+				report.Failed = make(map[string]error)
+			}
+			report.Failed[entry.Path] = result.err
+		case DigestsMatch(entry.Digests, result.digests):
+			report.Verified = append(report.Verified, entry.Path)
+		default:
+			report.Mismatched = append(report.Mismatched, entry.Path)
+		}
+	}
+	return report, paths, nil
+}
+
+// DigestsMatch compares the digests a manifest holds against computed ones,
+// in the same order, skipping algorithms the manifest has no value for.
+// Fewer actual digests than expected ones do not match.
+func DigestsMatch(expected, actual [][]byte) bool {
+	if len(actual) < len(expected) {
+		return false
+	}
+	for index, digest := range expected {
+		if digest != nil && !bytes.Equal(digest, actual[index]) {
+			return false
+		}
+	}
+	return true
+}
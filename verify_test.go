@@ -0,0 +1,119 @@
+package multihash
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trytriangles/multihash/manifest"
+)
+
+// writeTree creates count small files under a temporary directory and
+// returns it with a sha256 manifest of them.
+func writeTree(t *testing.T, count int) (string, *manifest.Manifest) {
+	root := t.TempDir()
+	m := &manifest.Manifest{Algorithms: []string{"sha256"}}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("dir%d/file%d.txt", i%3, i)
+		contents := []byte(fmt.Sprintf("contents of file %d\n", i))
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(contents)
+		m.Entries = append(m.Entries, manifest.Entry{Path: name, Digests: [][]byte{sum[:]}})
+	}
+	return root, m
+}
+
+func Test_VerifySample(t *testing.T) {
+	root, m := writeTree(t, 40)
+	report, err := VerifySample(root, m, 25, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Verified) != 10 || !report.OK() {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+	again, _ := VerifySample(root, m, 25, 7)
+	if fmt.Sprint(again.Verified) != fmt.Sprint(report.Verified) {
+		t.Fatal("the same seed sampled different entries")
+	}
+
+	os.WriteFile(filepath.Join(root, m.Entries[0].Path), []byte("changed"), 0o644)
+	os.Remove(filepath.Join(root, m.Entries[1].Path))
+	report, err = VerifySample(root, m, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Mismatched) != 1 || len(report.Missing) != 1 || len(report.Verified) != 38 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
+
+func Test_VerifySampleUnsafePath(t *testing.T) {
+	root, m := writeTree(t, 2)
+	outside := filepath.Join(filepath.Dir(root), "outside.txt")
+	if err := os.WriteFile(outside, []byte("outside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(outside)
+	sum := sha256.Sum256([]byte("outside"))
+	m.Entries = append(m.Entries, manifest.Entry{Path: "../outside.txt", Digests: [][]byte{sum[:]}})
+	report, err := VerifySample(root, m, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(report.Failed["../outside.txt"], manifest.ErrInvalidPath) || len(report.Verified) != 2 {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
+
+func Test_VerifySampleInvalidPercent(t *testing.T) {
+	root, m := writeTree(t, 4)
+	for _, percent := range []float64{-1, 100.5, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := VerifySample(root, m, percent, 1); !errors.Is(err, ErrInvalidPercent) {
+			t.Fatalf("got %v for percent %v\n", err, percent)
+		}
+	}
+	if report, err := VerifySample(root, m, 0, 1); err != nil || len(report.Verified) != 0 {
+		t.Fatalf("got %+v, %v for percent 0\n", report, err)
+	}
+}
+
+func Test_VerifyReportOK(t *testing.T) {
+	if !(VerifyReport{Verified: []string{"a.txt"}}).OK() {
+		t.Fatal("a report of only verified files is not OK")
+	}
+	if (VerifyReport{Verified: []string{"a.txt"}, Extra: []string{"b.txt"}}).OK() {
+		t.Fatal("a report with an unlisted file is OK")
+	}
+}
+
+func Test_VerifySampleUnverified(t *testing.T) {
+	root, m := writeTree(t, 2)
+	m.Entries[0].Digests = [][]byte{nil}
+	report, err := VerifySample(root, m, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicesEqual(report.Unverified, []string{m.Entries[0].Path}) || len(report.Verified) != 1 || report.OK() {
+		t.Fatalf("unexpected report %+v\n", report)
+	}
+}
+
+func Test_DigestsMatch(t *testing.T) {
+	digest := []byte{1, 2, 3}
+	if !DigestsMatch([][]byte{nil, digest}, [][]byte{{9}, digest}) {
+		t.Fatal("matching digests did not match")
+	}
+	if DigestsMatch([][]byte{digest, digest}, [][]byte{digest}) {
+		t.Fatal("more expected digests than actual ones matched")
+	}
+}
@@ -0,0 +1,100 @@
+package multihash
+
+import (
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Volumes returns the names of a set of sequentially numbered volumes,
+// starting from first, such as "backup.tar.001", and continuing through
+// "backup.tar.002" and on until the next number does not exist. The number
+// is the last extension, made of digits, and keeps its width. A first
+// volume whose name does not end in such a number is reported as a
+// VolumeNameError.
+func Volumes(first string) ([]string, error) {
+	ext := filepath.Ext(first)
+	digits := strings.TrimPrefix(ext, ".")
+	number, err := strconv.Atoi(digits)
+	if digits == "" || err != nil || strings.TrimLeft(digits, "0123456789") != "" {
+		return nil, VolumeNameError{Name: first}
+	}
+	if _, err = os.Stat(first); err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(first, ext)
+	volumes := []string{first}
+	for number++; ; number++ {
+		suffix := strconv.Itoa(number)
+		if len(suffix) < len(digits) {
+			suffix = strings.Repeat("0", len(digits)-len(suffix)) + suffix
+		}
+		name := base + "." + suffix
+		if _, err = os.Stat(name); os.IsNotExist(err) {
+			return volumes, nil
+		} else if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, name)
+	}
+}
+
+// FromVolumes hashes the volume set beginning with first, as found by
+// Volumes, as one logical stream, computing every algorithm both over the
+// whole and over each volume, as FromReaders does. Each volume is opened
+// only when the stream reaches it and closed when it is done.
+func FromVolumes(first string, hashes ...func() hash.Hash) (volumes []string, whole [][]byte, perVolume [][][]byte, err error) {
+	volumes, err = Volumes(first)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	readers := make([]io.Reader, len(volumes))
+	files := make([]*volumeReader, len(volumes))
+	for index, name := range volumes {
+		files[index] = &volumeReader{name: name}
+		readers[index] = files[index]
+	}
+	defer func() {
+		for _, f := range files {
+			f.close()
+		}
+	}()
+	whole, perVolume, err = hashParts(readers, hashes)
+	return volumes, whole, perVolume, err
+}
+
+// volumeReader opens its file on the first Read and closes it at EOF.
+type volumeReader struct {
+	name string
+	f    *os.File
+	done bool
+}
+
+func (v *volumeReader) Read(p []byte) (int, error) {
+	if v.done {
+		return 0, io.EOF
+	}
+	if v.f == nil {
+		f, err := os.Open(v.name)
+		if err != nil {
+			return 0, err
+		}
+		v.f = f
+	}
+	n, err := v.f.Read(p)
+	if err == io.EOF {
+		v.close()
+		v.done = true
+	}
+	return n, err
+}
+
+func (v *volumeReader) close() {
+	if v.f != nil {
+		v.f.Close()
+		v.f = nil
+	}
+}
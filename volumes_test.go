@@ -0,0 +1,45 @@
+package multihash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_FromVolumes(t *testing.T) {
+	dir := t.TempDir()
+	contents := []string{"first volume, ", "second volume, ", "last"}
+	for index, content := range contents {
+		name := filepath.Join(dir, "transfer.bin.00"+string(rune('1'+index)))
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A gap ends the set.
+	os.WriteFile(filepath.Join(dir, "transfer.bin.005"), []byte("stray"), 0o644)
+
+	volumes, whole, perVolume, err := FromVolumes(filepath.Join(dir, "transfer.bin.001"), md5.New, sha256.New)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(volumes) != 3 || filepath.Base(volumes[2]) != "transfer.bin.003" {
+		t.Fatalf("unexpected volumes %v\n", volumes)
+	}
+	expected := sha256.Sum256([]byte("first volume, second volume, last"))
+	if !slicesEqual(whole[1], expected[:]) {
+		t.Fatalf("whole-stream digest %x, expected %x\n", whole[1], expected)
+	}
+	for index, content := range contents {
+		expected := md5.Sum([]byte(content))
+		if !slicesEqual(perVolume[index][0], expected[:]) {
+			t.Fatalf("volume %d digest %x, expected %x\n", index, perVolume[index][0], expected)
+		}
+	}
+
+	if _, err = Volumes(filepath.Join(dir, "transfer.bin")); !errors.Is(err, ErrVolumeName) {
+		t.Fatalf("expected ErrVolumeName, got %v\n", err)
+	}
+}
@@ -0,0 +1,135 @@
+//go:build js && wasm
+
+// package main builds multihash for the browser, so that web applications
+// can compute several digests of user-selected files client-side, before
+// upload, in a single pass. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o multihash.wasm ./wasm
+//
+// and serve it with multihash.js from this directory and wasm_exec.js from
+// the Go distribution (lib/wasm, or misc/wasm in older releases).
+//
+// The module installs a global multihash object:
+//
+//	multihash.algorithms()      // ["crc32", "md5", "sha256", ...]
+//	const h = multihash.create(["md5", "sha256"])
+//	h.write(uint8Array)          // as often as needed
+//	h.digests()                  // {md5: "…hex…", sha256: "…hex…"}
+//	h.reset()
+//	h.release()                  // free it; h is unusable afterwards
+//
+// Failures are returned as Error values rather than thrown; the functions
+// exported by multihash.js throw them instead, and are the intended API.
+//
+// Nothing here touches the filesystem: data only arrives through write, and
+// multihash.js feeds it from a Blob's stream so that files of any size are
+// hashed in bounded memory.
+package main
+
+import (
+	"encoding/hex"
+	"hash"
+	"syscall/js"
+
+	"github.com/trytriangles/multihash"
+
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	_ "github.com/trytriangles/multihash/mailru"
+	_ "github.com/trytriangles/multihash/quickxor"
+)
+
+func main() {
+	js.Global().Set("multihash", js.ValueOf(map[string]any{
+		"algorithms": js.FuncOf(algorithms),
+		"create":     js.FuncOf(create),
+	}))
+	// Stay alive so that the exported functions remain callable.
+	select {}
+}
+
+func algorithms(this js.Value, args []js.Value) any {
+	names := multihash.Algorithms()
+	result := make([]any, len(names))
+	for index, name := range names {
+		result[index] = name
+	}
+	return result
+}
+
+// create returns a hasher object for the algorithm names in args[0], or an
+// Error if any of them is unknown. Its methods are Go functions that
+// JavaScript's garbage collector cannot free, so they stay allocated until
+// its release method is called.
+func create(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeObject {
+		return jsError("create expects an array of algorithm names")
+	}
+	names := make([]string, args[0].Length())
+	for index := range names {
+		names[index] = args[0].Index(index).String()
+	}
+	hashes, err := multihash.NewAll(names...)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	var buffer []byte
+	var funcs []js.Func
+	method := func(fn func(this js.Value, args []js.Value) any) js.Func {
+		f := js.FuncOf(fn)
+		funcs = append(funcs, f)
+		return f
+	}
+	return js.ValueOf(map[string]any{
+		"write": method(func(this js.Value, args []js.Value) any {
+			if len(args) != 1 {
+				return jsError("write expects a Uint8Array")
+			}
+			length := args[0].Get("length").Int()
+			if cap(buffer) < length {
+				buffer = make([]byte, length)
+			}
+			buffer = buffer[:length]
+			js.CopyBytesToGo(buffer, args[0])
+			write(hashes, buffer)
+			return nil
+		}),
+		"digests": method(func(this js.Value, args []js.Value) any {
+			result := make(map[string]any, len(hashes))
+			for index, h := range hashes {
+				result[names[index]] = hex.EncodeToString(h.Sum(nil))
+			}
+			return result
+		}),
+		"reset": method(func(this js.Value, args []js.Value) any {
+			for _, h := range hashes {
+				h.Reset()
+			}
+			return nil
+		}),
+		"release": method(func(this js.Value, args []js.Value) any {
+			// A js.Func may be released while it runs, so this one goes too.
+			for _, f := range funcs {
+				f.Release()
+			}
+			return nil
+		}),
+	})
+}
+
+// write feeds data to every hash. The browser gives a wasm module a single
+// thread, so there is nothing to gain from the goroutines FromReader uses.
+func write(hashes []hash.Hash, data []byte) {
+	for _, h := range hashes {
+		h.Write(data)
+	}
+}
+
+// jsError returns a JavaScript Error for message. Go cannot throw across
+// js.FuncOf, so failures are returned as values, and multihash.js turns them
+// into exceptions.
+func jsError(message string) any {
+	return js.Global().Get("Error").New(message)
+}
@@ -0,0 +1,53 @@
+// Glue for multihash.wasm; see main.go. Load wasm_exec.js first, then:
+//
+//   const multihash = await loadMultihash("multihash.wasm");
+//   const digests = await multihash.hashBlob(file, ["md5", "sha256"]);
+//   // {md5: "…", sha256: "…"}
+
+async function loadMultihash(url) {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  const exported = globalThis.multihash;
+
+  function check(value) {
+    if (value instanceof Error) {
+      throw value;
+    }
+    return value;
+  }
+
+  function create(algorithms) {
+    const h = check(exported.create(algorithms));
+    return {
+      write: (chunk) => check(h.write(chunk)),
+      digests: () => check(h.digests()),
+      reset: () => check(h.reset()),
+      release: () => h.release(),
+    };
+  }
+
+  // hashBlob streams a Blob or File through every algorithm in one pass,
+  // never holding more than one chunk of it in memory.
+  async function hashBlob(blob, algorithms) {
+    const h = create(algorithms);
+    try {
+      const reader = blob.stream().getReader();
+      for (;;) {
+        const { done, value } = await reader.read();
+        if (done) {
+          return h.digests();
+        }
+        h.write(value);
+      }
+    } finally {
+      h.release();
+    }
+  }
+
+  return {
+    algorithms: () => exported.algorithms(),
+    create,
+    hashBlob,
+  };
+}